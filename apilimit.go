@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// maxConcurrentAPICallsFlag bounds the G-Core API calls in flight across all
+// challenges, so a mass renewal of hundreds of certificates neither runs
+// into the API's rate limits nor exhausts connections.
+const maxConcurrentAPICallsFlag = "--max-concurrent-api-calls"
+
+// maxConcurrentAPICalls returns the limiter of the last
+// --max-concurrent-api-calls flag in args, nil without one or with 0 as
+// calls are then unlimited, and args without the flags.
+func maxConcurrentAPICalls(args []string) (*semaphore.Weighted, []string, error) {
+	values, rest, err := cutFlag(args, maxConcurrentAPICallsFlag)
+	if err != nil || len(values) == 0 {
+		return nil, rest, err
+	}
+	value := values[len(values)-1]
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return nil, nil, fmt.Errorf("%s must be a non-negative integer, got %q", maxConcurrentAPICallsFlag, value)
+	}
+	if n == 0 {
+		return nil, rest, nil
+	}
+	return semaphore.NewWeighted(n), rest, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxConcurrentAPICalls(t *testing.T) {
+	limiter, rest, err := maxConcurrentAPICalls([]string{"--secure-port=443", "--max-concurrent-api-calls", "10",
+		"--max-concurrent-api-calls=2"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--secure-port=443"}, rest)
+	require.NotNil(t, limiter)
+	assert.True(t, limiter.TryAcquire(2))
+	assert.False(t, limiter.TryAcquire(1))
+
+	limiter, rest, err = maxConcurrentAPICalls([]string{"--max-concurrent-api-calls=0"})
+	require.NoError(t, err)
+	assert.Nil(t, limiter)
+	assert.Empty(t, rest)
+
+	limiter, _, err = maxConcurrentAPICalls(nil)
+	require.NoError(t, err)
+	assert.Nil(t, limiter)
+
+	_, _, err = maxConcurrentAPICalls([]string{"--max-concurrent-api-calls=-1"})
+	assert.ErrorContains(t, err, "--max-concurrent-api-calls must be a non-negative integer")
+}
+
+// slowAPI is a mockSDK whose zone lookups take a while and which tracks how
+// many of them run at once.
+type slowAPI struct {
+	*mockSDK
+	inFlight, maxInFlight atomic.Int32
+}
+
+func (s *slowAPI) Zone(ctx context.Context, name string) (dnssdk.Zone, error) {
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		peak := s.maxInFlight.Load()
+		if n <= peak || s.maxInFlight.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return s.mockSDK.Zone(ctx, name)
+}
+
+func TestSolverLimitsConcurrentAPICalls(t *testing.T) {
+	api := &slowAPI{mockSDK: newMockSDK("example.com")}
+	limiter, _, err := maxConcurrentAPICalls([]string{"--max-concurrent-api-calls=3"})
+	require.NoError(t, err)
+	solver := &gcoreDNSProviderSolver{
+		newSDK:   func(gcoreDNSProviderConfig, string) (dnsAPI, error) { return api, nil },
+		apiCalls: limiter,
+	}
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "token",
+				`{"apiToken":"t","zoneDiscovery":"probe"}`)))
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, api.maxInFlight.Load(), int32(3))
+	assert.Greater(t, api.maxInFlight.Load(), int32(1))
+
+	// Waiting for a slot ends with the challenge's context.
+	require.True(t, limiter.TryAcquire(3))
+	defer limiter.Release(3)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = (&retryingAPI{api: api, limiter: limiter}).Zone(ctx, "example.com")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "wait for an API call slot")
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+// auditLogStdout makes GCORE_AUDIT_LOG write the audit records to stdout,
+// apart from the log lines on stderr.
+const auditLogStdout = "stdout"
+
+// Actions of audit records.
+const (
+	auditCreate     = "create"
+	auditUpdate     = "update"
+	auditDelete     = "delete"
+	auditCreateZone = "create_zone"
+	auditEnableZone = "enable_zone"
+)
+
+// auditLog writes a JSON line for every change the webhook makes to DNS, for
+// compliance teams tracking them. A nil auditLog writes nothing.
+type auditLog struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+// auditRecord is a line of the audit log.
+type auditRecord struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Zone   string    `json:"zone"`
+	RRSet  string    `json:"rrset,omitempty"`
+	Type   string    `json:"type,omitempty"`
+	// ValuesHash is a fingerprint of the record values written, not enough
+	// to recover them.
+	ValuesHash string `json:"valuesHash,omitempty"`
+	auditActor
+	// Error is why the change failed, empty when it was made.
+	Error string `json:"error,omitempty"`
+}
+
+// auditActor identifies who made a change: the hash of the API endpoint and
+// credential, and the Challenge it was made for, if any.
+type auditActor struct {
+	Account   string `json:"account"`
+	Namespace string `json:"namespace,omitempty"`
+	Challenge string `json:"challenge,omitempty"`
+	FQDN      string `json:"fqdn,omitempty"`
+}
+
+// auditLogFromEnv opens the audit log of GCORE_AUDIT_LOG, a file records are
+// appended to or "stdout", nil when it is unset.
+func auditLogFromEnv() (*auditLog, error) {
+	path := os.Getenv(auditLogEnvVar)
+	switch path {
+	case "":
+		return nil, nil
+	case auditLogStdout:
+		return &auditLog{w: os.Stdout, now: time.Now}, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", auditLogEnvVar, err)
+	}
+	return &auditLog{w: f, now: time.Now}, nil
+}
+
+// challengeActor is the actor of the changes made for ch with the credential
+// of account.
+func challengeActor(account string, ch *v1alpha1.ChallengeRequest) auditActor {
+	return auditActor{Account: account, Namespace: ch.ResourceNamespace, Challenge: string(ch.UID),
+		FQDN: ch.ResolvedFQDN}
+}
+
+// wrap returns api recording its changes as made by actor.
+func (l *auditLog) wrap(api dnsAPI, actor auditActor) dnsAPI {
+	if l == nil {
+		return api
+	}
+	return &auditingAPI{dnsAPI: api, log: l, actor: actor}
+}
+
+func (l *auditLog) record(r auditRecord, err error) {
+	r.Time = l.now().UTC()
+	if err != nil {
+		r.Error = redact(err.Error())
+	}
+	line, _ := json.Marshal(r)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.w.Write(append(line, '\n'))
+}
+
+// valuesHash fingerprints the values of record regardless of their order.
+func valuesHash(record dnssdk.RRSet) string {
+	var values []string
+	for _, rr := range record.Records {
+		for _, content := range rr.Content {
+			values = append(values, fmt.Sprint(content))
+		}
+	}
+	slices.Sort(values)
+	return contentHash(strings.Join(values, "\n"))
+}
+
+// auditingAPI records the changes made through dnsAPI.
+type auditingAPI struct {
+	dnsAPI
+	log   *auditLog
+	actor auditActor
+}
+
+func (a *auditingAPI) rrset(action, zone, name, recordType, hash string, err error) {
+	a.log.record(auditRecord{Action: action, Zone: zone, RRSet: name, Type: recordType, ValuesHash: hash,
+		auditActor: a.actor}, err)
+}
+
+func (a *auditingAPI) CreateRRSet(ctx context.Context, zone, name, recordType string, record dnssdk.RRSet) error {
+	err := a.dnsAPI.CreateRRSet(ctx, zone, name, recordType, record)
+	a.rrset(auditCreate, zone, name, recordType, valuesHash(record), err)
+	return err
+}
+
+func (a *auditingAPI) UpdateRRSet(ctx context.Context, zone, name, recordType string, record dnssdk.RRSet) error {
+	err := a.dnsAPI.UpdateRRSet(ctx, zone, name, recordType, record)
+	a.rrset(auditUpdate, zone, name, recordType, valuesHash(record), err)
+	return err
+}
+
+func (a *auditingAPI) DeleteRRSet(ctx context.Context, zone, name, recordType string) error {
+	err := a.dnsAPI.DeleteRRSet(ctx, zone, name, recordType)
+	a.rrset(auditDelete, zone, name, recordType, "", err)
+	return err
+}
+
+func (a *auditingAPI) UpdateRRSetIfMatch(ctx context.Context, zone, name, recordType string, record dnssdk.RRSet,
+	version rrsetVersion) error {
+	err := a.dnsAPI.UpdateRRSetIfMatch(ctx, zone, name, recordType, record, version)
+	a.rrset(auditUpdate, zone, name, recordType, valuesHash(record), err)
+	return err
+}
+
+func (a *auditingAPI) DeleteRRSetIfMatch(ctx context.Context, zone, name, recordType string,
+	version rrsetVersion) error {
+	err := a.dnsAPI.DeleteRRSetIfMatch(ctx, zone, name, recordType, version)
+	a.rrset(auditDelete, zone, name, recordType, "", err)
+	return err
+}
+
+func (a *auditingAPI) CreateZone(ctx context.Context, name string) (uint64, error) {
+	id, err := a.dnsAPI.CreateZone(ctx, name)
+	a.log.record(auditRecord{Action: auditCreateZone, Zone: name, auditActor: a.actor}, err)
+	return id, err
+}
+
+func (a *auditingAPI) EnableZone(ctx context.Context, name string) error {
+	err := a.dnsAPI.EnableZone(ctx, name)
+	a.log.record(auditRecord{Action: auditEnableZone, Zone: name, auditActor: a.actor}, err)
+	return err
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	var out bytes.Buffer
+	m := newMockSDK("example.com")
+	solver := solverWithMock(m)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	solver.audit = &auditLog{w: &out, now: func() time.Time { return now }}
+
+	ch := challenge("_acme-challenge.example.com.", "key", `{"apiToken":"t","zoneDiscovery":"probe"}`)
+	ch.ResourceNamespace = "team-a"
+	ch.UID = "4b1c0c41-5d0e-4f53-9f4e-2c1d0a6f2d11"
+	require.NoError(t, solver.Present(ch))
+	other := challenge("_acme-challenge.example.com.", "other", `{"apiToken":"t","zoneDiscovery":"probe"}`)
+	require.NoError(t, solver.Present(other))
+	require.NoError(t, solver.CleanUp(other))
+	require.NoError(t, solver.CleanUp(ch))
+
+	var records []auditRecord
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var r auditRecord
+		require.NoError(t, json.Unmarshal([]byte(line), &r), line)
+		records = append(records, r)
+	}
+	var actions []string
+	for _, r := range records {
+		actions = append(actions, r.Action)
+		assert.Equal(t, now, r.Time)
+		assert.Equal(t, "example.com", r.Zone)
+		assert.Equal(t, "_acme-challenge.example.com", r.RRSet)
+		assert.Equal(t, txtType, r.Type)
+	}
+	// Failed attempts are recorded too, like adding the second value to the
+	// existing RRSet before it is updated.
+	assert.Equal(t, []string{auditCreate, auditCreate, auditUpdate, auditUpdate, auditDelete}, actions)
+	assert.Equal(t, "409: rrset already exists", records[1].Error)
+	assert.Empty(t, records[2].Error)
+	assert.Equal(t, auditActor{Account: accountKey(defaultAPIURL, "t"), Namespace: "team-a",
+		Challenge: "4b1c0c41-5d0e-4f53-9f4e-2c1d0a6f2d11", FQDN: "_acme-challenge.example.com."}, records[0].auditActor)
+	// The hashes tell the values apart without revealing them.
+	assert.Equal(t, records[0].ValuesHash, records[3].ValuesHash)
+	assert.NotEqual(t, records[0].ValuesHash, records[2].ValuesHash)
+	assert.Empty(t, records[4].ValuesHash)
+	assert.NotContains(t, out.String(), "key")
+}
+
+func TestAuditLogFailure(t *testing.T) {
+	var out bytes.Buffer
+	log := &auditLog{w: &out, now: time.Now}
+	api := log.wrap(readOnlyAPI{newMockSDK("example.com")}, auditActor{Account: "a"})
+	err := api.CreateRRSet(t.Context(), "example.com", "_acme-challenge.example.com", txtType,
+		dnssdk.RRSet{TTL: 60, Records: []dnssdk.ResourceRecord{{Content: []any{"key"}}}})
+	require.Error(t, err)
+
+	var r auditRecord
+	require.NoError(t, json.Unmarshal(out.Bytes(), &r))
+	assert.Equal(t, auditCreate, r.Action)
+	assert.Equal(t, "a", r.Account)
+	assert.Equal(t, err.Error(), r.Error)
+}
+
+func TestAuditLogFromEnv(t *testing.T) {
+	t.Setenv(auditLogEnvVar, "")
+	log, err := auditLogFromEnv()
+	require.NoError(t, err)
+	assert.Nil(t, log)
+	assert.Equal(t, dnsAPI(readOnlyAPI{}), log.wrap(readOnlyAPI{}, auditActor{}))
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	require.NoError(t, os.WriteFile(path, []byte("{}\n"), 0o600))
+	t.Setenv(auditLogEnvVar, path)
+	log, err = auditLogFromEnv()
+	require.NoError(t, err)
+	log.record(auditRecord{Action: auditDelete, Zone: "example.com"}, &dnssdk.APIError{StatusCode: http.StatusForbidden,
+		Message: "token=abc is read-only"})
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[1], `"action":"delete","zone":"example.com","account":""`)
+	assert.Contains(t, lines[1], `token=[REDACTED]`)
+
+	t.Setenv(auditLogEnvVar, filepath.Join(t.TempDir(), "missing", "audit.log"))
+	_, err = auditLogFromEnv()
+	assert.ErrorContains(t, err, "open GCORE_AUDIT_LOG")
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+)
+
+const (
+	authModePermanent = "permanent"
+	authModeBearer    = "bearer"
+
+	defaultAuthURL = "https://api.gcore.com/iam"
+	// bearerRenewBefore is how long before its expiry an access token is renewed.
+	bearerRenewBefore = time.Minute
+	// bearerTokensTTL bounds how long an idle bearer token source is kept.
+	bearerTokensTTL = 24 * time.Hour
+)
+
+// bearerToken hands out short-lived access tokens obtained from the G-Core
+// auth endpoint with a refresh token, and renews them before they expire.
+type bearerToken struct {
+	authURL string
+	client  *http.Client
+	now     func() time.Time
+
+	// username and password log in again when there is no refresh token
+	// or it was rejected, e.g. because it expired too.
+	username string
+	password string
+
+	mu      sync.Mutex
+	refresh string
+	access  string
+	expiry  time.Time
+}
+
+func newBearerToken(authURL, refresh string, timeout time.Duration) *bearerToken {
+	return &bearerToken{
+		authURL: strings.TrimSuffix(authURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+		now:     time.Now,
+		refresh: refresh,
+	}
+}
+
+// newLoginToken returns a token source that logs in with a username and
+// password, for accounts that have no API token.
+func newLoginToken(authURL, username, password string, timeout time.Duration) *bearerToken {
+	b := newBearerToken(authURL, "", timeout)
+	b.username, b.password = username, password
+	return b
+}
+
+// Token returns a valid access token, renewing it when it is about to expire.
+func (b *bearerToken) Token(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.access != "" && b.now().Add(bearerRenewBefore).Before(b.expiry) {
+		return b.access, nil
+	}
+	if b.refresh != "" {
+		err := b.exchange(ctx, "refresh bearer token", "/auth/jwt/refresh", map[string]string{"refresh": b.refresh})
+		if err == nil {
+			return b.access, nil
+		}
+		if b.username == "" || !isAuthRejected(err) {
+			return "", err
+		}
+	}
+	if b.username == "" {
+		return "", errors.New("refresh bearer token: no refresh token")
+	}
+	err := b.exchange(ctx, "log in", "/auth/jwt/login", map[string]string{"username": b.username, "password": b.password})
+	if err != nil {
+		return "", err
+	}
+	return b.access, nil
+}
+
+// isAuthRejected reports whether the auth API refused the credentials.
+func isAuthRejected(err error) bool {
+	var apiErr dnssdk.APIError
+	return errors.As(err, &apiErr) &&
+		(apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
+
+// exchange posts credentials to an auth endpoint, the refresh or the login
+// one, and keeps the access token of the response. A refresh token returned along with it
+// replaces the current one.
+// https://api.gcore.com/docs/iam#tag/Authentication
+func (b *bearerToken) exchange(ctx context.Context, action, endpoint string, credentials map[string]string) error {
+	body, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.authURL+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s: read response body: %w", action, err)
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%s: %w", action,
+			dnssdk.APIError{StatusCode: resp.StatusCode, Message: string(respBody)})
+	}
+	var tokens struct {
+		Access  string `json:"access"`
+		Refresh string `json:"refresh"`
+	}
+	if err := json.Unmarshal(respBody, &tokens); err != nil {
+		return fmt.Errorf("%s: decode response: %w", action, err)
+	}
+	if tokens.Access == "" {
+		return fmt.Errorf("%s: response holds no access token", action)
+	}
+	expiry, err := jwtExpiry(tokens.Access)
+	if err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+	b.access, b.expiry = tokens.Access, expiry
+	if tokens.Refresh != "" {
+		b.refresh = tokens.Refresh
+	}
+	return nil
+}
+
+// jwtExpiry returns the exp claim of a JWT. The signature is not checked,
+// the token is only inspected to know when to renew it.
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("access token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("decode access token: %w", err)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, errors.New("access token has no exp claim")
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
+
+// bearerTransport authenticates every request with a current access token,
+// overriding the Authorization header set by the SDK.
+type bearerTransport struct {
+	base  http.RoundTripper
+	token *bearerToken
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.token.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+// bearerTokenFor returns the token source of a refresh token, shared by the
+// challenges using it so access tokens are reused until they expire.
+func (c *gcoreDNSProviderSolver) bearerTokenFor(cfg gcoreDNSProviderConfig, refresh string) (*bearerToken, error) {
+	key := accountKey(cfg.AuthURL, refresh)
+	c.bearerMu.Lock()
+	defer c.bearerMu.Unlock()
+	if token, ok := c.bearerTokens.Get(key); ok {
+		return token, nil
+	}
+	transport, err := cfg.apiTransport()
+	if err != nil {
+		return nil, err
+	}
+	token := newBearerToken(cfg.AuthURL, refresh, time.Duration(cfg.Timeout)*time.Second)
+	token.client.Transport = transport
+	c.bearerTokens.Set(key, token)
+	return token, nil
+}
+
+// loginTokenFor returns the token source of a username and password, shared
+// like the bearer mode ones.
+func (c *gcoreDNSProviderSolver) loginTokenFor(cfg gcoreDNSProviderConfig, password string) (*bearerToken, error) {
+	key := accountKey(cfg.AuthURL, cfg.Login.Username+"\x00"+password)
+	c.bearerMu.Lock()
+	defer c.bearerMu.Unlock()
+	if token, ok := c.bearerTokens.Get(key); ok {
+		return token, nil
+	}
+	transport, err := cfg.apiTransport()
+	if err != nil {
+		return nil, err
+	}
+	token := newLoginToken(cfg.AuthURL, cfg.Login.Username, password, time.Duration(cfg.Timeout)*time.Second)
+	token.client.Transport = transport
+	c.bearerTokens.Set(key, token)
+	return token, nil
+}
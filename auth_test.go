@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testJWT returns an unsigned JWT expiring at exp.
+func testJWT(name string, exp time.Time) string {
+	payload, _ := json.Marshal(map[string]any{"sub": name, "exp": exp.Unix()})
+	return "e30." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
+// authServer serves the refresh and login endpoints, handing out access-1,
+// access-2... valid for ttl, and a rotated refresh token.
+type authServer struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	refresh  string
+	password string
+	renewals int
+	logins   int
+}
+
+func (a *authServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var body struct {
+		Refresh  string `json:"refresh"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	decoded := json.NewDecoder(r.Body).Decode(&body) == nil
+	switch {
+	case decoded && r.URL.Path == "/iam/auth/jwt/refresh" && body.Refresh == a.refresh:
+	case decoded && r.URL.Path == "/iam/auth/jwt/login" && a.password != "" &&
+		body.Username == "admin" && body.Password == a.password:
+		a.logins++
+	default:
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"invalid credentials"}`))
+		return
+	}
+	a.renewals++
+	a.refresh = fmt.Sprintf("refresh-%d", a.renewals)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"access":  testJWT(fmt.Sprintf("access-%d", a.renewals), time.Now().Add(a.ttl)),
+		"refresh": a.refresh,
+	})
+}
+
+func TestBearerToken(t *testing.T) {
+	auth := &authServer{ttl: time.Hour, refresh: "refresh-0"}
+	server := httptest.NewServer(auth)
+	t.Cleanup(server.Close)
+
+	token := newBearerToken(server.URL+"/iam/", "refresh-0", time.Second)
+	first, err := token.Token(context.Background())
+	require.NoError(t, err)
+	again, err := token.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+	assert.Equal(t, 1, auth.renewals)
+
+	// Renewed shortly before expiry with the rotated refresh token.
+	token.now = func() time.Time { return time.Now().Add(time.Hour - 30*time.Second) }
+	renewed, err := token.Token(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, first, renewed)
+	assert.Equal(t, 2, auth.renewals)
+
+	_, err = newBearerToken(server.URL+"/iam", "bad", time.Second).Token(context.Background())
+	assert.ErrorContains(t, err, "401")
+}
+
+func TestLoginToken(t *testing.T) {
+	auth := &authServer{ttl: time.Hour, password: "secret"}
+	server := httptest.NewServer(auth)
+	t.Cleanup(server.Close)
+
+	token := newLoginToken(server.URL+"/iam", "admin", "secret", time.Second)
+	first, err := token.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, auth.logins)
+
+	// Renewed with the refresh token while it is valid.
+	token.now = func() time.Time { return time.Now().Add(time.Hour) }
+	renewed, err := token.Token(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, first, renewed)
+	assert.Equal(t, 1, auth.logins)
+
+	// Logs in again once the refresh token is rejected.
+	auth.refresh = "expired"
+	relogged, err := token.Token(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, renewed, relogged)
+	assert.Equal(t, 2, auth.logins)
+
+	_, err = newLoginToken(server.URL+"/iam", "admin", "wrong", time.Second).Token(context.Background())
+	assert.ErrorContains(t, err, "log in: ")
+	assert.ErrorContains(t, err, "401")
+}
+
+func Test_jwtExpiry(t *testing.T) {
+	exp := time.Unix(1700000000, 0)
+	got, err := jwtExpiry(testJWT("a", exp))
+	require.NoError(t, err)
+	assert.Equal(t, exp, got)
+
+	_, err = jwtExpiry("opaque-token")
+	assert.ErrorContains(t, err, "not a JWT")
+	_, err = jwtExpiry("e30.e30.sig")
+	assert.ErrorContains(t, err, "no exp claim")
+}
+
+func TestPresentBearerAuth(t *testing.T) {
+	auth := &authServer{ttl: time.Hour, refresh: "refresh-0"}
+	var authHeaders []string
+	mux := http.NewServeMux()
+	mux.Handle("/iam/", auth)
+	mux.Handle("/dns/v2/zones/example.com", rejectingAPI("Authorization", &authHeaders))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	solver := &gcoreDNSProviderSolver{bearerTokens: newCache[string, *bearerToken](time.Hour, 10)}
+	cfg := fmt.Sprintf(`{"apiToken":"refresh-0","authMode":"bearer","apiUrl":%q,"authUrl":%q}`,
+		server.URL+"/dns", server.URL+"/iam")
+	for i := 0; i < 2; i++ {
+		err := solver.Present(challenge("_acme-challenge.example.com.", "key", cfg))
+		assert.ErrorContains(t, err, "stop here")
+	}
+	require.Len(t, authHeaders, 2)
+	assert.Regexp(t, `^Bearer e30\.`, authHeaders[0])
+	assert.Equal(t, authHeaders[0], authHeaders[1])
+	assert.Equal(t, 1, auth.renewals)
+
+	assert.ErrorContains(t, gcoreDNSProviderConfig{AuthMode: "oauth"}.Validate(), "authMode")
+}
+
+func TestPresentLogin(t *testing.T) {
+	auth := &authServer{ttl: time.Hour, password: "secret"}
+	var authHeaders []string
+	mux := http.NewServeMux()
+	mux.Handle("/iam/", auth)
+	mux.Handle("/dns/v2/zones/example.com", rejectingAPI("Authorization", &authHeaders))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	solver := &gcoreDNSProviderSolver{
+		client: fake.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{Name: "gcore-login", Namespace: "default"},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		}),
+		bearerTokens: newCache[string, *bearerToken](time.Hour, 10),
+	}
+	cfg := fmt.Sprintf(`{"login":{"username":"admin","passwordSecretRef":{"name":"gcore-login","key":"password"}},`+
+		`"apiUrl":%q,"authUrl":%q}`, server.URL+"/dns", server.URL+"/iam")
+	for i := 0; i < 2; i++ {
+		ch := challenge("_acme-challenge.example.com.", "key", cfg)
+		ch.ResourceNamespace = "default"
+		assert.ErrorContains(t, solver.Present(ch), "stop here")
+	}
+	require.Len(t, authHeaders, 2)
+	assert.Regexp(t, `^Bearer e30\.`, authHeaders[0])
+	assert.Equal(t, authHeaders[0], authHeaders[1])
+	assert.Equal(t, 1, auth.logins)
+}
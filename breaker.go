@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Defaults of GCORE_CIRCUIT_BREAKER_THRESHOLD and
+// GCORE_CIRCUIT_BREAKER_COOLDOWN.
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// errProviderUnavailable marks calls failed fast because the circuit breaker
+// of the API is open.
+var errProviderUnavailable = errors.New("G-Core API provider unavailable")
+
+// circuitBreaker stops calls to an API that failed threshold times in a row
+// for cooldown, so pending challenges fail fast instead of each adding its
+// timeouts and retries to the load of a struggling API. Once cooldown is
+// over a single call is let through, closing the breaker if it succeeds and
+// opening it again if not.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether a call may be made now, and if not how long the
+// breaker stays open.
+func (b *circuitBreaker) allow(now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true, 0
+	}
+	if now.Before(b.openUntil) {
+		return false, b.openUntil.Sub(now)
+	}
+	if b.probing {
+		return false, b.cooldown
+	}
+	b.probing = true
+	return true, 0
+}
+
+// abort ends a call let through by allow without counting it, e.g. because
+// its context was done before the API answered, so that the next call after
+// the cooldown is let through as the trial.
+func (b *circuitBreaker) abort() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// record counts the outcome of a call let through by allow.
+func (b *circuitBreaker) record(failed bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+	}
+}
+
+// isProviderFailure reports whether err counts against the breaker: the API
+// could not be reached or failed transiently. Rate limits and client errors
+// show the API is up.
+func isProviderFailure(err error) bool {
+	return isUnreachable(err) || isRetryable(err) && !isRateLimited(err)
+}
+
+// circuitBreakers holds a circuitBreaker per API URL, shared by all
+// challenges using it. Its zero value has breakers disabled.
+type circuitBreakers struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+// get returns the breaker of apiURL, nil when breakers are disabled.
+func (c *circuitBreakers) get(apiURL string) *circuitBreaker {
+	if c.threshold <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.breakers == nil {
+		c.breakers = map[string]*circuitBreaker{}
+	}
+	breaker, ok := c.breakers[apiURL]
+	if !ok {
+		breaker = &circuitBreaker{threshold: c.threshold, cooldown: c.cooldown}
+		c.breakers[apiURL] = breaker
+	}
+	return breaker
+}
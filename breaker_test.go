@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	breaker := &circuitBreaker{threshold: 2, cooldown: time.Minute}
+
+	breaker.record(true, now)
+	ok, _ := breaker.allow(now)
+	assert.True(t, ok, "below the threshold calls pass")
+	breaker.record(false, now)
+	breaker.record(true, now)
+	ok, _ = breaker.allow(now)
+	assert.True(t, ok, "a success resets the failure count")
+
+	breaker.record(true, now)
+	ok, open := breaker.allow(now.Add(time.Second))
+	assert.False(t, ok)
+	assert.Equal(t, 59*time.Second, open)
+
+	later := now.Add(time.Minute)
+	ok, _ = breaker.allow(later)
+	assert.True(t, ok, "one call is let through after the cooldown")
+	ok, _ = breaker.allow(later)
+	assert.False(t, ok, "other calls wait for its outcome")
+	breaker.record(true, later)
+	ok, _ = breaker.allow(later.Add(time.Second))
+	assert.False(t, ok, "a failed trial opens the breaker again")
+
+	later = later.Add(time.Minute)
+	ok, _ = breaker.allow(later)
+	require.True(t, ok)
+	breaker.record(false, later)
+	ok, _ = breaker.allow(later)
+	assert.True(t, ok, "a successful trial closes the breaker")
+	ok, _ = breaker.allow(later)
+	assert.True(t, ok)
+}
+
+func TestCircuitBreakerAbortedTrial(t *testing.T) {
+	breaker := &circuitBreaker{threshold: 1, cooldown: time.Minute}
+	breaker.record(true, time.Now().Add(-time.Hour))
+	held := semaphore.NewWeighted(1)
+	require.True(t, held.TryAcquire(1))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, api := range []*retryingAPI{
+		// The trial call fails because its context is done.
+		{api: &flakyAPI{mockSDK: newMockSDK("example.com"), failures: 1,
+			err: &url.Error{Op: "Get", URL: "https://api.gcore.com", Err: context.Canceled}}, breaker: breaker},
+		// The trial call gives up waiting for an API call slot.
+		{api: newMockSDK("example.com"), breaker: breaker, limiter: held},
+	} {
+		_, err := api.Zone(ctx, "example.com")
+		assert.ErrorIs(t, err, context.Canceled)
+		ok, _ := breaker.allow(time.Now())
+		assert.True(t, ok, "an aborted trial lets the next call through")
+		breaker.abort()
+	}
+}
+
+func TestIsProviderFailure(t *testing.T) {
+	assert.True(t, isProviderFailure(syscall.ECONNREFUSED))
+	assert.True(t, isProviderFailure(syscall.ECONNRESET))
+	assert.True(t, isProviderFailure(dnssdk.APIError{StatusCode: http.StatusBadGateway}))
+	assert.False(t, isProviderFailure(dnssdk.APIError{StatusCode: http.StatusTooManyRequests}))
+	assert.False(t, isProviderFailure(dnssdk.APIError{StatusCode: http.StatusNotFound}))
+}
+
+func TestPresentCircuitBreaker(t *testing.T) {
+	setForTest(t, &retryBackoff, time.Millisecond)
+	flaky := &flakyAPI{mockSDK: newMockSDK("example.com"), failures: 100,
+		err: dnssdk.APIError{StatusCode: http.StatusServiceUnavailable, Message: "unavailable"}}
+	solver := &gcoreDNSProviderSolver{
+		newSDK:   func(gcoreDNSProviderConfig, string) (dnsAPI, error) { return flaky, nil },
+		breakers: circuitBreakers{threshold: 3, cooldown: time.Minute},
+	}
+	cfg := `{"apiToken":"t","zoneDiscovery":"probe"}`
+
+	err := solver.Present(challenge("_acme-challenge.example.com.", "key", cfg))
+	assert.ErrorContains(t, err, "unavailable")
+	assert.NotErrorIs(t, err, errProviderUnavailable)
+	failed := 100 - flaky.failures
+
+	err = solver.Present(challenge("_acme-challenge.example.com.", "key", cfg))
+	assert.ErrorIs(t, err, errProviderUnavailable)
+	assert.ErrorContains(t, err, "circuit breaker open")
+	assert.Equal(t, failed, 100-flaky.failures, "no call reaches the API while the breaker is open")
+
+	other := &retryingAPI{api: flaky, apiURL: "https://other.example", breaker: solver.breakers.get("https://other.example")}
+	_, err = other.Zone(context.Background(), "example.com")
+	assert.NotErrorIs(t, err, errProviderUnavailable, "breakers are kept per API URL")
+	assert.Nil(t, (&circuitBreakers{}).get("https://api.gcore.com"), "a zero threshold disables breakers")
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCacheTTL         = 5 * time.Minute
+	defaultCacheMaxEntries  = 1000
+	defaultNegativeCacheTTL = 30 * time.Second
+)
+
+// cacheStats counts lookups and evictions of a cache.
+type cacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+}
+
+// HitRate returns the share of lookups that were hits, 0 before the first one.
+func (s cacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// cache is a thread-safe map whose entries expire after ttl and which evicts
+// the least recently used entry once it holds maxEntries. A nil cache never
+// hits and ignores writes, so caching can be switched off by leaving it unset.
+type cache[K comparable, V any] struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	order      *list.List // front is the most recently used entry
+	items      map[K]*list.Element
+	stats      cacheStats
+	now        func() time.Time
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+func newCache[K comparable, V any](ttl time.Duration, maxEntries int) *cache[K, V] {
+	return &cache[K, V]{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		items:      map[K]*list.Element{},
+		now:        time.Now,
+	}
+}
+
+// Get returns the value stored for key if it has not expired yet.
+func (c *cache[K, V]) Get(key K) (V, bool) {
+	var zero V
+	if c == nil {
+		return zero, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		return zero, false
+	}
+	entry := elem.Value.(*cacheEntry[K, V])
+	if !c.now().Before(entry.expires) {
+		c.removeElement(elem)
+		c.stats.Misses++
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	c.stats.Hits++
+	return entry.value, true
+}
+
+// Set stores value for key, evicting the least recently used entry if the
+// cache is full.
+func (c *cache[K, V]) Set(key K, value V) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := c.now().Add(c.ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*cacheEntry[K, V])
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&cacheEntry[K, V]{key: key, value: value, expires: expires})
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.removeElement(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// Delete drops the entry for key.
+func (c *cache[K, V]) Delete(key K) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// DeleteFunc drops every entry for which match returns true and returns how
+// many were dropped.
+func (c *cache[K, V]) DeleteFunc(match func(key K, value V) bool) int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	deleted := 0
+	for _, elem := range c.items {
+		entry := elem.Value.(*cacheEntry[K, V])
+		if match(entry.key, entry.value) {
+			c.removeElement(elem)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// Each calls fn with every entry that has not expired, most recently used
+// first. fn must not use the cache.
+func (c *cache[K, V]) Each(fn func(key K, value V, expires time.Time)) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*cacheEntry[K, V])
+		if now.Before(entry.expires) {
+			fn(entry.key, entry.value, entry.expires)
+		}
+	}
+}
+
+// Stats returns the lookup counters and the current number of entries.
+func (c *cache[K, V]) Stats() cacheStats {
+	if c == nil {
+		return cacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
+
+func (c *cache[K, V]) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*cacheEntry[K, V]).key)
+}
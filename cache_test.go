@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheExpiry(t *testing.T) {
+	now := time.Now()
+	c := newCache[string, int](time.Minute, 10)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1)
+	got, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, got)
+
+	now = now.Add(time.Minute)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, cacheStats{Hits: 1, Misses: 1}, c.Stats())
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := newCache[string, int](time.Minute, 2)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	// touch a so that b becomes the least recently used entry
+	_, _ = c.Get("a")
+	c.Set("c", 3)
+
+	_, ok := c.Get("b")
+	assert.False(t, ok, "least recently used entry should be evicted")
+	_, ok = c.Get("a")
+	assert.True(t, ok)
+	_, ok = c.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), c.Stats().Evictions)
+	assert.Equal(t, 2, c.Stats().Size)
+}
+
+func TestCacheNil(t *testing.T) {
+	var c *cache[string, int]
+	c.Set("a", 1)
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	c.Delete("a")
+	assert.Equal(t, cacheStats{}, c.Stats())
+	c.Each(func(string, int, time.Time) { t.Fatal("nil cache has entries") })
+}
+
+func TestCacheEach(t *testing.T) {
+	now := time.Now()
+	c := newCache[string, int](time.Minute, 10)
+	c.now = func() time.Time { return now }
+	c.Set("a", 1)
+	now = now.Add(30 * time.Second)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	_, _ = c.Get("b")
+
+	now = now.Add(45 * time.Second)
+	var keys []string
+	c.Each(func(key string, value int, expires time.Time) {
+		keys = append(keys, fmt.Sprintf("%s=%d", key, value))
+		assert.Equal(t, now.Add(15*time.Second), expires)
+	})
+	// a expired, and b was used last.
+	assert.Equal(t, []string{"b=2", "c=3"}, keys)
+}
+
+func TestCacheConcurrency(t *testing.T) {
+	c := newCache[string, int](time.Minute, 50)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				key := fmt.Sprint(j % 100)
+				c.Set(key, i)
+				_, _ = c.Get(key)
+				if j%10 == 0 {
+					c.Delete(key)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stats := c.Stats()
+	assert.LessOrEqual(t, stats.Size, 50)
+	assert.Equal(t, uint64(8*1000), stats.Hits+stats.Misses)
+}
+
+func TestSolverZoneCache(t *testing.T) {
+	mock := newMockSDK("example.com", "example.org")
+	solver := solverWithMock(mock)
+	solver.zones = newCache[zoneCacheKey, zoneDetails](time.Minute, 10)
+	cfg := `{"apiToken":"t"}`
+
+	calls := func() int { return mock.zoneLookups + mock.listCalls }
+
+	assert.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "token-A", cfg)))
+	lookups := calls()
+	assert.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "token-B", cfg)))
+	assert.Equal(t, lookups, calls(), "cached zone should not be looked up again")
+	assert.Equal(t, uint64(1), solver.zones.Stats().Hits)
+
+	// A deeper name is looked up once, it may be in a child zone.
+	assert.NoError(t, solver.Present(challenge("_acme-challenge.www.example.com.", "token-B", cfg)))
+	assert.Equal(t, lookups+1, calls())
+	assert.Equal(t, uint64(1), solver.zones.Stats().Hits)
+	lookups = calls()
+	assert.NoError(t, solver.Present(challenge("_acme-challenge.www.example.com.", "token-C", cfg)))
+	assert.Equal(t, lookups, calls(), "cached zone should not be looked up again")
+	assert.Equal(t, uint64(2), solver.zones.Stats().Hits)
+
+	// another account does not share the cached zones
+	assert.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "token-C", `{"apiToken":"other"}`)))
+	assert.Greater(t, calls(), lookups)
+
+	// probed zones are cached as well: a deeper name only probes the
+	// candidate below the cached zone
+	probe := `{"apiToken":"t","zoneDiscovery":"probe"}`
+	assert.NoError(t, solver.Present(challenge("_acme-challenge.example.org.", "token-D", probe)))
+	lookups, hits := calls(), solver.zones.Stats().Hits
+	assert.NoError(t, solver.Present(challenge("_acme-challenge.example.org.", "token-E", probe)))
+	assert.Equal(t, lookups, calls(), "cached zone should not be looked up again")
+	assert.Equal(t, hits+1, solver.zones.Stats().Hits)
+	assert.NoError(t, solver.Present(challenge("_acme-challenge.www.example.org.", "token-F", probe)))
+	assert.Equal(t, lookups+1, calls())
+	assert.Equal(t, hits+2, solver.zones.Stats().Hits)
+}
+
+func TestCacheDeleteFunc(t *testing.T) {
+	c := newCache[string, int](time.Minute, 10)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	assert.Equal(t, 2, c.DeleteFunc(func(_ string, v int) bool { return v != 2 }))
+	assert.Equal(t, 1, c.Stats().Size)
+	_, ok := c.Get("b")
+	assert.True(t, ok)
+	_, _ = c.Get("a")
+	assert.InDelta(t, 0.5, c.Stats().HitRate(), 0.001)
+
+	var disabled *cache[string, int]
+	assert.Zero(t, disabled.DeleteFunc(func(string, int) bool { return true }))
+	assert.Zero(t, disabled.Stats().HitRate())
+}
+
+func TestSolverZoneCacheForgetsMissingZone(t *testing.T) {
+	mock := newMockSDK("example.com")
+	solver := solverWithMock(mock)
+	solver.zones = newCache[zoneCacheKey, zoneDetails](time.Minute, 10)
+	cfg := `{"apiToken":"t"}`
+	require.NoError(t, solver.Present(challenge("_acme-challenge.sub.example.com.", "token-A", cfg)))
+
+	// The cached zone is deleted and the name delegated to a zone of its
+	// own, so the write fails once and the zone is looked up again by the
+	// next attempt.
+	delete(mock.zones, "example.com")
+	mock.zones["sub.example.com"] = newMockSDK("sub.example.com").zones["sub.example.com"]
+	err := solver.Present(challenge("_acme-challenge.sub.example.com.", "token-B", cfg))
+	assert.ErrorContains(t, err, "zone not found")
+	require.NoError(t, solver.Present(challenge("_acme-challenge.sub.example.com.", "token-B", cfg)))
+	assert.Equal(t, []string{"token-B"}, mock.contents("sub.example.com", "_acme-challenge.sub.example.com"))
+}
+
+func TestSolverMissingZoneCache(t *testing.T) {
+	for _, cfg := range []string{
+		`{"apiToken":"t"}`,
+		`{"apiToken":"t","zoneDiscovery":"probe"}`,
+		`{"apiToken":"t","zoneDiscovery":"list"}`,
+		`{"apiToken":"t","zoneName":"example.org"}`,
+	} {
+		t.Run(cfg, func(t *testing.T) {
+			mock := newMockSDK("example.com")
+			solver := solverWithMock(mock)
+			solver.missingZones = newCache[zoneCacheKey, error](time.Minute, 10)
+			calls := func() int { return mock.zoneLookups + mock.listCalls }
+
+			err := solver.Present(challenge("_acme-challenge.www.example.org.", "token-A", cfg))
+			require.ErrorIs(t, err, errZoneNotFound)
+			lookups := calls()
+			err = solver.Present(challenge("_acme-challenge.www.example.org.", "token-A", cfg))
+			require.ErrorIs(t, err, errZoneNotFound)
+			assert.Equal(t, lookups, calls(), "missing zone should not be looked up again")
+
+			// The entry is per record name and expires, so a zone created
+			// meanwhile is found by the next attempt after the TTL.
+			mock.zones["example.org"] = newMockSDK("example.org").zones["example.org"]
+			solver.missingZones.now = func() time.Time { return time.Now().Add(time.Minute) }
+			require.NoError(t, solver.Present(challenge("_acme-challenge.www.example.org.", "token-A", cfg)))
+		})
+	}
+}
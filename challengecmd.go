@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/google/uuid"
+	"github.com/miekg/dns"
+	"github.com/spf13/pflag"
+	"golang.org/x/sync/semaphore"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+)
+
+// The present and cleanup subcommands run a single Present or CleanUp call
+// outside of Kubernetes, through the same solver code as the webhook, to
+// reproduce issuance problems.
+const (
+	presentCommand = "present"
+	cleanupCommand = "cleanup"
+)
+
+// challengeCommand is the challenge given to the present or cleanup
+// subcommand.
+type challengeCommand struct {
+	fqdn   string
+	key    string
+	zone   string
+	issuer issuerFlags
+}
+
+// isChallengeCommand reports whether args, without the program name, run
+// the present or cleanup subcommand.
+func isChallengeCommand(args []string) bool {
+	return len(args) > 0 && (args[0] == presentCommand || args[0] == cleanupCommand)
+}
+
+// runChallengeCommand runs the present or cleanup subcommand of args,
+// without the program name, and returns the exit code.
+func runChallengeCommand(args []string, stdout, stderr io.Writer) int {
+	op := args[0]
+	solver, ch, err := parseChallengeCommand(op, args[1:], stderr)
+	if errors.Is(err, pflag.ErrHelp) {
+		return 0
+	}
+	if err == nil {
+		err = runChallenge(op, solver, ch)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "%s: %v\n", op, err)
+		return 1
+	}
+	_, _ = fmt.Fprintf(stdout, "%s %s: done\n", op, ch.ResolvedFQDN)
+	return 0
+}
+
+// parseChallengeCommand returns the solver and the challenge of the present
+// or cleanup subcommand op with args.
+func parseChallengeCommand(op string, args []string, stderr io.Writer) (*gcoreDNSProviderSolver,
+	*v1alpha1.ChallengeRequest, error) {
+	settings, args, err := cutSolverFlags(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cmd challengeCommand
+	flags := commandFlagSet(op, stderr)
+	flags.StringVar(&cmd.fqdn, "fqdn", "", "FQDN of the TXT record, e.g. _acme-challenge.example.com")
+	flags.StringVar(&cmd.key, "key", "", "value of the TXT record")
+	flags.StringVar(&cmd.zone, "zone", "", "zone cert-manager resolved for the record, only logged and traced")
+	cmd.issuer.add(flags)
+	if err := parseCommandFlags(flags, args); err != nil {
+		return nil, nil, err
+	}
+	ch, err := cmd.request(op)
+	if err != nil {
+		return nil, nil, err
+	}
+	solver, err := settings.solver(cmd.issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+	return solver, ch, nil
+}
+
+// request returns the challenge request cert-manager would send for cmd.
+func (cmd challengeCommand) request(op string) (*v1alpha1.ChallengeRequest, error) {
+	if cmd.fqdn == "" || cmd.key == "" {
+		return nil, errors.New("--fqdn and --key are required")
+	}
+	ch := &v1alpha1.ChallengeRequest{
+		Action:       v1alpha1.ChallengeActionPresent,
+		ResolvedFQDN: dns.Fqdn(cmd.fqdn),
+		Key:          cmd.key,
+	}
+	if op == cleanupCommand {
+		ch.Action = v1alpha1.ChallengeActionCleanUp
+	}
+	if cmd.zone != "" {
+		ch.ResolvedZone = dns.Fqdn(cmd.zone)
+	}
+	return ch, cmd.issuer.apply(ch)
+}
+
+// runChallenge makes the Present or CleanUp call op for ch and flushes the
+// spans it traced.
+func runChallenge(op string, solver *gcoreDNSProviderSolver, ch *v1alpha1.ChallengeRequest) error {
+	defer solver.flushTraces()
+	if op == presentCommand {
+		return solver.Present(ch)
+	}
+	return solver.CleanUp(ch)
+}
+
+// issuerFlags describe the issuer a subcommand acts for.
+type issuerFlags struct {
+	namespace  string
+	config     string
+	kubeconfig string
+}
+
+func (f *issuerFlags) add(flags *pflag.FlagSet) {
+	flags.StringVar(&f.namespace, "namespace", "default", "namespace of the issuer, where its secrets are read")
+	flags.StringVar(&f.config, "config", "", "solver config of the issuer as JSON, or @file to read it from")
+	flags.StringVar(&f.kubeconfig, "kubeconfig", "", "kubeconfig file to read the secrets of the config with")
+}
+
+// apply sets the issuer's namespace and config on ch, which allows the
+// ambient GCORE_API_TOKEN.
+func (f issuerFlags) apply(ch *v1alpha1.ChallengeRequest) error {
+	ch.UID = types.UID(uuid.NewString())
+	ch.Type = "dns-01"
+	ch.ResourceNamespace = f.namespace
+	ch.AllowAmbientCredentials = true
+	config := f.config
+	if path, ok := strings.CutPrefix(config, "@"); ok {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read config: %w", err)
+		}
+		config = string(raw)
+	}
+	if strings.TrimSpace(config) != "" {
+		ch.Config = &extapi.JSON{Raw: []byte(config)}
+	}
+	return nil
+}
+
+// solverFlags are the webhook's flags the subcommands take as well.
+type solverFlags struct {
+	resolvers []string
+	apiCalls  *semaphore.Weighted
+	debugHTTP bool
+	dryRun    bool
+}
+
+// cutSolverFlags returns the --dns-resolvers, --max-concurrent-api-calls,
+// --debug-http and --dry-run flags of args, and args without them.
+func cutSolverFlags(args []string) (solverFlags, []string, error) {
+	var f solverFlags
+	var err error
+	if f.resolvers, args, err = dnsResolvers(args); err != nil {
+		return f, nil, err
+	}
+	if f.apiCalls, args, err = maxConcurrentAPICalls(args); err != nil {
+		return f, nil, err
+	}
+	if f.debugHTTP, args, err = debugHTTP(args); err != nil {
+		return f, nil, err
+	}
+	if f.dryRun, args, err = cutBoolFlag(args, dryRunFlag); err != nil {
+		return f, nil, err
+	}
+	return f, args, nil
+}
+
+// solver returns the solver of a subcommand, with the settings of the
+// environment and f, reading the secrets of issuer with its kubeconfig.
+func (f solverFlags) solver(issuer issuerFlags) (*gcoreDNSProviderSolver, error) {
+	solver, err := newSolver(f.resolvers, f.apiCalls, f.debugHTTP, false)
+	if err != nil {
+		return nil, err
+	}
+	solver.dryRun = f.dryRun
+	if issuer.kubeconfig != "" {
+		config, err := clientcmd.BuildConfigFromFlags("", issuer.kubeconfig)
+		if err != nil {
+			return nil, fmt.Errorf("kubeconfig: %w", err)
+		}
+		if solver.client, err = kubernetes.NewForConfig(config); err != nil {
+			return nil, fmt.Errorf("client: %w", err)
+		}
+	}
+	return solver, nil
+}
+
+// commandFlagSet returns the flags of subcommand name, with -v setting the
+// log verbosity.
+func commandFlagSet(name string, stderr io.Writer) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetOutput(stderr)
+	klogFlags := flag.NewFlagSet(name, flag.ContinueOnError)
+	klog.InitFlags(klogFlags)
+	flags.AddGoFlag(klogFlags.Lookup("v"))
+	return flags
+}
+
+// parseCommandFlags parses args, which take no arguments besides flags.
+func parseCommandFlags(flags *pflag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %q", flags.Args())
+	}
+	return nil
+}
+
+// flushTraces exports the spans of a subcommand before it exits.
+func (c *gcoreDNSProviderSolver) flushTraces() {
+	if c.tracing == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = c.tracing.Shutdown(ctx)
+}
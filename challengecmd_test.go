@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsChallengeCommand(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, isChallengeCommand([]string{"present", "--fqdn=x"}))
+	assert.True(t, isChallengeCommand([]string{"cleanup"}))
+	assert.False(t, isChallengeCommand([]string{"--secure-port=443", "present"}))
+	assert.False(t, isChallengeCommand(nil))
+}
+
+func TestParseChallengeCommand(t *testing.T) {
+	t.Parallel()
+
+	config := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(config, []byte(`{"apiToken":"t","ttl":120}`), 0o600))
+	var stderr bytes.Buffer
+	solver, ch, err := parseChallengeCommand(cleanupCommand, []string{"--fqdn", "_acme-challenge.example.com",
+		"--key=k", "--zone=example.com", "--namespace=team-a", "--config=@" + config,
+		"--dns-resolvers=192.0.2.53", "--debug-http"}, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "_acme-challenge.example.com.", ch.ResolvedFQDN)
+	assert.Equal(t, "example.com.", ch.ResolvedZone)
+	assert.Equal(t, "k", ch.Key)
+	assert.Equal(t, "team-a", ch.ResourceNamespace)
+	assert.Equal(t, v1alpha1.ChallengeActionCleanUp, ch.Action)
+	assert.True(t, ch.AllowAmbientCredentials)
+	assert.NotEmpty(t, ch.UID)
+	assert.JSONEq(t, `{"apiToken":"t","ttl":120}`, string(ch.Config.Raw))
+	assert.Equal(t, []string{"192.0.2.53:53"}, solver.dnsResolvers)
+	assert.True(t, solver.debugHTTP)
+	assert.Nil(t, solver.client)
+
+	// Without --config the challenge has no config, as with ambient
+	// credentials in a ClusterIssuer.
+	_, ch, err = parseChallengeCommand(presentCommand, []string{"--fqdn=_acme-challenge.example.com.", "--key=k"},
+		&stderr)
+	require.NoError(t, err)
+	assert.Nil(t, ch.Config)
+	assert.Equal(t, v1alpha1.ChallengeActionPresent, ch.Action)
+
+	_, _, err = parseChallengeCommand(presentCommand, []string{"--fqdn=_acme-challenge.example.com."}, &stderr)
+	assert.ErrorContains(t, err, "--fqdn and --key are required")
+	_, _, err = parseChallengeCommand(presentCommand, []string{"--fqdn=a.", "--key=k", "extra"}, &stderr)
+	assert.ErrorContains(t, err, `unexpected arguments ["extra"]`)
+	_, _, err = parseChallengeCommand(presentCommand, []string{"--fqdn=a.", "--key=k", "--config=@/nonexistent"},
+		&stderr)
+	assert.ErrorContains(t, err, "read config")
+	_, _, err = parseChallengeCommand(presentCommand, []string{"--fqdn=a.", "--key=k", "--kubeconfig=/nonexistent"},
+		&stderr)
+	assert.ErrorContains(t, err, "kubeconfig")
+}
+
+func TestRunChallengeCommandUsage(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, runChallengeCommand([]string{"present", "--help"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "--fqdn")
+	assert.Contains(t, stderr.String(), "--kubeconfig")
+
+	stderr.Reset()
+	assert.Equal(t, 1, runChallengeCommand([]string{"cleanup", "--key=k"}, &stdout, &stderr))
+	assert.Equal(t, "cleanup: --fqdn and --key are required\n", stderr.String())
+	assert.Empty(t, stdout.String())
+}
+
+// TestRunChallenge checks that the subcommands make the webhook's Present
+// and CleanUp calls.
+func TestRunChallenge(t *testing.T) {
+	t.Parallel()
+
+	mock := newMockSDK("example.com")
+	solver := solverWithMock(mock)
+	_, ch, err := parseChallengeCommand(presentCommand, []string{"--fqdn=_acme-challenge.example.com",
+		"--key=k", `--config={"apiToken":"t"}`}, &bytes.Buffer{})
+	require.NoError(t, err)
+
+	require.NoError(t, runChallenge(presentCommand, solver, ch))
+	assert.Equal(t, []string{"k"}, mock.contents("example.com", "_acme-challenge.example.com"))
+	require.NoError(t, runChallenge(cleanupCommand, solver, ch))
+	assert.Nil(t, mock.contents("example.com", "_acme-challenge.example.com"))
+}
+
+func TestReadSecretWithoutClient(t *testing.T) {
+	t.Parallel()
+
+	mock := newMockSDK("example.com")
+	solver := solverWithMock(mock)
+	ch := challenge("_acme-challenge.example.com.", "k",
+		`{"apiKeySecretRef":{"name":"gcore","key":"token"}}`)
+	err := solver.Present(ch)
+	assert.ErrorContains(t, err, `/gcore" can't be read without a Kubernetes client`)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/miekg/dns"
+	"github.com/spf13/pflag"
+)
+
+// checkCommand diagnoses the credentials of an issuer for a zone: whether
+// the G-Core API accepts them, which zones they reach and whether they may
+// change the records of the zone.
+const checkCommand = "check"
+
+// maxListedZones is how many of the accessible zones the check prints.
+const maxListedZones = 10
+
+// runCheckCommand runs the check subcommand with args and returns the exit
+// code, non-zero when a step of the check fails.
+func runCheckCommand(args []string, stdout, stderr io.Writer) int {
+	solver, ch, err := parseCheckCommand(args, stderr)
+	if errors.Is(err, pflag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "%s: %v\n", checkCommand, err)
+		return 1
+	}
+	defer solver.flushTraces()
+	if !solver.diagnose(solver.challengeContext(ch), ch, stdout) {
+		return 1
+	}
+	return 0
+}
+
+// parseCheckCommand returns the solver of the check subcommand with args and
+// the challenge it would be asked to present for the zone to check.
+func parseCheckCommand(args []string, stderr io.Writer) (*gcoreDNSProviderSolver, *v1alpha1.ChallengeRequest,
+	error) {
+	settings, args, err := cutSolverFlags(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	var zone string
+	var issuer issuerFlags
+	flags := commandFlagSet(checkCommand, stderr)
+	flags.StringVar(&zone, "zone", "", "domain to check the write access for, e.g. example.com")
+	issuer.add(flags)
+	if err := parseCommandFlags(flags, args); err != nil {
+		return nil, nil, err
+	}
+	if zone == "" {
+		return nil, nil, errors.New("--zone is required")
+	}
+	ch := &v1alpha1.ChallengeRequest{
+		Action:       v1alpha1.ChallengeActionPresent,
+		ResolvedFQDN: "_acme-challenge." + dns.Fqdn(zone),
+		ResolvedZone: dns.Fqdn(zone),
+		Key:          checkCommand,
+	}
+	if err := issuer.apply(ch); err != nil {
+		return nil, nil, err
+	}
+	solver, err := settings.solver(issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+	return solver, ch, nil
+}
+
+// diagnose writes the outcome of each step of the check of ch to w, up to
+// the first failing one, and reports whether all of them passed. The write
+// access is checked with the probe record the webhook creates and deletes
+// before its first challenge in a zone.
+func (c *gcoreDNSProviderSolver) diagnose(ctx context.Context, ch *v1alpha1.ChallengeRequest, w io.Writer) bool {
+	report := func(step, result string) {
+		_, _ = fmt.Fprintf(w, "%-12s %s\n", step+":", result)
+	}
+	fail := func(step string, err error, hint string) bool {
+		report(step, "FAILED")
+		_, _ = fmt.Fprintf(w, "  %v\n", redactError(classifyError(err)))
+		if hint != "" {
+			_, _ = fmt.Fprintf(w, "  %s\n", hint)
+		}
+		return false
+	}
+
+	sdk, cfg, err := c.initSDK(ctx, ch)
+	if errors.Is(err, errNoConfig) {
+		return fail("credentials", err, "set "+apiTokenEnvVar+" or pass the solver config of the issuer with --config")
+	}
+	if err != nil {
+		return fail("credentials", err, "")
+	}
+	report("credentials", "ok, using the G-Core API at "+cfg.ApiUrl)
+
+	var names []string
+	count := 0
+	err = eachZone(ctx, sdk, dnssdk.ZonesParam{}, func(z dnssdk.Zone) bool {
+		if count++; len(names) < maxListedZones {
+			names = append(names, strings.Trim(z.Name, "."))
+		}
+		return true
+	})
+	if err != nil {
+		return fail("token", err, tokenHint(err, cfg))
+	}
+	listed := strings.Join(names, ", ")
+	if count > len(names) {
+		listed += fmt.Sprintf(" and %d more", count-len(names))
+	}
+	report("token", fmt.Sprintf("ok, %d zones accessible: %s", count, listed))
+
+	record := cfg.recordName(ch.ResolvedFQDN)
+	zone, err := c.detectZone(ctx, record, sdk, cfg)
+	if err == nil {
+		err = c.checkZone(cfg, zone)
+	}
+	if err != nil {
+		return fail("zone", err, "")
+	}
+	report("zone", fmt.Sprintf("ok, %s is in zone %s", record, zone))
+
+	if cfg.DryRun {
+		report("write", "skipped, dry run")
+		return true
+	}
+	if err := c.verifyWriteScope(ctx, sdk, cfg, zone); err != nil {
+		hint := ""
+		if errors.Is(err, errNoWriteScope) {
+			hint = "the token may read the zone but not change its records, grant it write access to DNS"
+		}
+		return fail("write", err, hint)
+	}
+	if cfg.ScratchZone != "" {
+		zone = asciiDomain(cfg.ScratchZone)
+	}
+	report("write", fmt.Sprintf("ok, created and deleted the TXT record %s.%s", writeProbeLabel, zone))
+	return true
+}
+
+// tokenHint tells what to do about err, returned when listing the zones.
+func tokenHint(err error, cfg gcoreDNSProviderConfig) string {
+	var apiErr dnssdk.APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized:
+		return "the token is invalid or expired, create a new permanent API token in the G-Core account"
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden:
+		return "the token may not list DNS zones, grant it access to DNS"
+	case errors.Is(err, errAPIUnreachable):
+		return "check that " + cfg.ApiUrl + " can be reached, e.g. through the proxy settings"
+	}
+	return ""
+}
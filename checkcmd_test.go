@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unauthorizedAPI rejects the token like the G-Core API does an expired one.
+type unauthorizedAPI struct {
+	*mockSDK
+}
+
+func (u unauthorizedAPI) ZonesWithParam(context.Context, dnssdk.ZonesParam) (dnssdk.ListZones, error) {
+	return dnssdk.ListZones{}, dnssdk.APIError{StatusCode: http.StatusUnauthorized, Message: "invalid token"}
+}
+
+// runDiagnose runs the check of zone with the args of the check subcommand
+// against api and returns its output.
+func runDiagnose(t *testing.T, api dnsAPI, args ...string) (string, bool) {
+	t.Helper()
+	solver, ch, err := parseCheckCommand(append([]string{`--config={"apiToken":"t"}`}, args...), &bytes.Buffer{})
+	require.NoError(t, err)
+	solver.newSDK = func(gcoreDNSProviderConfig, string) (dnsAPI, error) { return api, nil }
+	var out bytes.Buffer
+	ok := solver.diagnose(t.Context(), ch, &out)
+	return out.String(), ok
+}
+
+func TestDiagnose(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+		mock := newMockSDK("example.com", "example.org")
+		out, ok := runDiagnose(t, mock, "--zone=www.example.com")
+		assert.True(t, ok)
+		assert.Equal(t, "credentials: ok, using the G-Core API at https://api.gcore.com/dns\n"+
+			"token:       ok, 2 zones accessible: example.com, example.org\n"+
+			"zone:        ok, _acme-challenge.www.example.com is in zone example.com\n"+
+			"write:       ok, created and deleted the TXT record _cert-manager-write-probe.example.com\n", out)
+		assert.Nil(t, mock.contents("example.com", writeProbeLabel+".example.com"))
+	})
+
+	t.Run("many zones", func(t *testing.T) {
+		t.Parallel()
+		mock := newMockSDK("a.com", "b.com", "c.com", "d.com", "e.com", "f.com", "g.com", "h.com", "i.com",
+			"j.com", "k.com", "l.com")
+		out, ok := runDiagnose(t, mock, "--zone=l.com")
+		assert.True(t, ok)
+		assert.Contains(t, out, "token:       ok, 12 zones accessible: ")
+		assert.Contains(t, out, " and 2 more\n")
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		t.Parallel()
+		out, ok := runDiagnose(t, unauthorizedAPI{newMockSDK("example.com")}, "--zone=example.com")
+		assert.False(t, ok)
+		assert.Contains(t, out, "token:       FAILED\n  G-Core API rejected the credentials")
+		assert.Contains(t, out, "the token is invalid or expired")
+		assert.NotContains(t, out, "zone:")
+	})
+
+	t.Run("zone of another account", func(t *testing.T) {
+		t.Parallel()
+		out, ok := runDiagnose(t, newMockSDK("example.org"), "--zone=example.com")
+		assert.False(t, ok)
+		assert.Contains(t, out, "zone:        FAILED\n  no G-Core zone found for the record")
+		assert.NotContains(t, out, "write:")
+	})
+
+	t.Run("read-only token", func(t *testing.T) {
+		t.Parallel()
+		out, ok := runDiagnose(t, readOnlyAPI{newMockSDK("example.com")}, "--zone=example.com")
+		assert.False(t, ok)
+		assert.Contains(t, out, "write:       FAILED\n")
+		assert.Contains(t, out, "credential has no write access")
+		assert.Contains(t, out, "grant it write access to DNS")
+	})
+}
+
+func TestDiagnoseWithoutCredentials(t *testing.T) {
+	t.Parallel()
+
+	_, ch, err := parseCheckCommand([]string{"--zone=example.com"}, &bytes.Buffer{})
+	require.NoError(t, err)
+	// The ambient token is only used when GCORE_API_TOKEN is set.
+	ch.AllowAmbientCredentials = false
+	var out bytes.Buffer
+	assert.False(t, solverWithMock(newMockSDK("example.com")).diagnose(t.Context(), ch, &out))
+	assert.Contains(t, out.String(), "credentials: FAILED\n")
+	assert.Contains(t, out.String(), "set GCORE_API_TOKEN or pass the solver config of the issuer with --config\n")
+}
+
+func TestRunCheckCommandUsage(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, runCheckCommand([]string{"--help"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "--zone")
+	assert.Contains(t, stderr.String(), "--config")
+
+	stderr.Reset()
+	assert.Equal(t, 1, runCheckCommand(nil, &stdout, &stderr))
+	assert.Equal(t, "check: --zone is required\n", stderr.String())
+	assert.Empty(t, stdout.String())
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"path"
+	"strings"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+)
+
+// gcoreClient extends the SDK client with the API calls the SDK does not cover.
+type gcoreClient struct {
+	*dnssdk.Client
+	authHeader string
+}
+
+// zoneDetails holds the zone fields the SDK's Zone DTO drops.
+type zoneDetails struct {
+	Name    string         `json:"name"`
+	Meta    map[string]any `json:"meta"`
+	Enabled *bool          `json:"enabled,omitempty"`
+	Status  string         `json:"status,omitempty"`
+	// DNSSECEnabled reports whether G-Core signs the zone.
+	DNSSECEnabled bool `json:"dnssec_enabled,omitempty"`
+}
+
+// CloseIdleConnections drops pooled connections, e.g. after the API reset one.
+func (c *gcoreClient) CloseIdleConnections() {
+	c.HTTPClient.CloseIdleConnections()
+}
+
+// ZoneDetails gets the zone including its meta data.
+// https://apidocs.gcore.com/dns#tag/zones/operation/Zone
+func (c *gcoreClient) ZoneDetails(ctx context.Context, name string) (zoneDetails, error) {
+	name = strings.Trim(name, ".")
+	var zone zoneDetails
+	_, err := c.do(ctx, http.MethodGet, path.Join("/v2/zones", name), nil, &zone, nil)
+	if err != nil {
+		return zoneDetails{}, fmt.Errorf("get zone %s: %w", name, err)
+	}
+	return zone, nil
+}
+
+// EnableZone enables a disabled zone.
+// https://apidocs.gcore.com/dns#tag/zones/operation/EnableZone
+func (c *gcoreClient) EnableZone(ctx context.Context, name string) error {
+	name = strings.Trim(name, ".")
+	_, err := c.do(ctx, http.MethodPatch, path.Join("/v2/zones", name, "enable"), nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("enable zone %s: %w", name, err)
+	}
+	return nil
+}
+
+// rrsetVersion describes the state of an RRSet that was read: the ETag an
+// update is made conditional on, empty when the API does not version RRSets,
+// and the RRSet fields other than its records and TTL as the API returned
+// them. Those are written back verbatim, so pickers, filters and meta the
+// SDK's RRSet DTO lacks or only partly models survive adding a record.
+type rrsetVersion struct {
+	etag   string
+	fields map[string]json.RawMessage
+}
+
+// RRSetWithVersion gets an RRSet together with its version.
+func (c *gcoreClient) RRSetWithVersion(ctx context.Context, zone, name, recordType string) (dnssdk.RRSet, rrsetVersion, error) {
+	zone, name = strings.Trim(zone, "."), strings.Trim(name, ".")
+	var raw json.RawMessage
+	header, err := c.do(ctx, http.MethodGet, path.Join("/v2/zones", zone, name, recordType), nil, &raw, nil)
+	if err != nil {
+		return dnssdk.RRSet{}, rrsetVersion{}, fmt.Errorf("request %s -> %s: %w", zone, name, err)
+	}
+	var rrset dnssdk.RRSet
+	version := rrsetVersion{etag: header.Get("ETag")}
+	if err := json.Unmarshal(raw, &rrset); err != nil {
+		return dnssdk.RRSet{}, rrsetVersion{}, fmt.Errorf("decode rrset %s -> %s: %w", zone, name, err)
+	}
+	if err := json.Unmarshal(raw, &version.fields); err != nil {
+		return dnssdk.RRSet{}, rrsetVersion{}, fmt.Errorf("decode rrset %s -> %s: %w", zone, name, err)
+	}
+	delete(version.fields, "resource_records")
+	delete(version.fields, "ttl")
+	return rrset, version, nil
+}
+
+// UpdateRRSetIfMatch replaces the records and TTL of an RRSet only if it
+// still has the given version, the API answers 412 Precondition Failed
+// otherwise. A version without ETag makes the update unconditional.
+func (c *gcoreClient) UpdateRRSetIfMatch(ctx context.Context, zone, name, recordType string,
+	record dnssdk.RRSet, version rrsetVersion) error {
+	zone, name = strings.Trim(zone, "."), strings.Trim(name, ".")
+	header := http.Header{}
+	if version.etag != "" {
+		header.Set("If-Match", version.etag)
+	}
+	var body any = record
+	if len(version.fields) > 0 {
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("encode rrset: %w", err)
+		}
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(encoded, &fields); err != nil {
+			return fmt.Errorf("encode rrset: %w", err)
+		}
+		maps.Copy(fields, version.fields)
+		body = fields
+	}
+	_, err := c.do(ctx, http.MethodPut, path.Join("/v2/zones", zone, name, recordType), body, nil, header)
+	return err
+}
+
+// DeleteRRSetIfMatch deletes an RRSet only if it still has the given
+// version, so a record another writer added meanwhile is not deleted with
+// it. Like the SDK's DeleteRRSet, an RRSet that is already gone is no error.
+func (c *gcoreClient) DeleteRRSetIfMatch(ctx context.Context, zone, name, recordType string, version rrsetVersion) error {
+	zone, name = strings.Trim(zone, "."), strings.Trim(name, ".")
+	header := http.Header{}
+	if version.etag != "" {
+		header.Set("If-Match", version.etag)
+	}
+	_, err := c.do(ctx, http.MethodDelete, path.Join("/v2/zones", zone, name, recordType), nil, nil, header)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// do sends an authenticated request the same way the SDK does and decodes the
+// response into dest. API failures are returned as dnssdk.APIError.
+func (c *gcoreClient) do(ctx context.Context, method, uri string,
+	body, dest any, header http.Header) (http.Header, error) {
+	endpoint, err := c.BaseURL.Parse(path.Join(c.BaseURL.Path, uri))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse endpoint: %w", err)
+	}
+	var reqBody io.Reader
+	if body != nil {
+		bs, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encode body: %w", err)
+		}
+		reqBody = bytes.NewReader(bs)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", c.authHeader)
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		apiErr := dnssdk.APIError{StatusCode: resp.StatusCode}
+		if json.Unmarshal(respBody, &apiErr) != nil {
+			apiErr.Message = string(respBody)
+		}
+		return resp.Header, apiErr
+	}
+	if dest == nil {
+		return resp.Header, nil
+	}
+	return resp.Header, json.Unmarshal(respBody, dest)
+}
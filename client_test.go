@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient returns a client talking to a test server serving handler.
+func newTestClient(t *testing.T, handler http.Handler) *gcoreClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	sdk, err := newSDKClient(gcoreDNSProviderConfig{ApiUrl: server.URL}, "secret")
+	require.NoError(t, err)
+	return sdk.(*gcoreClient)
+}
+
+func TestGcoreClient_ZoneDetails(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/zones/example.com", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "APIKey secret", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"name":"example.com","meta":{"cert-manager":"enabled"}}`))
+	})
+	mux.HandleFunc("/v2/zones/example.org", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"zone not found"}`))
+	})
+	client := newTestClient(t, mux)
+
+	zone, err := client.ZoneDetails(context.Background(), "example.com.")
+	require.NoError(t, err)
+	assert.Equal(t, zoneDetails{Name: "example.com", Meta: map[string]any{"cert-manager": "enabled"}}, zone)
+
+	_, err = client.ZoneDetails(context.Background(), "example.org")
+	apiErr := dnssdk.APIError{}
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "zone not found", apiErr.Message)
+}
+
+func TestGcoreClient_EnableZone(t *testing.T) {
+	enabled := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/zones/example.com", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"name":"example.com","enabled":%t}`, enabled)
+	})
+	mux.HandleFunc("/v2/zones/example.com/enable", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		enabled = true
+		_, _ = w.Write([]byte(`{}`))
+	})
+	client := newTestClient(t, mux)
+
+	zone, err := client.ZoneDetails(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.True(t, zone.disabled())
+	require.NoError(t, client.EnableZone(context.Background(), "example.com."))
+	zone, err = client.ZoneDetails(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.False(t, zone.disabled())
+
+	err = client.EnableZone(context.Background(), "example.org")
+	assert.ErrorContains(t, err, "enable zone example.org")
+}
+
+func TestGcoreClient_ConditionalUpdate(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/zones/example.com/_acme-challenge.example.com/TXT", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(`{"ttl":120,"resource_records":[{"content":["token-A"],"enabled":true}]}`))
+		case http.MethodPut, http.MethodDelete:
+			if r.Header.Get("If-Match") != `"v1"` {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				_, _ = w.Write([]byte(`{"error":"rrset was modified"}`))
+			}
+		}
+	})
+	mux.HandleFunc("/v2/zones/example.com/_acme-challenge.gone.example.com/TXT", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"rrset not found"}`))
+	})
+	client := newTestClient(t, mux)
+	ctx := context.Background()
+
+	rrset, version, err := client.RRSetWithVersion(ctx, "example.com", "_acme-challenge.example.com.", txtType)
+	require.NoError(t, err)
+	assert.Equal(t, `"v1"`, version.etag)
+	assert.Equal(t, 120, rrset.TTL)
+	assert.Equal(t, "token-A", rrset.Records[0].ContentToString())
+
+	assert.NoError(t, client.UpdateRRSetIfMatch(ctx, "example.com", "_acme-challenge.example.com", txtType, rrset, version))
+	err = client.UpdateRRSetIfMatch(ctx, "example.com", "_acme-challenge.example.com", txtType, rrset, rrsetVersion{etag: `"v0"`})
+	assert.True(t, isPreconditionFailed(err))
+
+	assert.NoError(t, client.DeleteRRSetIfMatch(ctx, "example.com", "_acme-challenge.example.com", txtType, version))
+	err = client.DeleteRRSetIfMatch(ctx, "example.com", "_acme-challenge.example.com", txtType, rrsetVersion{etag: `"v0"`})
+	assert.True(t, isPreconditionFailed(err))
+	assert.NoError(t, client.DeleteRRSetIfMatch(ctx, "example.com", "_acme-challenge.gone.example.com", txtType, version))
+}
+
+func TestPresentRecordNameRoundTrip(t *testing.T) {
+	var written []string
+	rrsets := map[string]string{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/zones/example.com", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"example.com"}`))
+	})
+	mux.HandleFunc("/v2/zones/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut:
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			written = append(written, r.URL.EscapedPath())
+			rrsets[r.URL.Path] = string(body)
+		case http.MethodGet:
+			body, ok := rrsets[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error":"not found"}`))
+				return
+			}
+			_, _ = w.Write([]byte(body))
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	testCases := []struct {
+		fqdn string
+		path string
+	}{
+		{"_acme-challenge.example.com.", "/v2/zones/example.com/_acme-challenge.example.com/TXT"},
+		{"_acme-challenge._sub.example.com.", "/v2/zones/example.com/_acme-challenge._sub.example.com/TXT"},
+		{"_acme-challenge.WWW.example.com.", "/v2/zones/example.com/_acme-challenge.www.example.com/TXT"},
+		{"_acme-challenge.Bücher.example.com.", "/v2/zones/example.com/_acme-challenge.xn--bcher-kva.example.com/TXT"},
+	}
+	solver := &gcoreDNSProviderSolver{}
+	for _, test := range testCases {
+		written = nil
+		err := solver.Present(challenge(test.fqdn, "key", `{"apiToken":"secret","apiUrl":"`+server.URL+`"}`))
+		require.NoError(t, err, test.fqdn)
+		require.NotEmpty(t, written, test.fqdn)
+		assert.Equal(t, test.path, written[0])
+		assert.Contains(t, rrsets[test.path], `"content":["key"]`)
+	}
+}
+
+func TestPresentKeepsRRSetFields(t *testing.T) {
+	const stored = `{"type":"TXT","ttl":300,` +
+		`"resource_records":[{"content":["site-verification"],"meta":{"countries":["de"]},"enabled":true}],` +
+		`"filters":[{"type":"geodns","limit":1,"strict":false,"extra":true}],` +
+		`"pickers":[{"type":"geodns","limit":1}],"meta":{"failover":{"protocol":"HTTP","port":443}}}`
+	var put map[string]json.RawMessage
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/zones/example.com", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"example.com"}`))
+	})
+	mux.HandleFunc("/v2/zones/example.com/_acme-challenge.example.com/TXT", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error":"rrset already exists"}`))
+		case http.MethodPut:
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&put))
+		case http.MethodGet:
+			if put == nil {
+				_, _ = w.Write([]byte(stored))
+				return
+			}
+			body, _ := json.Marshal(put)
+			_, _ = w.Write(body)
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	solver := &gcoreDNSProviderSolver{}
+	require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "key",
+		`{"apiToken":"secret","apiUrl":"`+server.URL+`","zoneDiscovery":"probe"}`)))
+	require.NotNil(t, put)
+
+	var want map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(stored), &want))
+	for _, field := range []string{"filters", "pickers", "meta", "ttl"} {
+		assert.JSONEq(t, string(want[field]), string(put[field]), field)
+	}
+	var records []dnssdk.ResourceRecord
+	require.NoError(t, json.Unmarshal(put["resource_records"], &records))
+	require.Len(t, records, 2)
+	assert.Equal(t, "key", records[0].ContentToString())
+	assert.True(t, owned(records[0]))
+	assert.JSONEq(t, `{"content":["site-verification"],"meta":{"countries":["de"]},"enabled":true}`,
+		string(mustJSON(t, records[1])))
+}
+
+// mustJSON encodes v as JSON.
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return b
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sdkClientsTTL bounds how long an API client, and with it its connection
+// pool, is reused before it is built anew, e.g. to pick up changed proxy
+// environment variables.
+const sdkClientsTTL = time.Hour
+
+// sdkClientKey identifies the API clients built alike for cfg and token: the
+// same credential, API URL and transport settings. Bearer and token file
+// sources are compared by identity, as they are shared between challenges.
+func sdkClientKey(cfg gcoreDNSProviderConfig, token string) string {
+	sum := sha256.New()
+	for _, part := range []string{
+		token, cfg.ApiUrl, strings.Join(cfg.FallbackEndpoints, " "), cfg.AuthMode, fmt.Sprintf("%p %p", cfg.bearer, cfg.tokenFile),
+		strconv.FormatUint(cfg.ClientID, 10), strconv.Itoa(cfg.Timeout), cfg.ProxyURL,
+		strconv.FormatBool(cfg.ForceHTTP1), cfg.CABundle, strconv.FormatBool(cfg.InsecureSkipVerify), cfg.MinTLSVersion,
+	} {
+		sum.Write([]byte(part))
+		sum.Write([]byte{0})
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// sdkClient returns the API client for cfg and token, reusing the one built
+// for an earlier challenge with the same settings, so that thousands of
+// renewals share TLS sessions and connections instead of opening their own.
+func (c *gcoreDNSProviderSolver) sdkClient(cfg gcoreDNSProviderConfig, token string) (dnsAPI, error) {
+	key := sdkClientKey(cfg, token)
+	if sdk, ok := c.sdkClients.Get(key); ok {
+		return sdk, nil
+	}
+	newSDK := c.newSDK
+	if newSDK == nil {
+		newSDK = newSDKClient
+	}
+	sdk, err := newSDK(cfg, token)
+	if err != nil {
+		return nil, err
+	}
+	c.sdkClients.Set(key, sdk)
+	return sdk, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSDKClientKey(t *testing.T) {
+	base := gcoreDNSProviderConfig{ApiUrl: defaultAPIURL}
+	key := sdkClientKey(base, "token")
+	assert.Equal(t, key, sdkClientKey(base, "token"))
+	assert.NotEqual(t, key, sdkClientKey(base, "other token"))
+
+	for desc, cfg := range map[string]gcoreDNSProviderConfig{
+		"api url":    {ApiUrl: "https://api.example.com/dns"},
+		"client id":  {ApiUrl: defaultAPIURL, ClientID: 7},
+		"timeout":    {ApiUrl: defaultAPIURL, Timeout: 5},
+		"proxy":      {ApiUrl: defaultAPIURL, ProxyURL: "http://proxy:3128"},
+		"tls":        {ApiUrl: defaultAPIURL, MinTLSVersion: tlsVersion13},
+		"http1":      {ApiUrl: defaultAPIURL, ForceHTTP1: true},
+		"token file": {ApiUrl: defaultAPIURL, tokenFile: &tokenFile{}},
+	} {
+		assert.NotEqual(t, key, sdkClientKey(cfg, "token"), desc)
+	}
+}
+
+func TestSDKClientPool(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"example.com"}`))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	solver := &gcoreDNSProviderSolver{sdkClients: newCache[string, dnsAPI](time.Hour, 10)}
+	get := func(cfg string) dnsAPI {
+		sdk, _, err := solver.initSDK(t.Context(), challenge("_acme-challenge.example.com.", "key", fmt.Sprintf(cfg, server.URL)))
+		require.NoError(t, err)
+		_, err = sdk.ZoneDetails(context.Background(), "example.com")
+		require.NoError(t, err)
+		return sdk.(*retryingAPI).api
+	}
+
+	first := get(`{"apiToken":"t","apiUrl":%q}`)
+	assert.Same(t, first, get(`{"apiToken":"t","apiUrl":%q}`), "challenges alike share the client")
+	assert.EqualValues(t, 1, conns.Load(), "the connection is reused")
+
+	assert.NotSame(t, first, get(`{"apiToken":"other","apiUrl":%q}`))
+	assert.NotSame(t, first, get(`{"apiToken":"t","apiUrl":%q,"clientId":3}`))
+	assert.Equal(t, 3, solver.sdkClients.Stats().Size)
+}
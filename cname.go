@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+	"github.com/miekg/dns"
+)
+
+// maxCNAMEHops bounds the CNAME chains followCNAME walks.
+const maxCNAMEHops = 10
+
+// followCNAME returns the name the CNAME chain starting at fqdn ends at, or
+// fqdn itself when it is no alias. It matches cert-manager's cnameStrategy
+// Follow for issuers that delegate _acme-challenge names to a validation
+// zone in G-Core with a CNAME.
+func (c *gcoreDNSProviderSolver) followCNAME(ctx context.Context, fqdn string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, util.DNSTimeout)
+	defer cancel()
+	lookupCNAME := c.lookupCNAME
+	if lookupCNAME == nil {
+		nameservers := c.recursiveNameservers()
+		lookupCNAME = func(ctx context.Context, fqdn string) (string, error) {
+			return lookupCNAMETarget(ctx, fqdn, nameservers)
+		}
+	}
+	name := strings.ToLower(strings.Trim(fqdn, "."))
+	seen := map[string]bool{name: true}
+	for range maxCNAMEHops {
+		target, err := lookupCNAME(ctx, name+".")
+		if err != nil {
+			return "", fmt.Errorf("lookup cname %s: %w", name, err)
+		}
+		target = strings.ToLower(strings.Trim(target, "."))
+		if target == "" {
+			return name, nil
+		}
+		if seen[target] {
+			return "", fmt.Errorf("cname loop at %s following %s", target, fqdn)
+		}
+		seen[target] = true
+		name = target
+	}
+	return "", fmt.Errorf("cname chain of %s is longer than %d", fqdn, maxCNAMEHops)
+}
+
+// lookupCNAMETarget returns the target of the CNAME record at fqdn, empty
+// when there is none, asking the given recursive nameservers.
+func lookupCNAMETarget(ctx context.Context, fqdn string, nameservers []string) (string, error) {
+	msg, err := util.DNSQuery(ctx, fqdn, dns.TypeCNAME, nameservers, true)
+	if err != nil {
+		return "", err
+	}
+	if msg.Rcode != dns.RcodeSuccess {
+		return "", nil
+	}
+	for _, rr := range msg.Answer {
+		if cname, ok := rr.(*dns.CNAME); ok && strings.EqualFold(cname.Hdr.Name, fqdn) {
+			return cname.Target, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cnames answers CNAME lookups from a map of alias to target.
+func cnames(records map[string]string) func(context.Context, string) (string, error) {
+	return func(_ context.Context, fqdn string) (string, error) {
+		return records[fqdn], nil
+	}
+}
+
+func TestFollowCNAME(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		records map[string]string
+		target  string
+		errMsg  string
+	}{
+		{desc: "no alias", target: "_acme-challenge.example.com"},
+		{desc: "single hop",
+			records: map[string]string{"_acme-challenge.example.com.": "example.com.validation.example.net."},
+			target:  "example.com.validation.example.net"},
+		{desc: "chain", records: map[string]string{
+			"_acme-challenge.example.com.": "a.example.org.",
+			"a.example.org.":               "B.Validation.example.net.",
+		}, target: "b.validation.example.net"},
+		{desc: "loop", records: map[string]string{
+			"_acme-challenge.example.com.": "a.example.org.",
+			"a.example.org.":               "_acme-challenge.example.com.",
+		}, errMsg: "cname loop at _acme-challenge.example.com following _acme-challenge.example.com."},
+	}
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			solver := &gcoreDNSProviderSolver{lookupCNAME: cnames(test.records)}
+			target, err := solver.followCNAME(context.Background(), "_acme-challenge.example.com.")
+			if test.errMsg != "" {
+				assert.EqualError(t, err, test.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.target, target)
+		})
+	}
+}
+
+func TestPresentFollowCNAME(t *testing.T) {
+	mock := newMockSDK("example.com", "validation.example.net")
+	solver := solverWithMock(mock)
+	solver.lookupCNAME = cnames(map[string]string{
+		"_acme-challenge.example.com.": "example.com.validation.example.net.",
+	})
+	cfg := `{"apiToken":"t","followCNAME":true}`
+
+	require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "token-A", cfg)))
+	assert.Equal(t, []string{"token-A"}, mock.contents("validation.example.net", "example.com.validation.example.net"))
+	assert.Empty(t, mock.zones["example.com"].rrsets)
+
+	require.NoError(t, solver.CleanUp(challenge("_acme-challenge.example.com.", "token-A", cfg)))
+	assert.Empty(t, mock.contents("validation.example.net", "example.com.validation.example.net"))
+
+	// Without followCNAME the record is written at the alias.
+	require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "token-B", `{"apiToken":"t"}`)))
+	assert.Equal(t, []string{"token-B"}, mock.contents("example.com", "_acme-challenge.example.com"))
+
+	solver.lookupCNAME = func(context.Context, string) (string, error) { return "", errors.New("i/o timeout") }
+	err := solver.Present(challenge("_acme-challenge.example.com.", "token-C", cfg))
+	assert.ErrorContains(t, err, "lookup cname _acme-challenge.example.com: i/o timeout")
+
+	// The lookup is bounded by the challenge's context.
+	solver.lookupCNAME = func(ctx context.Context, _ string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, _, err = solver.initSDK(ctx, challenge("_acme-challenge.example.com.", "token-C", cfg))
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+// defaultCoalesceWindow is how long the first Present for an RRSet waits
+// for further pending challenges of the same name before writing.
+const defaultCoalesceWindow = 100 * time.Millisecond
+
+// presentBatches collects the challenge records presented for the same
+// RRSet at about the same time, e.g. of a wildcard and its apex, so they are
+// written in one update instead of a read-modify-write cycle each. The zero
+// value is ready to use and, without a window, only batches challenges
+// arriving while the first one waits for the record lock.
+type presentBatches struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	open map[string]*presentBatch
+	// pending counts the Present calls in progress per challenge name and
+	// config.
+	pending map[string]int
+}
+
+// presentBatch is the challenge keys of one write, its outcome once done is
+// closed.
+type presentBatch struct {
+	keys []string
+	done chan struct{}
+	err  error
+}
+
+// pendingKey identifies the challenges of ch's name and issuer config.
+func pendingKey(ch *v1alpha1.ChallengeRequest) string {
+	key := strings.ToLower(strings.Trim(ch.ResolvedFQDN, "."))
+	if ch.Config != nil {
+		key += "\x00" + string(ch.Config.Raw)
+	}
+	return key
+}
+
+// arrive counts a Present of the challenges of key as pending until the
+// returned function is called.
+func (b *presentBatches) arrive(key string) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pending == nil {
+		b.pending = map[string]int{}
+	}
+	b.pending[key]++
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.pending[key]--; b.pending[key] == 0 {
+			delete(b.pending, key)
+		}
+	}
+}
+
+// presentCoalesced adds key to the open batch of batchKey and waits for it to
+// be written. Without an open batch it opens one and writes it: when other
+// Present calls of pending are in progress it first waits the coalesce
+// window for them, then it takes the record lock of lockKey and calls write
+// with the keys that joined until the lock was taken. A key whose ctx is
+// done before then is left out.
+func (c *gcoreDNSProviderSolver) presentCoalesced(ctx context.Context, lockKey, batchKey, pending, key string,
+	write func(keys []string) error) error {
+	b := &c.presentBatches
+	b.mu.Lock()
+	if b.open == nil {
+		b.open = map[string]*presentBatch{}
+	}
+	if batch, ok := b.open[batchKey]; ok {
+		batch.keys = append(batch.keys, key)
+		b.mu.Unlock()
+		select {
+		case <-batch.done:
+			return batch.err
+		case <-ctx.Done():
+			b.mu.Lock()
+			if b.open[batchKey] == batch {
+				if i := slices.Index(batch.keys, key); i >= 0 {
+					batch.keys = slices.Delete(batch.keys, i, i+1)
+				}
+			}
+			b.mu.Unlock()
+			return fmt.Errorf("wait for batched rrset write: %w", ctx.Err())
+		}
+	}
+	batch := &presentBatch{keys: []string{key}, done: make(chan struct{})}
+	b.open[batchKey] = batch
+	others := b.pending[pending] > 1
+	b.mu.Unlock()
+
+	defer close(batch.done)
+	var err error
+	if others {
+		err = sleepContext(ctx, b.window)
+	}
+	var unlock func()
+	if err == nil {
+		unlock, err = c.recordLocks.lock(ctx, lockKey)
+	}
+	// Challenges arriving from now on wait for the next write.
+	b.mu.Lock()
+	delete(b.open, batchKey)
+	var keys []string
+	for _, k := range batch.keys {
+		if !slices.Contains(keys, k) {
+			keys = append(keys, k)
+		}
+	}
+	b.mu.Unlock()
+	if err != nil {
+		batch.err = fmt.Errorf("wait for rrset lock: %w", err)
+		return batch.err
+	}
+	defer unlock()
+	if len(keys) > 1 {
+		c.logger(ctx).V(1).Info("writing challenge records together", "count", len(keys))
+	}
+	batch.err = write(keys)
+	return batch.err
+}
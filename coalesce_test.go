@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatheringAPI holds the zone queries of challenges until n of them are
+// being presented, so that all of them are pending when the first is
+// written.
+type gatheringAPI struct {
+	*mockSDK
+	n       int32
+	arrived atomic.Int32
+	all     chan struct{}
+}
+
+func gather(m *mockSDK, n int) *gatheringAPI {
+	return &gatheringAPI{mockSDK: m, n: int32(n), all: make(chan struct{})}
+}
+
+func (g *gatheringAPI) ZonesWithParam(ctx context.Context, param dnssdk.ZonesParam) (dnssdk.ListZones, error) {
+	if g.arrived.Add(1) == g.n {
+		close(g.all)
+	}
+	<-g.all
+	return g.mockSDK.ZonesWithParam(ctx, param)
+}
+
+func TestPresentCoalesced(t *testing.T) {
+	const fqdn = "_acme-challenge.example.com."
+	cfg := `{"apiToken":"t"}`
+
+	presentAll := func(solver *gcoreDNSProviderSolver, mock *mockSDK, challenges [][2]string) []error {
+		api := gather(mock, len(challenges))
+		solver.newSDK = func(gcoreDNSProviderConfig, string) (dnsAPI, error) { return api, nil }
+		errs := make([]error, len(challenges))
+		var wg sync.WaitGroup
+		for i, ch := range challenges {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = solver.Present(challenge(fqdn, ch[0], ch[1]))
+			}()
+		}
+		wg.Wait()
+		return errs
+	}
+
+	t.Run("one write", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		solver := solverWithMock(mock)
+		solver.presentBatches.window = 50 * time.Millisecond
+		var challenges [][2]string
+		for i := range 5 {
+			challenges = append(challenges, [2]string{fmt.Sprintf("token-%d", i), cfg})
+		}
+		challenges = append(challenges, [2]string{"token-0", cfg})
+		for _, err := range presentAll(solver, mock, challenges) {
+			assert.NoError(t, err)
+		}
+		assert.ElementsMatch(t, []string{"token-0", "token-1", "token-2", "token-3", "token-4"},
+			mock.contents("example.com", "_acme-challenge.example.com"))
+		assert.Equal(t, 1, mock.creates)
+		assert.Equal(t, 1, mock.writes)
+		assert.Empty(t, solver.presentBatches.open)
+	})
+
+	t.Run("per issuer", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		solver := solverWithMock(mock)
+		solver.presentBatches.window = 50 * time.Millisecond
+		errs := presentAll(solver, mock, [][2]string{{"token-A", cfg}, {"token-B", `{"apiToken":"t","ttl":300}`}})
+		for _, err := range errs {
+			assert.NoError(t, err)
+		}
+		assert.ElementsMatch(t, []string{"token-A", "token-B"}, mock.contents("example.com", "_acme-challenge.example.com"))
+		assert.Equal(t, 2, mock.creates)
+	})
+
+	t.Run("shared failure", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		mock.zones["example.com"].disabled = true
+		solver := solverWithMock(mock)
+		solver.presentBatches.window = 50 * time.Millisecond
+		errs := presentAll(solver, mock, [][2]string{{"token-A", cfg}, {"token-B", cfg}})
+		for _, err := range errs {
+			require.ErrorIs(t, err, errZoneDisabled)
+		}
+		assert.Equal(t, 1, mock.creates)
+	})
+}
+
+func TestPresentCoalescedAlone(t *testing.T) {
+	mock := newMockSDK("example.com")
+	solver := solverWithMock(mock)
+	solver.presentBatches.window = time.Hour
+
+	start := time.Now()
+	require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "key", `{"apiToken":"t"}`)))
+	assert.Less(t, time.Since(start), time.Minute, "a challenge alone doesn't wait the window")
+	assert.Empty(t, solver.presentBatches.pending)
+}
+
+func TestPresentCoalescedCancelledJoiner(t *testing.T) {
+	solver := &gcoreDNSProviderSolver{}
+	unlock, err := solver.recordLocks.lock(t.Context(), "lock")
+	require.NoError(t, err)
+
+	written := make(chan []string, 1)
+	go func() {
+		_ = solver.presentCoalesced(t.Context(), "lock", "batch", "pending", "key-A", func(keys []string) error {
+			written <- keys
+			return nil
+		})
+	}()
+	require.Eventually(t, func() bool {
+		solver.presentBatches.mu.Lock()
+		defer solver.presentBatches.mu.Unlock()
+		return solver.presentBatches.open["batch"] != nil
+	}, 5*time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	err = solver.presentCoalesced(ctx, "lock", "batch", "pending", "key-B", nil)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	unlock()
+	assert.Equal(t, []string{"key-A"}, <-written, "the key of a cancelled challenge is not written")
+}
@@ -0,0 +1,800 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	certmgrv1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/go-logr/logr"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// secretRef points at a key of a Secret in a possibly different namespace.
+type secretRef struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+}
+
+// login holds the credentials of an account that has no API token.
+type login struct {
+	Username          string    `json:"username"`
+	PasswordSecretRef secretRef `json:"passwordSecretRef"`
+}
+
+// errNoConfig is returned when the issuer does not carry any solver config at
+// all, as opposed to a config that was provided but could not be used.
+var errNoConfig = errors.New("no solver config provided: set apiToken, apiTokenFile, apiTokenSecretRef, " +
+	"apiKeySecretRef, tokenSource or login in the issuer's dns01.webhook.config")
+
+// gcoreDNSProviderConfig is a structure that is used to decode into when
+// solving a DNS01 challenge.
+// This information is provided by cert-manager, and may be a reference to
+// additional configuration that's needed to solve the challenge for this
+// particular certificate or issuer.
+// This typically includes references to Secret resources containing DNS
+// provider credentials, in cases where a 'multi-tenant' DNS solver is being
+// created.
+// If you do *not* require per-issuer or per-certificate configuration to be
+// provided to your webhook, you can skip decoding altogether in favour of
+// using CLI flags or similar to provide configuration.
+// You should not include sensitive information here. If credentials need to
+// be used by your provider here, you should reference a Kubernetes Secret
+// resource and fetch these credentials using a Kubernetes clientset.
+type gcoreDNSProviderConfig struct {
+	// These fields will be set by users in the
+	// `issuer.spec.acme.dns01.providers.webhook.config` field.
+
+	APIKeySecretRef certmgrv1.SecretKeySelector `json:"apiKeySecretRef"`
+	// +optional. Secret holding the API token, which unlike apiKeySecretRef
+	// may name the secret's namespace. It defaults to the issuer's namespace
+	// (the cluster resource namespace for ClusterIssuers).
+	APITokenSecretRef secretRef `json:"apiTokenSecretRef"`
+
+	// +optional. Version of this config's fields, "v1alpha1" (default and
+	// only one so far). Configs of an older version are converted when they
+	// are loaded.
+	ConfigVersion string `json:"configVersion"`
+	// +optional. Base url for API requests, e.g. to target a staging or
+	// regional G-Core environment. Defaults to https://api.gcore.com/dns.
+	ApiUrl string `json:"apiUrl"`
+	// +optional. Further base urls of the same API, e.g. regional G-Core
+	// endpoints. Calls move on to them in order while apiUrl can't be
+	// reached.
+	FallbackEndpoints []string `json:"fallbackEndpoints"`
+	// +optional. Permanent token if you don't want to use a k8s secret
+	ApiToken string `json:"apiToken"`
+
+	// +optional. File in the webhook pod holding the token, e.g. a projected
+	// secret volume. It is read again when it changes, and when the API
+	// rejects the token. Files must be below GCORE_API_TOKEN_DIR.
+	APITokenFile string `json:"apiTokenFile"`
+
+	// +optional. Named credentials of further G-Core accounts, with the
+	// same token sources as above, e.g. {"shop": {"apiTokenSecretRef": ...}}.
+	Credentials map[string]credential `json:"credentials"`
+	// +optional. Credential to use per zone, e.g. {"shop.example": "shop"}.
+	// Challenges in zones without an entry use the top-level token.
+	ZoneCredentials map[string]string `json:"zoneCredentials"`
+
+	// +optional. External source of the token, such as a HashiCorp Vault
+	// secret, read at runtime instead of a Kubernetes secret.
+	TokenSource *tokenSource `json:"tokenSource"`
+
+	// +optional. TTL of the challenge record in seconds, defaults to 300.
+	// Values below G-Core's minimum of 60 are raised to it.
+	TTL int `json:"ttl"`
+	// +optional
+	Timeout int `json:"timeout"`
+	// +optional
+	PropagationTimeout int `json:"propagationTimeout"`
+	// +optional
+	PollingInterval int `json:"pollingInterval"`
+	// +optional. What Present does when the records read back after a write
+	// differ from the written ones: "retry" (default) or "error".
+	OnVerifyMismatch string `json:"onVerifyMismatch"`
+	// +optional. Where the zone's authoritative nameservers come from: "api"
+	// (default, as listed by G-Core) or "dns" (live NS lookup of the delegation).
+	NSSource string `json:"nsSource"`
+	// +optional. Make Present wait, up to propagationTimeout, until every
+	// authoritative nameserver of the zone (see nsSource) answers the
+	// challenge record, so the ACME server can't ask one that lags behind.
+	// In a DNSSEC-signed zone the answer must also carry a valid signature.
+	VerifyPropagation bool `json:"verifyPropagation"`
+	// +optional. Seconds CleanUp waits before removing the record, for
+	// overlapping validations of the same name. Defaults to 0.
+	CleanupDelay int `json:"cleanupDelay"`
+	// +optional. Tags (zone meta entries) a zone must carry to be used,
+	// e.g. {"cert-manager": "enabled"}.
+	ZoneTagFilter map[string]string `json:"zoneTagFilter"`
+	// +optional. Make CleanUp fail when the challenge record is not found
+	// instead of treating it as already cleaned up.
+	StrictCleanup bool `json:"strictCleanup"`
+	// +optional. Let CleanUp also remove challenge records lacking the
+	// ownership note the webhook stamps on the records it creates, e.g.
+	// written by an earlier webhook version or another ACME client.
+	CleanupUnowned bool `json:"cleanupUnowned"`
+	// +optional. Create the zone in G-Core when the account has none of
+	// the candidate zones of a challenge: zoneName if set, else the
+	// shallowest candidate. Validation only succeeds once the zone is
+	// delegated to the G-Core nameservers.
+	AllowZoneCreation bool `json:"allowZoneCreation"`
+	// +optional. Enable the zone of a challenge when G-Core has it disabled
+	// or suspended, instead of failing the challenge. Records of a disabled
+	// zone are not served, so validation fails until it is enabled.
+	EnableDisabledZones bool `json:"enableDisabledZones"`
+	// +optional. Fewest labels a candidate zone may have, defaults to 2 so
+	// a bare TLD is never treated as a zone.
+	MinZoneLabels int `json:"minZoneLabels"`
+	// +optional. TTL per zone name, used instead of ttl for records created
+	// in that zone.
+	ZoneTTLOverrides map[string]int `json:"zoneTTLOverrides"`
+	// +optional. How the zone of a name is found: "filter" (default) asks
+	// for every candidate zone in one filtered zone list query, probing when
+	// the API ignores the filter, "probe" fetches every candidate zone,
+	// "list" pages through the account's zone list, "soa" resolves the
+	// authoritative zone in DNS and only checks that one, probing when DNS
+	// gives no zone the account has.
+	ZoneDiscovery string `json:"zoneDiscovery"`
+	// +optional. Which zone gets the record when the account has several
+	// parent zones of a name, e.g. both example.com and sub.example.com:
+	// "deepest" (default) picks the delegated child zone, "shallowest" the
+	// parent. The SOA lookup of zoneDiscovery "soa" always finds the
+	// deepest.
+	ZoneMatch string `json:"zoneMatch"`
+	// +optional. Domain appended to the challenge record name when ACME
+	// challenges are delegated to a dedicated zone, e.g. with
+	// "delegated.example.net" the record for _acme-challenge.example.com is
+	// written as _acme-challenge.example.com.delegated.example.net.
+	RecordNameSuffix string `json:"recordNameSuffix"`
+	// +optional. Follow the CNAME chain of the challenge record and write
+	// the record at its end, like cert-manager's cnameStrategy Follow, e.g.
+	// when _acme-challenge.example.com is an alias into a validation zone
+	// hosted in G-Core.
+	FollowCNAME bool `json:"followCNAME"`
+	// +optional. Talk HTTP/1.1 to the API, for environments where HTTP/2
+	// connections are dropped.
+	ForceHTTP1 bool `json:"forceHTTP1"`
+	// +optional. Proxy for the API calls, e.g. http://proxy.internal:3128,
+	// used instead of the HTTP_PROXY and HTTPS_PROXY variables of the
+	// webhook. It may carry the proxy credentials.
+	ProxyURL string `json:"proxyUrl"`
+	// +optional. PEM encoded CA certificates trusted for the API in addition
+	// to the system roots, e.g. of a TLS inspecting egress proxy.
+	CABundle string `json:"caBundle"`
+	// +optional. Skip verifying the API certificate. Strongly discouraged,
+	// anyone on the path can then read the token; use caBundle instead.
+	InsecureSkipVerify bool `json:"insecureSkipVerify"`
+	// +optional. Lowest TLS version used for the API, "1.2" or "1.3".
+	MinTLSVersion string `json:"minTLSVersion"`
+	// +optional. How often an API call failing with a transient error,
+	// such as a reset connection, a timeout or a 5xx answer, is retried.
+	// Rate limited calls wait as long as their Retry-After asks. Defaults
+	// to 2, 0 disables retries.
+	MaxRetries *int `json:"maxRetries"`
+	// +optional. Milliseconds before the first retry, doubled for every
+	// further one. Defaults to 500.
+	InitialBackoff int `json:"initialBackoff"`
+	// +optional. Most milliseconds between two retries, defaults to 30000.
+	MaxBackoff int `json:"maxBackoff"`
+	// +optional. Wait a random time between half and all of the backoff,
+	// so replicas that failed together don't retry together.
+	RetryJitter bool `json:"retryJitter"`
+	// +optional. Before the first challenge in a zone, check that the
+	// credential may write to it by creating and deleting a probe record,
+	// so read-only tokens fail with a clear error.
+	VerifyWriteScope bool `json:"verifyWriteScope"`
+	// +optional. Zone the write scope probe writes to instead of the
+	// challenge zone.
+	ScratchZone string `json:"scratchZone"`
+	// +optional. Seconds Present waits after writing the record. With
+	// presentDelayMode "ttl" it caps the TTL-derived wait and defaults to 60.
+	PresentDelay int `json:"presentDelay"`
+	// +optional. How the wait after writing is chosen: "fixed" (default)
+	// waits presentDelay, "ttl" waits the record TTL up to presentDelay.
+	PresentDelayMode string `json:"presentDelayMode"`
+	// +optional. Follow zone aliases: when the API answers a lookup of an
+	// alias zone with its canonical zone, the record is written there.
+	ResolveZoneAliases bool `json:"resolveZoneAliases"`
+	// +optional. Most seconds of random wait added before Present returns
+	// and the self check starts, to spread the checks of many challenges.
+	SelfCheckJitter int `json:"selfCheckJitter"`
+	// +optional. ID of the G-Core zone the records are written to. Zone
+	// discovery is skipped, the zone name is only fetched once to check the
+	// record belongs to it.
+	ZoneID uint64 `json:"zoneID"`
+	// +optional. Zones the issuer may write to, each covering the zones
+	// below it too. Empty allows every zone.
+	AllowedZones []string `json:"allowedZones"`
+	// +optional. Zones the issuer must not write to, each covering the zones
+	// below it too. They win over allowedZones.
+	DeniedZones []string `json:"deniedZones"`
+	// +optional. Name of the G-Core zone the records are written to, e.g.
+	// when the search would pick another parent zone or the domain exists
+	// in several accounts. Zone discovery is skipped.
+	ZoneName string `json:"zoneName"`
+	// +optional. How CleanUp recognises the challenge record: "exact"
+	// (default), "normalized" (ignoring surrounding quotes and whitespace) or
+	// "prefix" (comparing the first 20 characters). The non-exact modes can
+	// remove a record of another challenge for the same name that happens to
+	// look alike, so only use them if the API alters stored values.
+	CleanupMatchMode string `json:"cleanupMatchMode"`
+	// +optional. Only log the RRSet changes Present and CleanUp would make
+	// and report success without making them, e.g. to validate a staging
+	// issuer. Propagation is not verified.
+	DryRun bool `json:"dryRun"`
+
+	// +optional. How the token authenticates: "permanent" (default) sends it
+	// as a permanent API token, "bearer" treats it as a refresh token that is
+	// exchanged at authUrl for short-lived access tokens, renewed before they
+	// expire.
+	AuthMode string `json:"authMode"`
+	// +optional. Base url of the G-Core auth API used in bearer mode.
+	AuthURL string `json:"authUrl"`
+	// +optional. ID of the client account a reseller token acts on behalf
+	// of, so resellers can solve challenges for their clients' zones without
+	// holding the clients' tokens.
+	ClientID uint64 `json:"clientId"`
+	// +optional. Username and password secret for older accounts that only
+	// have login credentials. The webhook logs in at authUrl, uses the
+	// short-lived access tokens like in bearer mode, and logs in again once
+	// the refresh token expired too. Only used when no token is configured.
+	Login login `json:"login"`
+
+	// bearer supplies the access tokens in bearer mode.
+	bearer *bearerToken
+	// tokenFile supplies the current token of apiTokenFile.
+	tokenFile *tokenFile
+	// account identifies the G-Core account the challenge is solved with,
+	// set once the token is resolved.
+	account string
+	// warnings lists the deprecated fields the config uses.
+	warnings []string
+	// cnameTarget is where the followed CNAME chain of the challenge record
+	// ends, set once followCNAME was resolved.
+	cnameTarget string
+	// metrics counts the API requests of the challenge.
+	metrics *webhookMetrics
+	// traceRequests adds a span for every HTTP request to the API.
+	traceRequests bool
+	// debugHTTP logs every HTTP request to the API with its response, unless
+	// it is the zero logger.
+	debugHTTP logr.Logger
+}
+
+const (
+	defaultAPIURL             = "https://api.gcore.com/dns"
+	defaultTTL                = 300
+	minTTL                    = 60
+	maxTTL                    = 24 * 60 * 60
+	defaultPropagationTimeout = 5 * 60
+	defaultMinZoneLabels      = 2
+	maxRetries                = 10
+
+	verifyMismatchRetry = "retry"
+	verifyMismatchError = "error"
+
+	nsSourceAPI = "api"
+	nsSourceDNS = "dns"
+
+	zoneDiscoveryFilter = "filter"
+	zoneDiscoveryProbe  = "probe"
+	zoneDiscoveryList   = "list"
+	zoneDiscoverySOA    = "soa"
+
+	zoneMatchDeepest    = "deepest"
+	zoneMatchShallowest = "shallowest"
+
+	cleanupMatchExact      = "exact"
+	cleanupMatchNormalized = "normalized"
+	cleanupMatchPrefix     = "prefix"
+	cleanupMatchPrefixLen  = 20
+
+	presentDelayFixed      = "fixed"
+	presentDelayTTL        = "ttl"
+	defaultPresentDelayCap = 60
+
+	cacheTTLEnvVar         = "GCORE_CACHE_TTL"
+	cacheMaxEntriesEnvVar  = "GCORE_CACHE_MAX_ENTRIES"
+	negativeCacheTTLEnvVar = "GCORE_NEGATIVE_CACHE_TTL"
+	adminAddrEnvVar        = "GCORE_ADMIN_ADDR"
+	crossNamespaceEnvVar   = "GCORE_ALLOW_CROSS_NAMESPACE_SECRETS"
+	tokenDirEnvVar         = "GCORE_API_TOKEN_DIR"
+	allowedZonesEnvVar     = "GCORE_ALLOWED_ZONES"
+	deniedZonesEnvVar      = "GCORE_DENIED_ZONES"
+	vaultAddrsEnvVar       = "GCORE_VAULT_ADDRS"
+	lastErrorsSizeEnvVar   = "GCORE_LAST_ERRORS_SIZE"
+	ambientNSEnvVar        = "GCORE_AMBIENT_CREDENTIALS_NAMESPACES"
+	startupCheckEnvVar     = "GCORE_STARTUP_CHECK"
+	startupCheckZoneEnvVar = "GCORE_STARTUP_CHECK_ZONE"
+	staleGCIntervalEnvVar  = "GCORE_STALE_RECORD_GC_INTERVAL"
+	staleMaxAgeEnvVar      = "GCORE_STALE_RECORD_MAX_AGE"
+	breakerThresholdEnvVar = "GCORE_CIRCUIT_BREAKER_THRESHOLD"
+	breakerCooldownEnvVar  = "GCORE_CIRCUIT_BREAKER_COOLDOWN"
+
+	readinessCheckIntervalEnvVar = "GCORE_READINESS_CHECK_INTERVAL"
+	auditLogEnvVar               = "GCORE_AUDIT_LOG"
+	drainTimeoutEnvVar           = "GCORE_DRAIN_TIMEOUT"
+)
+
+// loadConfig is a small helper function that decodes JSON configuration into
+// the typed config struct.
+// It returns errNoConfig when the issuer has no solver config at all.
+func loadConfig(cfgJSON *extapi.JSON) (gcoreDNSProviderConfig, error) {
+	cfg := gcoreDNSProviderConfig{}
+	// handle the 'base case' where no configuration has been provided
+	if cfgJSON == nil {
+		return cfg, errNoConfig
+	}
+	raw := bytes.TrimSpace(cfgJSON.Raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return cfg, errNoConfig
+	}
+	raw, warnings, err := convertConfig(raw, configRenames)
+	if err != nil {
+		return cfg, fmt.Errorf("error decoding solver config: %v", err)
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("error decoding solver config: %v", err)
+	}
+	cfg.warnings = warnings
+
+	return cfg, nil
+}
+
+// validateCredentials makes sure the config names a source for the API token.
+func (cfg gcoreDNSProviderConfig) validateCredentials() error {
+	if cfg.ApiToken != "" || cfg.APITokenFile != "" {
+		return nil
+	}
+	if cfg.APITokenSecretRef.Name != "" {
+		if cfg.APITokenSecretRef.Key == "" {
+			return fmt.Errorf("missing credentials: apiTokenSecretRef.key is not set for secret %q",
+				cfg.APITokenSecretRef.Name)
+		}
+		return nil
+	}
+	if cfg.APIKeySecretRef.Name == "" && cfg.TokenSource != nil {
+		return nil
+	}
+	if cfg.APIKeySecretRef.Name == "" && cfg.Login.Username != "" {
+		if cfg.Login.PasswordSecretRef.Name == "" || cfg.Login.PasswordSecretRef.Key == "" {
+			return fmt.Errorf("missing credentials: login.passwordSecretRef name and key must be set for user %q",
+				cfg.Login.Username)
+		}
+		return nil
+	}
+	if cfg.APIKeySecretRef.Name == "" {
+		return errors.New("missing credentials: neither apiToken, apiTokenFile, apiTokenSecretRef.name, " +
+			"apiKeySecretRef.name, tokenSource nor login.username is set")
+	}
+	if cfg.APIKeySecretRef.Key == "" {
+		return fmt.Errorf("missing credentials: apiKeySecretRef.key is not set for secret %q",
+			cfg.APIKeySecretRef.Name)
+	}
+	return nil
+}
+
+// usesLogin reports whether the account is accessed with login.
+func (cfg gcoreDNSProviderConfig) usesLogin() bool {
+	return cfg.Login.Username != "" && cfg.ApiToken == "" && cfg.APITokenFile == "" &&
+		cfg.APITokenSecretRef.Name == "" && cfg.APIKeySecretRef.Name == "" && cfg.TokenSource == nil
+}
+
+// Validate checks the config fields that have a fixed set of allowed values
+// or ranges. It reports every problem it finds, not just the first, in the
+// order of the fields, so they can be fixed in one go rather than one
+// Challenge status update at a time.
+func (cfg gcoreDNSProviderConfig) Validate() error {
+	var problems configErrors
+	if cfg.ConfigVersion != "" && !slices.Contains(configVersions, cfg.ConfigVersion) {
+		problems.add(fmt.Errorf("unsupported configVersion %q", cfg.ConfigVersion))
+	}
+	if cfg.ApiUrl != "" {
+		if err := validateURL(cfg.ApiUrl); err != nil {
+			problems.add(fmt.Errorf("apiUrl: %w", err))
+		}
+	}
+	for i, endpoint := range cfg.FallbackEndpoints {
+		if err := validateURL(endpoint); err != nil {
+			problems.add(fmt.Errorf("fallbackEndpoints[%d]: %w", i, err))
+		}
+	}
+	if cfg.AuthURL != "" {
+		if err := validateURL(cfg.AuthURL); err != nil {
+			problems.add(fmt.Errorf("authUrl: %w", err))
+		}
+	}
+	if cfg.ProxyURL != "" {
+		if _, err := parseProxyURL(cfg.ProxyURL); err != nil {
+			problems.add(fmt.Errorf("proxyUrl: %w", err))
+		}
+	}
+	if _, err := cfg.tlsConfig(); err != nil {
+		problems.add(err)
+	}
+	switch cfg.OnVerifyMismatch {
+	case "", verifyMismatchRetry, verifyMismatchError:
+	default:
+		problems.add(fmt.Errorf("onVerifyMismatch must be %q or %q, got %q",
+			verifyMismatchRetry, verifyMismatchError, cfg.OnVerifyMismatch))
+	}
+	switch cfg.NSSource {
+	case "", nsSourceAPI, nsSourceDNS:
+	default:
+		problems.add(fmt.Errorf("nsSource must be %q or %q, got %q", nsSourceAPI, nsSourceDNS, cfg.NSSource))
+	}
+	if _, ok := cfg.ZoneTagFilter[""]; ok {
+		problems.add(errors.New("zoneTagFilter must not contain an empty tag name"))
+	}
+	switch cfg.ZoneDiscovery {
+	case "", zoneDiscoveryFilter, zoneDiscoveryProbe, zoneDiscoveryList, zoneDiscoverySOA:
+	default:
+		problems.add(fmt.Errorf("zoneDiscovery must be %q, %q, %q or %q, got %q", zoneDiscoveryFilter,
+			zoneDiscoveryProbe, zoneDiscoveryList, zoneDiscoverySOA, cfg.ZoneDiscovery))
+	}
+	switch cfg.ZoneMatch {
+	case "", zoneMatchDeepest, zoneMatchShallowest:
+	default:
+		problems.add(fmt.Errorf("zoneMatch must be %q or %q, got %q", zoneMatchDeepest, zoneMatchShallowest, cfg.ZoneMatch))
+	}
+	if cfg.AllowZoneCreation && (len(cfg.ZoneTagFilter) > 0 || cfg.ZoneID != 0) {
+		problems.add(errors.New("allowZoneCreation can't be used with zoneTagFilter or zoneID, a created zone has neither"))
+	}
+	if cfg.ZoneMatch == zoneMatchShallowest && cfg.ZoneDiscovery == zoneDiscoverySOA {
+		problems.add(fmt.Errorf("zoneMatch %q can't be used with zoneDiscovery %q, which finds the deepest zone",
+			zoneMatchShallowest, zoneDiscoverySOA))
+	}
+	if cfg.MinZoneLabels != 0 && cfg.MinZoneLabels < defaultMinZoneLabels {
+		problems.add(fmt.Errorf("minZoneLabels must be at least %d, got %d", defaultMinZoneLabels, cfg.MinZoneLabels))
+	}
+	for _, zone := range slices.Sorted(maps.Keys(cfg.ZoneTTLOverrides)) {
+		ttl := cfg.ZoneTTLOverrides[zone]
+		if strings.Trim(zone, ".") == "" {
+			problems.add(errors.New("zoneTTLOverrides must not contain an empty zone name"))
+		}
+		if ttl <= 0 || ttl > maxTTL {
+			problems.add(fmt.Errorf("zoneTTLOverrides[%q] must be between 1 and %d, got %d", zone, maxTTL, ttl))
+		}
+	}
+	if suffix := strings.Trim(cfg.RecordNameSuffix, "."); cfg.RecordNameSuffix != "" &&
+		(suffix == "" || strings.ContainsAny(suffix, " \t/") || strings.Contains(suffix, "..")) {
+		problems.add(fmt.Errorf("recordNameSuffix %q is not a valid domain name", cfg.RecordNameSuffix))
+	}
+	for _, zone := range append(slices.Clone(cfg.AllowedZones), cfg.DeniedZones...) {
+		if strings.Trim(zone, ".") == "" {
+			problems.add(errors.New("allowedZones and deniedZones must not contain an empty zone name"))
+			break
+		}
+	}
+	if cfg.ZoneID != 0 && cfg.ZoneName != "" {
+		problems.add(errors.New("zoneID and zoneName are mutually exclusive"))
+	}
+	if cfg.ZoneName != "" && strings.Trim(cfg.ZoneName, ".") == "" {
+		problems.add(fmt.Errorf("zoneName %q is not a valid domain name", cfg.ZoneName))
+	}
+	if cfg.ScratchZone != "" && !cfg.VerifyWriteScope {
+		problems.add(errors.New("scratchZone requires verifyWriteScope"))
+	}
+	switch cfg.AuthMode {
+	case "", authModePermanent, authModeBearer:
+	default:
+		problems.add(fmt.Errorf("authMode must be %q or %q, got %q", authModePermanent, authModeBearer, cfg.AuthMode))
+	}
+	switch cfg.PresentDelayMode {
+	case "", presentDelayFixed, presentDelayTTL:
+	default:
+		problems.add(fmt.Errorf("presentDelayMode must be %q or %q, got %q",
+			presentDelayFixed, presentDelayTTL, cfg.PresentDelayMode))
+	}
+	switch cfg.CleanupMatchMode {
+	case "", cleanupMatchExact, cleanupMatchNormalized, cleanupMatchPrefix:
+	default:
+		problems.add(fmt.Errorf("cleanupMatchMode must be %q, %q or %q, got %q",
+			cleanupMatchExact, cleanupMatchNormalized, cleanupMatchPrefix, cfg.CleanupMatchMode))
+	}
+	if cfg.TTL < 0 || cfg.TTL > maxTTL {
+		problems.add(fmt.Errorf("ttl must be between 0 and %d, got %d", maxTTL, cfg.TTL))
+	}
+	if cfg.MaxRetries != nil && (*cfg.MaxRetries < 0 || *cfg.MaxRetries > maxRetries) {
+		problems.add(fmt.Errorf("maxRetries must be between 0 and %d, got %d", maxRetries, *cfg.MaxRetries))
+	}
+	if cfg.InitialBackoff < 0 || cfg.MaxBackoff < 0 {
+		problems.add(fmt.Errorf("initialBackoff and maxBackoff must not be negative, got %d and %d",
+			cfg.InitialBackoff, cfg.MaxBackoff))
+	}
+	if cfg.MaxBackoff != 0 && cfg.InitialBackoff > cfg.MaxBackoff {
+		problems.add(fmt.Errorf("initialBackoff %d must not exceed maxBackoff %d", cfg.InitialBackoff, cfg.MaxBackoff))
+	}
+	if cfg.PresentDelay < 0 {
+		problems.add(fmt.Errorf("presentDelay must not be negative, got %d", cfg.PresentDelay))
+	}
+	if cfg.SelfCheckJitter < 0 {
+		problems.add(fmt.Errorf("selfCheckJitter must not be negative, got %d", cfg.SelfCheckJitter))
+	}
+	if cfg.CleanupDelay < 0 {
+		problems.add(fmt.Errorf("cleanupDelay must not be negative, got %d", cfg.CleanupDelay))
+	}
+	if cfg.TokenSource != nil {
+		problems.add(cfg.TokenSource.validate())
+	}
+	problems.add(cfg.validateZoneCredentials())
+	return problems.err()
+}
+
+// configErrors holds every problem found in a config.
+type configErrors []error
+
+// add appends err unless it is nil, flattening nested configErrors.
+func (e *configErrors) add(err error) {
+	var nested configErrors
+	switch {
+	case err == nil:
+	case errors.As(err, &nested):
+		*e = append(*e, nested...)
+	default:
+		*e = append(*e, err)
+	}
+}
+
+// err returns the problems as an error, nil if there are none.
+func (e configErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e configErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d problems: %s", len(e), strings.Join(msgs, "; "))
+}
+
+func (e configErrors) Unwrap() []error {
+	return e
+}
+
+// validateURL checks that raw is an absolute http or https URL.
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute http(s) URL", raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("%q must not have a query or fragment", raw)
+	}
+	return nil
+}
+
+// recordName returns the name of the TXT record for the resolved challenge
+// FQDN, without trailing dot and with the recordNameSuffix applied, or the
+// end of its CNAME chain once followCNAME was resolved.
+// Labels are passed on verbatim: G-Core accepts the leading underscore of
+// _acme-challenge and its case, so neither is normalised.
+func (cfg gcoreDNSProviderConfig) recordName(resolvedFQDN string) string {
+	if cfg.cnameTarget != "" {
+		return cfg.cnameTarget
+	}
+	name := strings.Trim(resolvedFQDN, ".")
+	if suffix := strings.Trim(cfg.RecordNameSuffix, "."); suffix != "" {
+		name += "." + suffix
+	}
+	return asciiDomain(name)
+}
+
+// ttlForZone returns the TTL for records created in zone, raised to the
+// minimum TTL G-Core accepts.
+func (cfg gcoreDNSProviderConfig) ttlForZone(zone string) int {
+	zone = asciiDomain(zone)
+	for _, name := range slices.Sorted(maps.Keys(cfg.ZoneTTLOverrides)) {
+		if asciiDomain(name) == zone {
+			return max(cfg.ZoneTTLOverrides[name], minTTL)
+		}
+	}
+	return max(cfg.TTL, minTTL)
+}
+
+// matchesKey reports whether a stored record content is the challenge key
+// according to the cleanupMatchMode.
+func (cfg gcoreDNSProviderConfig) matchesKey(content, key string) bool {
+	switch cfg.CleanupMatchMode {
+	case cleanupMatchNormalized:
+		return normalizeContent(content) == normalizeContent(key)
+	case cleanupMatchPrefix:
+		content, key = normalizeContent(content), normalizeContent(key)
+		if len(content) < cleanupMatchPrefixLen || len(key) < cleanupMatchPrefixLen {
+			return content == key
+		}
+		return content[:cleanupMatchPrefixLen] == key[:cleanupMatchPrefixLen]
+	default:
+		return content == key
+	}
+}
+
+// normalizeContent strips the whitespace and quotes around a TXT value.
+func normalizeContent(content string) string {
+	return strings.TrimSpace(strings.Trim(strings.TrimSpace(content), `"`))
+}
+
+// presentDelayFor returns how long Present waits after writing a record
+// with the given TTL.
+func (cfg gcoreDNSProviderConfig) presentDelayFor(ttl int) time.Duration {
+	delay := cfg.PresentDelay
+	if cfg.PresentDelayMode == presentDelayTTL {
+		delay = min(ttl, cfg.PresentDelay)
+	}
+	return time.Duration(delay) * time.Second
+}
+
+// retryPolicy returns the retry policy of the config, with the defaults
+// for the fields it leaves empty.
+func (cfg gcoreDNSProviderConfig) retryPolicy() retryPolicy {
+	policy := defaultRetryPolicy()
+	if cfg.MaxRetries != nil {
+		policy.maxRetries = *cfg.MaxRetries
+	}
+	if cfg.InitialBackoff > 0 {
+		policy.initialBackoff = time.Duration(cfg.InitialBackoff) * time.Millisecond
+	}
+	if cfg.MaxBackoff > 0 {
+		policy.maxBackoff = time.Duration(cfg.MaxBackoff) * time.Millisecond
+	}
+	policy.initialBackoff = min(policy.initialBackoff, policy.maxBackoff)
+	policy.jitter = cfg.RetryJitter
+	return policy
+}
+
+// setDefaults fills in the optional fields left empty by the issuer.
+func (cfg *gcoreDNSProviderConfig) setDefaults() {
+	if cfg.ApiUrl == "" {
+		cfg.ApiUrl = defaultAPIURL
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = defaultTTL
+	}
+	if cfg.PropagationTimeout == 0 {
+		cfg.PropagationTimeout = defaultPropagationTimeout
+	}
+	if cfg.OnVerifyMismatch == "" {
+		cfg.OnVerifyMismatch = verifyMismatchRetry
+	}
+	if cfg.MinZoneLabels == 0 {
+		cfg.MinZoneLabels = defaultMinZoneLabels
+	}
+	if cfg.ZoneMatch == "" {
+		cfg.ZoneMatch = zoneMatchDeepest
+	}
+	if cfg.ZoneDiscovery == "" {
+		cfg.ZoneDiscovery = zoneDiscoveryFilter
+	}
+	if cfg.NSSource == "" {
+		cfg.NSSource = nsSourceAPI
+	}
+	if cfg.AuthMode == "" {
+		cfg.AuthMode = authModePermanent
+	}
+	if cfg.AuthURL == "" {
+		cfg.AuthURL = defaultAuthURL
+	}
+	if cfg.CleanupMatchMode == "" {
+		cfg.CleanupMatchMode = cleanupMatchExact
+	}
+	if cfg.PresentDelayMode == "" {
+		cfg.PresentDelayMode = presentDelayFixed
+	}
+	if cfg.PresentDelayMode == presentDelayTTL && cfg.PresentDelay == 0 {
+		cfg.PresentDelay = defaultPresentDelayCap
+	}
+}
+
+// cacheSettingsFromEnv reads the lookup cache TTL and size from the
+// GCORE_CACHE_TTL (a duration) and GCORE_CACHE_MAX_ENTRIES variables.
+func cacheSettingsFromEnv() (time.Duration, int, error) {
+	ttl, maxEntries := defaultCacheTTL, defaultCacheMaxEntries
+	if v := os.Getenv(cacheTTLEnvVar); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return 0, 0, fmt.Errorf("%s must be a non-negative duration, got %q", cacheTTLEnvVar, v)
+		}
+		ttl = d
+	}
+	if v := os.Getenv(cacheMaxEntriesEnvVar); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, 0, fmt.Errorf("%s must be a positive integer, got %q", cacheMaxEntriesEnvVar, v)
+		}
+		maxEntries = n
+	}
+	return ttl, maxEntries, nil
+}
+
+// negativeCacheTTLFromEnv reads how long a zone found missing stays so from
+// GCORE_NEGATIVE_CACHE_TTL (a duration).
+func negativeCacheTTLFromEnv() (time.Duration, error) {
+	v := os.Getenv(negativeCacheTTLEnvVar)
+	if v == "" {
+		return defaultNegativeCacheTTL, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative duration, got %q", negativeCacheTTLEnvVar, v)
+	}
+	return d, nil
+}
+
+// staleGCSettingsFromEnv reads how often stale challenge records are
+// collected from GCORE_STALE_RECORD_GC_INTERVAL, zero disabling the
+// collector, and their age from GCORE_STALE_RECORD_MAX_AGE.
+func staleGCSettingsFromEnv() (time.Duration, time.Duration, error) {
+	var interval time.Duration
+	maxAge := defaultStaleRecordMaxAge
+	if v := os.Getenv(staleGCIntervalEnvVar); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return 0, 0, fmt.Errorf("%s must be a non-negative duration, got %q", staleGCIntervalEnvVar, v)
+		}
+		interval = d
+	}
+	if v := os.Getenv(staleMaxAgeEnvVar); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Hour {
+			return 0, 0, fmt.Errorf("%s must be a duration of at least 1h, got %q", staleMaxAgeEnvVar, v)
+		}
+		maxAge = d
+	}
+	return interval, maxAge, nil
+}
+
+// breakerSettingsFromEnv reads how many API failures in a row open the
+// circuit breaker from GCORE_CIRCUIT_BREAKER_THRESHOLD, 0 disabling it, and
+// how long it stays open from GCORE_CIRCUIT_BREAKER_COOLDOWN.
+func breakerSettingsFromEnv() (int, time.Duration, error) {
+	threshold, cooldown := defaultBreakerThreshold, defaultBreakerCooldown
+	if v := os.Getenv(breakerThresholdEnvVar); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("%s must be a non-negative integer, got %q", breakerThresholdEnvVar, v)
+		}
+		threshold = n
+	}
+	if v := os.Getenv(breakerCooldownEnvVar); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return 0, 0, fmt.Errorf("%s must be a positive duration, got %q", breakerCooldownEnvVar, v)
+		}
+		cooldown = d
+	}
+	return threshold, cooldown, nil
+}
+
+// adminSettingsFromEnv reads the debug endpoint listen address from
+// GCORE_ADMIN_ADDR and the failure buffer size from GCORE_LAST_ERRORS_SIZE.
+func adminSettingsFromEnv() (string, int, error) {
+	size := defaultLastErrorsSize
+	if v := os.Getenv(lastErrorsSizeEnvVar); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return "", 0, fmt.Errorf("%s must be a non-negative integer, got %q", lastErrorsSizeEnvVar, v)
+		}
+		size = n
+	}
+	return os.Getenv(adminAddrEnvVar), size, nil
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func Test_loadConfig(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		cfgJSON *extapi.JSON
+		noCfg   bool
+		wantErr bool
+	}{
+		{
+			desc:  "nil config",
+			noCfg: true,
+		},
+		{
+			desc:    "empty raw config",
+			cfgJSON: &extapi.JSON{},
+			noCfg:   true,
+		},
+		{
+			desc:    "null config",
+			cfgJSON: &extapi.JSON{Raw: []byte("null")},
+			noCfg:   true,
+		},
+		{
+			desc:    "malformed config",
+			cfgJSON: &extapi.JSON{Raw: []byte("{ttl:")},
+			wantErr: true,
+		},
+		{
+			desc:    "valid config",
+			cfgJSON: &extapi.JSON{Raw: []byte(`{"apiToken":"token","ttl":120}`)},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := loadConfig(test.cfgJSON)
+			switch {
+			case test.noCfg:
+				assert.ErrorIs(t, err, errNoConfig)
+			case test.wantErr:
+				require.Error(t, err)
+				assert.NotErrorIs(t, err, errNoConfig)
+			default:
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_validateCredentials(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		cfgJSON string
+		errMsg  string
+	}{
+		{
+			desc:    "token",
+			cfgJSON: `{"apiToken":"token"}`,
+		},
+		{
+			desc:    "secret ref",
+			cfgJSON: `{"apiKeySecretRef":{"name":"gcore-api-token","key":"token"}}`,
+		},
+		{
+			desc:    "missing token",
+			cfgJSON: `{"ttl":120}`,
+			errMsg:  "neither apiToken, apiTokenFile, apiTokenSecretRef.name, apiKeySecretRef.name, tokenSource nor login.username is set",
+		},
+		{
+			desc:    "login",
+			cfgJSON: `{"login":{"username":"admin","passwordSecretRef":{"name":"gcore-login","key":"password"}}}`,
+		},
+		{
+			desc:    "login without password",
+			cfgJSON: `{"login":{"username":"admin"}}`,
+			errMsg:  `login.passwordSecretRef name and key must be set for user "admin"`,
+		},
+		{
+			desc:    "secret ref without key",
+			cfgJSON: `{"apiKeySecretRef":{"name":"gcore-api-token"}}`,
+			errMsg:  `apiKeySecretRef.key is not set for secret "gcore-api-token"`,
+		},
+		{
+			desc:    "token secret ref",
+			cfgJSON: `{"apiTokenSecretRef":{"name":"gcore","namespace":"dns","key":"token"}}`,
+		},
+		{
+			desc:    "token secret ref without key",
+			cfgJSON: `{"apiTokenSecretRef":{"name":"gcore"}}`,
+			errMsg:  `apiTokenSecretRef.key is not set for secret "gcore"`,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := loadConfig(&extapi.JSON{Raw: []byte(test.cfgJSON)})
+			require.NoError(t, err)
+
+			err = cfg.validateCredentials()
+			if test.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, test.errMsg)
+		})
+	}
+}
+
+func Test_ttlForZone(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		cfgJSON string
+		zone    string
+		want    int
+		errMsg  string
+	}{
+		{desc: "default", cfgJSON: `{}`, zone: "example.com", want: defaultTTL},
+		{desc: "configured", cfgJSON: `{"ttl":120}`, zone: "example.com", want: 120},
+		{desc: "raised to minimum", cfgJSON: `{"ttl":10}`, zone: "example.com", want: minTTL},
+		{desc: "zone override", cfgJSON: `{"ttl":120,"zoneTTLOverrides":{"example.com.":600}}`, zone: "example.com", want: 600},
+		{desc: "zone override raised to minimum", cfgJSON: `{"zoneTTLOverrides":{"example.com":1}}`,
+			zone: "example.com", want: minTTL},
+		{desc: "maximum", cfgJSON: `{"ttl":86400}`, zone: "example.com", want: maxTTL},
+		{desc: "negative", cfgJSON: `{"ttl":-1}`, errMsg: "ttl must be between 0 and 86400, got -1"},
+		{desc: "above maximum", cfgJSON: `{"ttl":86401}`, errMsg: "ttl must be between 0 and 86400, got 86401"},
+		{desc: "zone override above maximum", cfgJSON: `{"zoneTTLOverrides":{"example.com":100000}}`,
+			errMsg: `zoneTTLOverrides["example.com"] must be between 1 and 86400, got 100000`},
+	}
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			cfg, err := loadConfig(&extapi.JSON{Raw: []byte(test.cfgJSON)})
+			require.NoError(t, err)
+			err = cfg.Validate()
+			if test.errMsg != "" {
+				assert.EqualError(t, err, test.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			cfg.setDefaults()
+			assert.Equal(t, test.want, cfg.ttlForZone(test.zone))
+		})
+	}
+}
+
+func Test_validate(t *testing.T) {
+	assert.NoError(t, gcoreDNSProviderConfig{}.Validate())
+	assert.NoError(t, gcoreDNSProviderConfig{OnVerifyMismatch: verifyMismatchError}.Validate())
+	assert.ErrorContains(t, gcoreDNSProviderConfig{OnVerifyMismatch: "ignore"}.Validate(), "onVerifyMismatch")
+	assert.ErrorContains(t, gcoreDNSProviderConfig{ZoneTTLOverrides: map[string]int{"example.com": 0}}.Validate(),
+		`zoneTTLOverrides["example.com"] must be between 1 and 86400, got 0`)
+	assert.ErrorContains(t, gcoreDNSProviderConfig{ZoneTTLOverrides: map[string]int{".": 60}}.Validate(),
+		"empty zone name")
+	assert.NoError(t, gcoreDNSProviderConfig{RecordNameSuffix: "delegated.example.net."}.Validate())
+	assert.ErrorContains(t, gcoreDNSProviderConfig{RecordNameSuffix: "bad..name"}.Validate(), "recordNameSuffix")
+}
+
+func Test_cacheSettingsFromEnv(t *testing.T) {
+	ttl, maxEntries, err := cacheSettingsFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, defaultCacheTTL, ttl)
+	assert.Equal(t, defaultCacheMaxEntries, maxEntries)
+
+	t.Setenv(cacheTTLEnvVar, "30s")
+	t.Setenv(cacheMaxEntriesEnvVar, "20")
+	ttl, maxEntries, err = cacheSettingsFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, ttl)
+	assert.Equal(t, 20, maxEntries)
+
+	t.Setenv(cacheMaxEntriesEnvVar, "0")
+	_, _, err = cacheSettingsFromEnv()
+	assert.ErrorContains(t, err, cacheMaxEntriesEnvVar)
+}
+
+func Test_negativeCacheTTLFromEnv(t *testing.T) {
+	ttl, err := negativeCacheTTLFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, defaultNegativeCacheTTL, ttl)
+
+	t.Setenv(negativeCacheTTLEnvVar, "0s")
+	ttl, err = negativeCacheTTLFromEnv()
+	require.NoError(t, err)
+	assert.Zero(t, ttl)
+
+	t.Setenv(negativeCacheTTLEnvVar, "soon")
+	_, err = negativeCacheTTLFromEnv()
+	assert.ErrorContains(t, err, negativeCacheTTLEnvVar)
+}
+
+func Test_adminSettingsFromEnv(t *testing.T) {
+	addr, size, err := adminSettingsFromEnv()
+	require.NoError(t, err)
+	assert.Empty(t, addr)
+	assert.Equal(t, defaultLastErrorsSize, size)
+
+	t.Setenv(adminAddrEnvVar, ":8081")
+	t.Setenv(lastErrorsSizeEnvVar, "10")
+	addr, size, err = adminSettingsFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, ":8081", addr)
+	assert.Equal(t, 10, size)
+
+	t.Setenv(lastErrorsSizeEnvVar, "-1")
+	_, _, err = adminSettingsFromEnv()
+	assert.ErrorContains(t, err, lastErrorsSizeEnvVar)
+}
+
+func Test_staleGCSettingsFromEnv(t *testing.T) {
+	interval, maxAge, err := staleGCSettingsFromEnv()
+	require.NoError(t, err)
+	assert.Zero(t, interval)
+	assert.Equal(t, defaultStaleRecordMaxAge, maxAge)
+
+	t.Setenv(staleGCIntervalEnvVar, "1h")
+	t.Setenv(staleMaxAgeEnvVar, "72h")
+	interval, maxAge, err = staleGCSettingsFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, interval)
+	assert.Equal(t, 72*time.Hour, maxAge)
+
+	t.Setenv(staleMaxAgeEnvVar, "5m")
+	_, _, err = staleGCSettingsFromEnv()
+	assert.ErrorContains(t, err, staleMaxAgeEnvVar)
+}
+
+func Test_breakerSettingsFromEnv(t *testing.T) {
+	threshold, cooldown, err := breakerSettingsFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, defaultBreakerThreshold, threshold)
+	assert.Equal(t, defaultBreakerCooldown, cooldown)
+
+	t.Setenv(breakerThresholdEnvVar, "0")
+	t.Setenv(breakerCooldownEnvVar, "2m")
+	threshold, cooldown, err = breakerSettingsFromEnv()
+	require.NoError(t, err)
+	assert.Zero(t, threshold)
+	assert.Equal(t, 2*time.Minute, cooldown)
+
+	t.Setenv(breakerCooldownEnvVar, "0s")
+	_, _, err = breakerSettingsFromEnv()
+	assert.ErrorContains(t, err, breakerCooldownEnvVar)
+}
+
+func Test_validateScratchZone(t *testing.T) {
+	assert.ErrorContains(t, gcoreDNSProviderConfig{ScratchZone: "example.net"}.Validate(), "verifyWriteScope")
+	assert.NoError(t, gcoreDNSProviderConfig{ScratchZone: "example.net", VerifyWriteScope: true}.Validate())
+}
+
+func Test_presentDelayFor(t *testing.T) {
+	testCases := []struct {
+		desc string
+		cfg  string
+		ttl  int
+		want time.Duration
+	}{
+		{desc: "no delay by default", cfg: `{}`, ttl: 300, want: 0},
+		{desc: "fixed ignores ttl", cfg: `{"presentDelay":10}`, ttl: 300, want: 10 * time.Second},
+		{desc: "ttl below default cap", cfg: `{"presentDelayMode":"ttl"}`, ttl: 30, want: 30 * time.Second},
+		{desc: "ttl capped by default", cfg: `{"presentDelayMode":"ttl"}`, ttl: 300, want: 60 * time.Second},
+		{desc: "ttl below cap", cfg: `{"presentDelayMode":"ttl","presentDelay":120}`, ttl: 90, want: 90 * time.Second},
+		{desc: "ttl capped", cfg: `{"presentDelayMode":"ttl","presentDelay":120}`, ttl: 3600, want: 120 * time.Second},
+	}
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			cfg, err := loadConfig(&extapi.JSON{Raw: []byte(test.cfg)})
+			require.NoError(t, err)
+			require.NoError(t, cfg.Validate())
+			cfg.setDefaults()
+			assert.Equal(t, test.want, cfg.presentDelayFor(test.ttl))
+		})
+	}
+
+	assert.ErrorContains(t, gcoreDNSProviderConfig{PresentDelayMode: "random"}.Validate(), "presentDelayMode")
+	assert.ErrorContains(t, gcoreDNSProviderConfig{PresentDelay: -1}.Validate(), "presentDelay")
+}
+
+func Test_matchesKey(t *testing.T) {
+	const key = "LHDhK3oGRvkiefQnx7OOczTY5Tic_xZ6HcMOc_gmtoM"
+	testCases := []struct {
+		content    string
+		exact      bool
+		normalized bool
+		prefix     bool
+	}{
+		{content: key, exact: true, normalized: true, prefix: true},
+		{content: `"` + key + `"`, normalized: true, prefix: true},
+		{content: " " + key + "\t", normalized: true, prefix: true},
+		{content: `" ` + key + ` "`, normalized: true, prefix: true},
+		{content: key[:30], prefix: true},
+		{content: key + "x", prefix: true},
+		{content: key[:19]},
+		{content: "other-" + key},
+		{content: ""},
+	}
+	for _, test := range testCases {
+		assert.Equal(t, test.exact, gcoreDNSProviderConfig{}.matchesKey(test.content, key), "exact %q", test.content)
+		assert.Equal(t, test.exact, gcoreDNSProviderConfig{CleanupMatchMode: cleanupMatchExact}.matchesKey(test.content, key),
+			"exact %q", test.content)
+		assert.Equal(t, test.normalized,
+			gcoreDNSProviderConfig{CleanupMatchMode: cleanupMatchNormalized}.matchesKey(test.content, key),
+			"normalized %q", test.content)
+		assert.Equal(t, test.prefix, gcoreDNSProviderConfig{CleanupMatchMode: cleanupMatchPrefix}.matchesKey(test.content, key),
+			"prefix %q", test.content)
+	}
+	assert.ErrorContains(t, gcoreDNSProviderConfig{CleanupMatchMode: "fuzzy"}.Validate(), "cleanupMatchMode")
+}
+
+func Test_validateURL(t *testing.T) {
+	for _, valid := range []string{"https://api.gcore.com/dns", "http://localhost:8080", "https://api.example.com/dns/"} {
+		assert.NoError(t, validateURL(valid), valid)
+	}
+	for _, invalid := range []string{"api.gcore.com/dns", "ftp://api.gcore.com", "https://", "https://api.gcore.com/dns?x=1",
+		"://bad", "/dns"} {
+		assert.Error(t, validateURL(invalid), invalid)
+	}
+	assert.ErrorContains(t, gcoreDNSProviderConfig{ApiUrl: "api.gcore.com"}.Validate(), "apiUrl")
+	assert.ErrorContains(t, gcoreDNSProviderConfig{AuthURL: "api.gcore.com"}.Validate(), "authUrl")
+}
+
+func TestInitSDKReportsAllProblems(t *testing.T) {
+	solver := &gcoreDNSProviderSolver{}
+	_, _, err := solver.initSDK(t.Context(), challenge("_acme-challenge.example.com.", "key",
+		`{"configVersion":"v1alpha1","ttl":-5,"apiUrl":"api.gcore.com","nsSource":"whois"}`))
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid solver config: 4 problems: missing credentials: ")
+	assert.ErrorContains(t, err, `apiUrl: "api.gcore.com" is not an absolute http(s) URL`)
+	assert.ErrorContains(t, err, `nsSource must be "api" or "dns", got "whois"`)
+	assert.ErrorContains(t, err, "ttl must be between 0 and 86400, got -5")
+
+	var problems configErrors
+	require.ErrorAs(t, err, &problems)
+	assert.Len(t, problems, 4)
+
+	assert.EqualError(t, gcoreDNSProviderConfig{TTL: -1}.Validate(), "ttl must be between 0 and 86400, got -1")
+
+	// The problems of map fields are reported in the order of their keys.
+	cfg := gcoreDNSProviderConfig{ZoneTTLOverrides: map[string]int{"c.example": 0, "a.example": -1, "b.example": 60}}
+	for range 5 {
+		assert.EqualError(t, cfg.Validate(), "2 problems: "+
+			`zoneTTLOverrides["a.example"] must be between 1 and 86400, got -1; `+
+			`zoneTTLOverrides["c.example"] must be between 1 and 86400, got 0`)
+	}
+}
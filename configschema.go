@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+)
+
+// printConfigSchemaFlag makes main print the solver config JSON schema and exit.
+const printConfigSchemaFlag = "--print-config-schema"
+
+// configEnums lists the allowed values of the config fields that take one
+// of a fixed set of strings, by JSON name.
+var configEnums = map[string][]string{
+	"authMode":         {authModePermanent, authModeBearer},
+	"onVerifyMismatch": {verifyMismatchRetry, verifyMismatchError},
+	"nsSource":         {nsSourceAPI, nsSourceDNS},
+	"zoneDiscovery":    {zoneDiscoveryFilter, zoneDiscoveryProbe, zoneDiscoveryList, zoneDiscoverySOA},
+	"zoneMatch":        {zoneMatchDeepest, zoneMatchShallowest},
+	"presentDelayMode": {presentDelayFixed, presentDelayTTL},
+	"cleanupMatchMode": {cleanupMatchExact, cleanupMatchNormalized, cleanupMatchPrefix},
+	"minTLSVersion":    {tlsVersion12, tlsVersion13},
+	"configVersion":    configVersions,
+}
+
+// configSchema returns the JSON schema of the solver config, as set in the
+// issuer's dns01.webhook.config. It is derived from gcoreDNSProviderConfig
+// so it can't drift from what loadConfig accepts.
+func configSchema() ([]byte, error) {
+	schema := typeSchema(reflect.TypeOf(gcoreDNSProviderConfig{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "G-Core cert-manager webhook solver config"
+	properties := schema["properties"].(map[string]any)
+	for name, values := range configEnums {
+		properties[name].(map[string]any)["enum"] = values
+	}
+	// Renamed fields are still accepted under their old name.
+	for _, rename := range configRenames {
+		deprecated := maps.Clone(properties[rename.to].(map[string]any))
+		deprecated["deprecated"] = true
+		deprecated["description"] = fmt.Sprintf("Renamed to %s in configVersion %s.", rename.to, rename.version)
+		properties[rename.from] = deprecated
+	}
+
+	res, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(res, '\n'), nil
+}
+
+// typeSchema returns the JSON schema of a type as encoding/json decodes it.
+func typeSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		addFieldSchemas(t, properties)
+		return map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+	default:
+		return map[string]any{}
+	}
+}
+
+// addFieldSchemas adds the schema of every exported field of struct type t
+// to properties, flattening embedded structs like encoding/json does.
+func addFieldSchemas(t reflect.Type, properties map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addFieldSchemas(field.Type, properties)
+			continue
+		}
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = typeSchema(field.Type)
+	}
+}
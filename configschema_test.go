@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const configSchemaFile = "deploy/config.schema.json"
+
+// TestConfigSchemaUpToDate fails when the published schema no longer matches
+// the config struct. Regenerate it with:
+//
+//	go run . --print-config-schema > deploy/config.schema.json
+func TestConfigSchemaUpToDate(t *testing.T) {
+	want, err := configSchema()
+	require.NoError(t, err)
+	got, err := os.ReadFile(configSchemaFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got), "%s is out of date", configSchemaFile)
+}
+
+func TestConfigSchema(t *testing.T) {
+	raw, err := configSchema()
+	require.NoError(t, err)
+	var schema struct {
+		Properties map[string]struct {
+			Type       string         `json:"type"`
+			Enum       []string       `json:"enum"`
+			Properties map[string]any `json:"properties"`
+		} `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &schema))
+
+	configType := reflect.TypeOf(gcoreDNSProviderConfig{})
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		assert.Contains(t, schema.Properties, name)
+	}
+
+	assert.Equal(t, "object", schema.Properties["apiKeySecretRef"].Type)
+	assert.Contains(t, schema.Properties["apiKeySecretRef"].Properties, "name")
+	assert.Contains(t, schema.Properties["apiKeySecretRef"].Properties, "key")
+	assert.Equal(t, "integer", schema.Properties["ttl"].Type)
+	assert.Equal(t, "boolean", schema.Properties["strictCleanup"].Type)
+	assert.Equal(t, "object", schema.Properties["zoneTagFilter"].Type)
+	assert.Equal(t, []string{"retry", "error"}, schema.Properties["onVerifyMismatch"].Enum)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// configVersionV1alpha1 is the solver config version, selected by
+// configVersion. It is assumed when it is not set, so issuers written
+// before versioning keep working.
+const configVersionV1alpha1 = "v1alpha1"
+
+// configVersions lists the config versions, oldest first. The last one is
+// what gcoreDNSProviderConfig decodes. A version is added together with the
+// first field it renames, in configRenames.
+var configVersions = []string{configVersionV1alpha1}
+
+// configRename is a config field renamed in a config version.
+type configRename struct {
+	from, to, version string
+}
+
+// configRenames lists the renamed config fields, none so far. Renaming
+// apiUrl to endpoint in a new version v1 would add "v1" to configVersions
+// and {from: "apiUrl", to: "endpoint", version: "v1"} here.
+var configRenames []configRename
+
+// convertConfig converts a raw config of any config version to the latest
+// one by moving the fields of renames to their new name. It returns a
+// deprecation warning for every old field name the config uses.
+func convertConfig(raw []byte, renames []configRename) ([]byte, []string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, nil, err
+	}
+	version := configVersionV1alpha1
+	if value, ok := fields["configVersion"]; ok {
+		if err := json.Unmarshal(value, &version); err != nil {
+			return nil, nil, fmt.Errorf("configVersion: %w", err)
+		}
+	}
+	index := slices.Index(configVersions, version)
+	if index < 0 {
+		return nil, nil, fmt.Errorf("unsupported configVersion %q, must be one of %s",
+			version, strings.Join(configVersions, ", "))
+	}
+	var warnings []string
+	for _, rename := range renames {
+		value, ok := fields[rename.from]
+		if !ok {
+			continue
+		}
+		if index >= slices.Index(configVersions, rename.version) {
+			return nil, nil, fmt.Errorf("%s was renamed to %s in configVersion %s", rename.from, rename.to, rename.version)
+		}
+		if _, ok := fields[rename.to]; ok {
+			return nil, nil, fmt.Errorf("%s and %s are the same field, set only %s", rename.from, rename.to, rename.to)
+		}
+		delete(fields, rename.from)
+		fields[rename.to] = value
+		warnings = append(warnings, fmt.Sprintf("%s is deprecated, set configVersion %s and use %s instead",
+			rename.from, rename.version, rename.to))
+	}
+	if warnings == nil {
+		return raw, nil, nil
+	}
+	res, err := json.Marshal(fields)
+	return res, warnings, err
+}
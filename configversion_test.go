@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// testVersions and testRenames add a version v1 renaming url to apiUrl, to
+// test the conversion while v1alpha1 is the only version.
+var (
+	testVersions = []string{configVersionV1alpha1, "v1"}
+	testRenames  = []configRename{{from: "url", to: "apiUrl", version: "v1"}}
+)
+
+func TestConvertConfig(t *testing.T) {
+	setForTest(t, &configVersions, testVersions)
+	testCases := []struct {
+		desc     string
+		json     string
+		want     string
+		warnings []string
+		errMsg   string
+	}{
+		{desc: "v1alpha1 by default", json: `{"url":"https://api.example/dns"}`,
+			want:     `{"apiUrl":"https://api.example/dns"}`,
+			warnings: []string{"url is deprecated, set configVersion v1 and use apiUrl instead"}},
+		{desc: "explicit v1alpha1", json: `{"configVersion":"v1alpha1","url":"https://api.example/dns"}`,
+			want:     `{"apiUrl":"https://api.example/dns","configVersion":"v1alpha1"}`,
+			warnings: []string{"url is deprecated, set configVersion v1 and use apiUrl instead"}},
+		{desc: "v1alpha1 without renamed fields", json: `{"apiToken":"t"}`, want: `{"apiToken":"t"}`},
+		{desc: "v1", json: `{"configVersion":"v1","apiUrl":"https://api.example/dns"}`,
+			want: `{"configVersion":"v1","apiUrl":"https://api.example/dns"}`},
+		{desc: "old name in v1", json: `{"configVersion":"v1","url":"https://api.example/dns"}`,
+			errMsg: "url was renamed to apiUrl in configVersion v1"},
+		{desc: "old and new name", json: `{"url":"https://a.example","apiUrl":"https://b.example"}`,
+			errMsg: "url and apiUrl are the same field, set only apiUrl"},
+		{desc: "unknown version", json: `{"configVersion":"v2"}`,
+			errMsg: `unsupported configVersion "v2", must be one of v1alpha1, v1`},
+		{desc: "malformed version", json: `{"configVersion":1}`, errMsg: "configVersion: json: cannot unmarshal"},
+	}
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			raw, warnings, err := convertConfig([]byte(test.json), testRenames)
+			if test.errMsg != "" {
+				assert.ErrorContains(t, err, test.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, test.want, string(raw))
+			assert.Equal(t, test.warnings, warnings)
+		})
+	}
+}
+
+func TestLoadConfigVersions(t *testing.T) {
+	for _, json := range []string{`{"apiUrl":"https://api.example/dns"}`,
+		`{"configVersion":"v1alpha1","apiUrl":"https://api.example/dns"}`} {
+		cfg, err := loadConfig(&extapi.JSON{Raw: []byte(json)})
+		require.NoError(t, err)
+		assert.Equal(t, "https://api.example/dns", cfg.ApiUrl)
+		assert.Empty(t, cfg.warnings, "apiUrl is not deprecated")
+	}
+	assert.EqualError(t, gcoreDNSProviderConfig{ConfigVersion: "v1"}.Validate(), `unsupported configVersion "v1"`)
+	_, err := loadConfig(&extapi.JSON{Raw: []byte(`{"configVersion":"v1"}`)})
+	assert.ErrorContains(t, err, `unsupported configVersion "v1", must be one of v1alpha1`)
+}
+
+func TestDeprecatedConfigLoggedOnce(t *testing.T) {
+	setForTest(t, &configVersions, testVersions)
+	setForTest(t, &configRenames, testRenames)
+	var lines []string
+	solver := solverWithMock(newMockSDK("example.com"))
+	solver.log = funcr.New(func(_, args string) { lines = append(lines, args) }, funcr.Options{})
+	cfg := `{"apiToken":"t","url":"https://api.example/dns"}`
+
+	for range 2 {
+		require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "key", cfg)))
+	}
+	ch := challenge("_acme-challenge.example.com.", "key", cfg)
+	ch.ResourceNamespace = "other"
+	require.NoError(t, solver.Present(ch))
+
+	var warned []string
+	for _, line := range lines {
+		if strings.Contains(line, "deprecated solver config") {
+			warned = append(warned, line)
+		}
+	}
+	require.Len(t, warned, 2, "once per issuer")
+	assert.Contains(t, warned[0], `"namespace"=""`)
+	assert.Contains(t, warned[1], `"namespace"="other"`)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// credential is one named G-Core account credential of the credentials map.
+// It takes the same token sources as the top-level config.
+type credential struct {
+	ApiToken          string    `json:"apiToken"`
+	APITokenFile      string    `json:"apiTokenFile"`
+	APITokenSecretRef secretRef `json:"apiTokenSecretRef"`
+}
+
+// validate makes sure the credential names exactly one token source.
+func (c credential) validate() error {
+	sources := 0
+	for _, set := range []bool{c.ApiToken != "", c.APITokenFile != "", c.APITokenSecretRef.Name != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("exactly one of apiToken, apiTokenFile or apiTokenSecretRef must be set, got %d", sources)
+	}
+	if c.APITokenSecretRef.Name != "" && c.APITokenSecretRef.Key == "" {
+		return fmt.Errorf("apiTokenSecretRef.key is not set for secret %q", c.APITokenSecretRef.Name)
+	}
+	return nil
+}
+
+// validateZoneCredentials checks the credentials map and that every zone of
+// zoneCredentials maps to one of its entries.
+func (cfg gcoreDNSProviderConfig) validateZoneCredentials() error {
+	var problems configErrors
+	for _, name := range slices.Sorted(maps.Keys(cfg.Credentials)) {
+		if err := cfg.Credentials[name].validate(); err != nil {
+			problems.add(fmt.Errorf("credentials[%q]: %w", name, err))
+		}
+	}
+	for _, zone := range slices.Sorted(maps.Keys(cfg.ZoneCredentials)) {
+		name := cfg.ZoneCredentials[zone]
+		if strings.Trim(zone, ".") == "" {
+			problems.add(errors.New("zoneCredentials must not contain an empty zone name"))
+		}
+		if _, ok := cfg.Credentials[name]; !ok {
+			problems.add(fmt.Errorf("zoneCredentials[%q] names unknown credential %q", zone, name))
+		}
+	}
+	return problems.err()
+}
+
+// zoneCredential returns the name of the credential zoneCredentials maps the
+// deepest zone candidate of fqdn to, the first in sorted order if it is
+// listed with and without a trailing dot. The candidates are the same parent
+// domains zone discovery considers, so the account is known before the API
+// is asked for the zone.
+func (cfg gcoreDNSProviderConfig) zoneCredential(fqdn string) (string, bool) {
+	names := slices.Sorted(maps.Keys(cfg.ZoneCredentials))
+	for _, zone := range extractAllZones(fqdn) {
+		for _, name := range names {
+			if strings.EqualFold(strings.Trim(name, "."), zone) {
+				return cfg.ZoneCredentials[name], true
+			}
+		}
+	}
+	return "", false
+}
+
+// useZoneCredential replaces the top-level token sources with the
+// credential mapped to the zone of fqdn, if there is one.
+func (cfg *gcoreDNSProviderConfig) useZoneCredential(fqdn string) {
+	name, ok := cfg.zoneCredential(fqdn)
+	if !ok {
+		return
+	}
+	cred, ok := cfg.Credentials[name]
+	if !ok {
+		return
+	}
+	cfg.ApiToken = cred.ApiToken
+	cfg.APITokenFile = cred.APITokenFile
+	cfg.APITokenSecretRef = cred.APITokenSecretRef
+	cfg.APIKeySecretRef.Name, cfg.APIKeySecretRef.Key = "", ""
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateZoneCredentials(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		cfg    gcoreDNSProviderConfig
+		errMsg string
+	}{
+		{
+			desc: "mapped credential",
+			cfg: gcoreDNSProviderConfig{
+				Credentials:     map[string]credential{"shop": {ApiToken: "t"}},
+				ZoneCredentials: map[string]string{"shop.example": "shop"},
+			},
+		},
+		{
+			desc:   "unknown credential",
+			cfg:    gcoreDNSProviderConfig{ZoneCredentials: map[string]string{"shop.example": "shop"}},
+			errMsg: `zoneCredentials["shop.example"] names unknown credential "shop"`,
+		},
+		{
+			desc: "empty zone",
+			cfg: gcoreDNSProviderConfig{
+				Credentials:     map[string]credential{"shop": {ApiToken: "t"}},
+				ZoneCredentials: map[string]string{".": "shop"},
+			},
+			errMsg: "empty zone name",
+		},
+		{
+			desc:   "no token source",
+			cfg:    gcoreDNSProviderConfig{Credentials: map[string]credential{"shop": {}}},
+			errMsg: `credentials["shop"]: exactly one of apiToken, apiTokenFile or apiTokenSecretRef must be set, got 0`,
+		},
+		{
+			desc: "two token sources",
+			cfg: gcoreDNSProviderConfig{Credentials: map[string]credential{
+				"shop": {ApiToken: "t", APITokenFile: "/tokens/shop"},
+			}},
+			errMsg: "got 2",
+		},
+		{
+			desc: "secret ref without key",
+			cfg: gcoreDNSProviderConfig{Credentials: map[string]credential{
+				"shop": {APITokenSecretRef: secretRef{Name: "gcore-shop"}},
+			}},
+			errMsg: `apiTokenSecretRef.key is not set for secret "gcore-shop"`,
+		},
+	}
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			err := test.cfg.validateZoneCredentials()
+			if test.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, test.errMsg)
+		})
+	}
+}
+
+func TestValidateZoneCredentialsOrder(t *testing.T) {
+	cfg := gcoreDNSProviderConfig{
+		Credentials:     map[string]credential{"b": {}, "a": {}},
+		ZoneCredentials: map[string]string{"z.example": "z", "y.example": "y", "x.example": "a"},
+	}
+	for range 5 {
+		assert.EqualError(t, cfg.validateZoneCredentials(), "4 problems: "+
+			`credentials["a"]: exactly one of apiToken, apiTokenFile or apiTokenSecretRef must be set, got 0; `+
+			`credentials["b"]: exactly one of apiToken, apiTokenFile or apiTokenSecretRef must be set, got 0; `+
+			`zoneCredentials["y.example"] names unknown credential "y"; `+
+			`zoneCredentials["z.example"] names unknown credential "z"`)
+	}
+	cfg = gcoreDNSProviderConfig{ZoneCredentials: map[string]string{"x.example.": "b", "x.example": "a"}}
+	for range 5 {
+		name, ok := cfg.zoneCredential("_acme-challenge.x.example.")
+		assert.True(t, ok)
+		assert.Equal(t, "a", name, "the zone listed without the trailing dot sorts first")
+	}
+}
+
+func TestPresentZoneCredentials(t *testing.T) {
+	accounts := map[string]*mockSDK{
+		"main-token": newMockSDK("example.com"),
+		"shop-token": newMockSDK("shop.example", "eu.shop.example"),
+		"eu-token":   newMockSDK("eu.shop.example"),
+	}
+	solver := &gcoreDNSProviderSolver{
+		newSDK: func(_ gcoreDNSProviderConfig, token string) (dnsAPI, error) { return accounts[token], nil },
+	}
+	cfg := `{"apiToken":"main-token",
+		"credentials":{"shop":{"apiToken":"shop-token"},"eu":{"apiToken":"eu-token"}},
+		"zoneCredentials":{"shop.example.":"shop","EU.shop.example":"eu"}}`
+
+	require.NoError(t, solver.Present(challenge("_acme-challenge.www.example.com.", "main", cfg)))
+	require.NoError(t, solver.Present(challenge("_acme-challenge.shop.example.", "shop", cfg)))
+	require.NoError(t, solver.Present(challenge("_acme-challenge.www.eu.shop.example.", "eu", cfg)))
+
+	assert.Equal(t, []string{"main"}, accounts["main-token"].contents("example.com", "_acme-challenge.www.example.com"))
+	assert.Equal(t, []string{"shop"}, accounts["shop-token"].contents("shop.example", "_acme-challenge.shop.example"))
+	assert.Nil(t, accounts["shop-token"].contents("eu.shop.example", "_acme-challenge.www.eu.shop.example"),
+		"the deepest mapped zone wins")
+	assert.Equal(t, []string{"eu"},
+		accounts["eu-token"].contents("eu.shop.example", "_acme-challenge.www.eu.shop.example"))
+
+	err := solver.Present(challenge("_acme-challenge.example.org.", "k",
+		`{"credentials":{"shop":{"apiToken":"shop-token"}},"zoneCredentials":{"shop.example":"shop"}}`))
+	assert.ErrorContains(t, err, "no zoneCredentials entry matches _acme-challenge.example.org")
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+const defaultLastErrorsSize = 50
+
+// failureRecord describes one failed Present or CleanUp call.
+type failureRecord struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	FQDN      string    `json:"fqdn"`
+	Zone      string    `json:"zone"`
+	Code      int       `json:"code,omitempty"`
+	Class     string    `json:"class,omitempty"`
+	Error     string    `json:"error"`
+	RequestID string    `json:"requestID,omitempty"`
+}
+
+// failureLog is a thread-safe ring buffer of the most recent failures. A nil
+// failureLog records nothing.
+type failureLog struct {
+	mu      sync.Mutex
+	entries []failureRecord
+	next    int
+	full    bool
+}
+
+func newFailureLog(size int) *failureLog {
+	return &failureLog{entries: make([]failureRecord, size)}
+}
+
+// Add records a failure, overwriting the oldest one if the buffer is full.
+func (l *failureLog) Add(rec failureRecord) {
+	if l == nil || len(l.entries) == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = rec
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// List returns the recorded failures, newest first.
+func (l *failureLog) List() []failureRecord {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := l.next
+	if l.full {
+		n = len(l.entries)
+	}
+	res := make([]failureRecord, 0, n)
+	for i := 1; i <= n; i++ {
+		res = append(res, l.entries[(l.next-i+len(l.entries))%len(l.entries)])
+	}
+	return res
+}
+
+// ServeHTTP lists the recorded failures as JSON.
+func (l *failureLog) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(l.List())
+}
+
+// recordFailure adds a failed operation on the challenge to the failure log.
+func (c *gcoreDNSProviderSolver) recordFailure(ctx context.Context, op string, ch *v1alpha1.ChallengeRequest,
+	err error) {
+	rec := failureRecord{
+		Time:      time.Now().UTC(),
+		Operation: op,
+		FQDN:      ch.ResolvedFQDN,
+		Zone:      ch.ResolvedZone,
+		Error:     redact(err.Error()),
+		RequestID: challengeIDsFrom(ctx).requestID,
+	}
+	var apiErr dnssdk.APIError
+	if errors.As(err, &apiErr) {
+		rec.Code = apiErr.StatusCode
+	}
+	if class := errorClass(err); class != nil {
+		rec.Class = class.Error()
+	}
+	c.failures.Add(rec)
+}
+
+// cacheReport is the /debug/cache view of one cache.
+type cacheReport struct {
+	cacheStats
+	HitRate float64 `json:"hitRate"`
+}
+
+// cacheStats returns the lookup counters of every cache by name.
+func (c *gcoreDNSProviderSolver) cacheStats() map[string]cacheStats {
+	return map[string]cacheStats{
+		"zones":        c.zones.Stats(),
+		"missingZones": c.missingZones.Stats(),
+		"nameservers":  c.nameservers.Stats(),
+		"writeScope":   c.writeScope.Stats(),
+		"unfiltered":   c.unfiltered.Stats(),
+		"bearerTokens": c.bearerTokens.Stats(),
+		"sdkClients":   c.sdkClients.Stats(),
+	}
+}
+
+// serveCacheStats reports the lookup counters and hit rate of every cache.
+func (c *gcoreDNSProviderSolver) serveCacheStats(w http.ResponseWriter, _ *http.Request) {
+	reports := map[string]cacheReport{}
+	for name, stats := range c.cacheStats() {
+		reports[name] = cacheReport{cacheStats: stats, HitRate: stats.HitRate()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(reports)
+}
+
+// zoneMapping is the /debug/cache/zones view of a zone cache entry: the
+// zone a lookup of the account resolved to, by the candidate name, zone ID
+// or record name and zoneMatch policy it was looked up with.
+type zoneMapping struct {
+	Account string    `json:"account"`
+	Name    string    `json:"name,omitempty"`
+	ID      uint64    `json:"id,omitempty"`
+	Record  string    `json:"record,omitempty"`
+	Match   string    `json:"match,omitempty"`
+	Details bool      `json:"details,omitempty"`
+	Zone    string    `json:"zone"`
+	Expires time.Time `json:"expires"`
+}
+
+// serveZoneMappings lists the zone cache entries, most recently used first.
+func (c *gcoreDNSProviderSolver) serveZoneMappings(w http.ResponseWriter, _ *http.Request) {
+	mappings := []zoneMapping{}
+	c.zones.Each(func(key zoneCacheKey, details zoneDetails, expires time.Time) {
+		zone := details.Name
+		if zone == "" {
+			// A zone found by name is that name.
+			zone = key.name
+		}
+		mappings = append(mappings, zoneMapping{Account: key.account, Name: key.name, ID: key.id,
+			Record: key.record, Match: key.match, Details: key.details, Zone: zone, Expires: expires.UTC()})
+	})
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(mappings)
+}
+
+// adminHandler serves the debug endpoints, the metrics and, with
+// --enable-pprof, the profiles.
+func (c *gcoreDNSProviderSolver) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/last-errors", c.failures)
+	mux.HandleFunc("/debug/cache", c.serveCacheStats)
+	mux.HandleFunc("/debug/cache/zones", c.serveZoneMappings)
+	mux.Handle("/metrics", c.metrics)
+	if c.pprof {
+		handlePprof(mux)
+	}
+	return mux
+}
+
+// startAdminServer serves the debug endpoints and metrics on addr until stopCh is closed.
+// It only fails if addr can't be listened on; errors while serving are logged.
+func (c *gcoreDNSProviderSolver) startAdminServer(addr string, stopCh <-chan struct{}) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	server := &http.Server{Handler: c.adminHandler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-stopCh
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}()
+	go func() {
+		err := server.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			c.log.Error(err, "admin server stopped", "addr", addr)
+		}
+	}()
+	return nil
+}
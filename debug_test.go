@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+)
+
+func TestFailureLogBounds(t *testing.T) {
+	t.Parallel()
+
+	l := newFailureLog(3)
+	assert.Empty(t, l.List())
+	for i := 0; i < 5; i++ {
+		l.Add(failureRecord{FQDN: fmt.Sprintf("r%d.example.com.", i)})
+	}
+	got := l.List()
+	require.Len(t, got, 3)
+	assert.Equal(t, "r4.example.com.", got[0].FQDN)
+	assert.Equal(t, "r2.example.com.", got[2].FQDN)
+
+	var disabled *failureLog
+	disabled.Add(failureRecord{})
+	assert.Empty(t, disabled.List())
+	newFailureLog(0).Add(failureRecord{})
+}
+
+func TestFailureLogConcurrency(t *testing.T) {
+	t.Parallel()
+
+	l := newFailureLog(10)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				l.Add(failureRecord{Operation: "present"})
+				_ = l.List()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, l.List(), 10)
+}
+
+func TestRecordFailure(t *testing.T) {
+	t.Parallel()
+
+	m := newMockSDK("example.com")
+	solver := solverWithMock(m)
+	solver.failures = newFailureLog(5)
+	solver.newSDK = func(gcoreDNSProviderConfig, string) (dnsAPI, error) {
+		return nil, dnssdk.APIError{StatusCode: 401, Message: "bad header Authorization: APIKey 123$secret"}
+	}
+
+	ch := challenge("_acme-challenge.example.com.", "key", `{"apiToken":"123$secret"}`)
+	ch.ResolvedZone = "example.com."
+	require.Error(t, solver.Present(ch))
+
+	rec := httptest.NewRecorder()
+	solver.failures.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/last-errors", nil))
+	assert.NotContains(t, rec.Body.String(), "123$secret")
+
+	var got []failureRecord
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, "present", got[0].Operation)
+	assert.Equal(t, "_acme-challenge.example.com.", got[0].FQDN)
+	assert.Equal(t, "example.com.", got[0].Zone)
+	assert.Equal(t, 401, got[0].Code)
+}
+
+func TestServeCacheStats(t *testing.T) {
+	t.Parallel()
+	solver := solverWithMock(newMockSDK("example.com"))
+	solver.zones = newCache[zoneCacheKey, zoneDetails](time.Minute, 10)
+	cfg := `{"apiToken":"t"}`
+	require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "a", cfg)))
+	require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "b", cfg)))
+
+	rec := httptest.NewRecorder()
+	solver.serveCacheStats(rec, httptest.NewRequest("GET", "/debug/cache", nil))
+	var got map[string]cacheReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, cacheReport{cacheStats: cacheStats{Hits: 1, Misses: 1, Size: 1}, HitRate: 0.5}, got["zones"])
+	assert.Equal(t, cacheReport{}, got["nameservers"])
+}
+
+func TestServeZoneMappings(t *testing.T) {
+	t.Parallel()
+	solver := solverWithMock(newMockSDK("example.com"))
+	solver.zones = newCache[zoneCacheKey, zoneDetails](time.Minute, 10)
+	require.NoError(t, solver.Present(challenge("_acme-challenge.www.example.com.", "a", `{"apiToken":"t"}`)))
+
+	rec := httptest.NewRecorder()
+	solver.adminHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/cache/zones", nil))
+	var got []zoneMapping
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, accountKey(defaultAPIURL, "t"), got[0].Account)
+	// The record name resolved to its zone under the default zoneMatch.
+	assert.Equal(t, "_acme-challenge.www.example.com", got[0].Record)
+	assert.Equal(t, zoneMatchDeepest, got[0].Match)
+	assert.Equal(t, "example.com", got[0].Zone)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), got[0].Expires, 5*time.Second)
+
+	// An empty cache lists no mappings rather than null.
+	rec = httptest.NewRecorder()
+	solverWithMock(newMockSDK()).serveZoneMappings(rec, httptest.NewRequest("GET", "/debug/cache/zones", nil))
+	assert.JSONEq(t, `[]`, rec.Body.String())
+}
+
+func TestAdminServerPortInUse(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	var lines []string
+	solver := solverWithMock(newMockSDK("example.com"))
+	solver.adminAddr = ln.Addr().String()
+	solver.log = funcr.New(func(prefix, args string) { lines = append(lines, args) }, funcr.Options{})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	require.NoError(t, solver.Initialize(&rest.Config{Host: "http://127.0.0.1:1"}, stopCh))
+	require.Len(t, lines, 1)
+	assert.True(t, strings.Contains(lines[0], "admin server unavailable"), lines[0])
+
+	// Challenges are still solved without the debug endpoints.
+	assert.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "key", `{"apiToken":"t"}`)))
+}
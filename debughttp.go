@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// debugHTTPFlag logs every G-Core API request with its response, e.g. for
+// the exact payloads G-Core support asks for. Credentials are redacted.
+const debugHTTPFlag = "--debug-http"
+
+// maxDumpedBody is how much of a request or response body is logged.
+const maxDumpedBody = 4096
+
+// debugHTTP reports whether args enable --debug-http and returns args
+// without the flags.
+func debugHTTP(args []string) (bool, []string, error) {
+	return cutBoolFlag(args, debugHTTPFlag)
+}
+
+// dumpTransport logs the method, URL, status, duration and truncated bodies
+// of the requests made through base, with credentials redacted. Lines go to
+// the logger of the challenge the request is made for, to log otherwise.
+type dumpTransport struct {
+	base http.RoundTripper
+	log  logr.Logger
+}
+
+func (t *dumpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	log, err := logr.FromContext(req.Context())
+	if err != nil {
+		log = t.log
+	}
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	keysAndValues := []any{"method", req.Method, "url", redact(req.URL.String()), "requestBody", dumpBody(reqBody)}
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	keysAndValues = append(keysAndValues, "duration", time.Since(start).String())
+	if err != nil {
+		log.Info("G-Core API request failed", append(keysAndValues, "error", err.Error())...)
+		return resp, err
+	}
+	respBody, readErr := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	keysAndValues = append(keysAndValues, "status", resp.StatusCode, "responseBody", dumpBody(respBody))
+	if readErr != nil {
+		log.Info("G-Core API request failed", append(keysAndValues, "error", readErr.Error())...)
+		return nil, readErr
+	}
+	log.Info("G-Core API request", keysAndValues...)
+	return resp, nil
+}
+
+// dumpBody returns body for a log line, redacted and cut at maxDumpedBody.
+func dumpBody(body []byte) string {
+	if len(body) > maxDumpedBody {
+		return redact(string(body[:maxDumpedBody])) + fmt.Sprintf("... (%d bytes)", len(body))
+	}
+	return redact(string(body))
+}
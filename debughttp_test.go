@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugHTTPFlag(t *testing.T) {
+	t.Parallel()
+
+	enabled, args, err := debugHTTP([]string{"--secure-port=443", "--debug-http"})
+	require.NoError(t, err)
+	assert.True(t, enabled)
+	assert.Equal(t, []string{"--secure-port=443"}, args)
+
+	enabled, args, err = debugHTTP([]string{"--debug-http", "--debug-http=false", "-v=2"})
+	require.NoError(t, err)
+	assert.False(t, enabled)
+	assert.Equal(t, []string{"-v=2"}, args)
+
+	_, _, err = debugHTTP([]string{"--debug-http=maybe"})
+	assert.EqualError(t, err, `--debug-http must be true or false, got "maybe"`)
+}
+
+func TestDumpTransport(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(append([]byte(`{"echo":`), append(body, '}')...))
+	}))
+	t.Cleanup(server.Close)
+
+	var lines []string
+	client := &http.Client{Transport: &dumpTransport{base: http.DefaultTransport,
+		log: funcr.NewJSON(func(obj string) { lines = append(lines, obj) }, funcr.Options{})}}
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v2/zones?token=secret",
+		strings.NewReader(`{"password":"secret","name":"`+strings.Repeat("a", maxDumpedBody)+`"}`))
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	// The server got the whole request, and the caller the whole response.
+	assert.Contains(t, string(body), `{"echo":{"password":"secret","name":"aaa`)
+	assert.Len(t, body, len(`{"echo":}`)+len(`{"password":"secret","name":""}`)+maxDumpedBody)
+	require.Len(t, lines, 1)
+	assert.NotContains(t, lines[0], "secret")
+	assert.Contains(t, lines[0], `"msg":"G-Core API request"`)
+	assert.Contains(t, lines[0], `"method":"POST"`)
+	assert.Contains(t, lines[0], `/v2/zones?token=[REDACTED]`)
+	assert.Contains(t, lines[0], `"status":200`)
+	assert.Contains(t, lines[0], `bytes)"`)
+}
+
+func TestDebugHTTPChallenge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error":"forbidden"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	var lines []string
+	solver := &gcoreDNSProviderSolver{debugHTTP: true,
+		log: funcr.NewJSON(func(obj string) { lines = append(lines, obj) }, funcr.Options{})}
+	ch := challenge("_acme-challenge.example.com.", "key",
+		`{"apiToken":"secret","apiUrl":"`+server.URL+`","zoneDiscovery":"probe"}`)
+	ch.UID = "4b1c0c41-5d0e-4f53-9f4e-2c1d0a6f2d11"
+	require.Error(t, solver.Present(ch))
+
+	var dumped []string
+	for _, line := range lines {
+		if strings.Contains(line, `"msg":"G-Core API request"`) {
+			dumped = append(dumped, line)
+		}
+	}
+	require.NotEmpty(t, dumped)
+	assert.Contains(t, dumped[0], `"uid":"4b1c0c41-5d0e-4f53-9f4e-2c1d0a6f2d11"`)
+	assert.Contains(t, dumped[0], `"status":403`)
+	assert.Contains(t, dumped[0], `"responseBody":"{\"error\":\"forbidden\"}"`)
+	assert.NotContains(t, strings.Join(lines, "\n"), "secret")
+}
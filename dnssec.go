@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+	"github.com/miekg/dns"
+)
+
+// zoneSigned reports whether G-Core signs zone with DNSSEC. Its nameservers
+// then have to re-sign a changed RRSet before validating resolvers accept
+// it.
+func zoneSigned(ctx context.Context, sdk dnsAPI, zone string) (bool, error) {
+	details, err := sdk.ZoneDetails(ctx, zone)
+	if err != nil {
+		return false, fmt.Errorf("dnssec status of zone %s: %w", zone, err)
+	}
+	return details.DNSSECEnabled, nil
+}
+
+// verifyTXTSignatureAt asks nameserver for the TXT RRSet fqdn and the DNSKEYs
+// of zone, and checks that the RRSet carries a currently valid RRSIG by one
+// of the keys. A nameserver that answers the new records before re-signing
+// them makes validating resolvers, like those of Let's Encrypt, SERVFAIL.
+func verifyTXTSignatureAt(ctx context.Context, nameserver, zone, fqdn string) error {
+	return verifyTXTSignature(ctx, net.JoinHostPort(nameserver, "53"), zone, fqdn)
+}
+
+// verifyTXTSignature is verifyTXTSignatureAt for a host:port address.
+func verifyTXTSignature(ctx context.Context, addr, zone, fqdn string) error {
+	answer, err := dnssecQuery(ctx, addr, fqdn, dns.TypeTXT)
+	if err != nil {
+		return err
+	}
+	var txts []dns.RR
+	var sigs []*dns.RRSIG
+	for _, rr := range answer {
+		switch rr := rr.(type) {
+		case *dns.TXT:
+			txts = append(txts, rr)
+		case *dns.RRSIG:
+			if rr.TypeCovered == dns.TypeTXT {
+				sigs = append(sigs, rr)
+			}
+		}
+	}
+	if len(txts) == 0 {
+		return fmt.Errorf("no TXT records for %s", fqdn)
+	}
+	if len(sigs) == 0 {
+		return fmt.Errorf("TXT records of %s are not signed yet", fqdn)
+	}
+	keys, err := dnssecQuery(ctx, addr, dns.Fqdn(zone), dns.TypeDNSKEY)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	lastErr := fmt.Errorf("no DNSKEY of %s matches the RRSIG of %s", zone, fqdn)
+	for _, sig := range sigs {
+		if !sig.ValidityPeriod(now) {
+			lastErr = fmt.Errorf("RRSIG of %s is outside its validity period", fqdn)
+			continue
+		}
+		for _, rr := range keys {
+			key, ok := rr.(*dns.DNSKEY)
+			if !ok || key.KeyTag() != sig.KeyTag || key.Algorithm != sig.Algorithm {
+				continue
+			}
+			if err := sig.Verify(key, txts); err != nil {
+				lastErr = fmt.Errorf("RRSIG of %s: %w", fqdn, err)
+				continue
+			}
+			return nil
+		}
+	}
+	return lastErr
+}
+
+// dnssecQuery asks the nameserver at addr for the records of name with the
+// DNSSEC OK bit set, retrying over TCP when the answer was truncated.
+func dnssecQuery(ctx context.Context, addr, name string, qtype uint16) ([]dns.RR, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+	msg.SetEdns0(4096, true)
+	msg.RecursionDesired = false
+	var in *dns.Msg
+	var err error
+	for _, network := range []string{"udp", "tcp"} {
+		client := &dns.Client{Net: network, Timeout: util.DNSTimeout}
+		in, _, err = client.ExchangeContext(ctx, msg, addr)
+		if err != nil || !in.Truncated {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query %s %s at %s: %w", name, dns.TypeToString[qtype], addr, err)
+	}
+	if in.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("query %s %s at %s: %s", name, dns.TypeToString[qtype], addr,
+			strings.ToLower(dns.RcodeToString[in.Rcode]))
+	}
+	if len(in.Answer) == 0 {
+		return nil, errors.New("empty answer for " + name + " " + dns.TypeToString[qtype])
+	}
+	return in.Answer, nil
+}
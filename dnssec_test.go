@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signedZoneServer serves the DNSKEY of zone and the TXT records of fqdn
+// with the RRSIG sign returns, nil leaving them unsigned.
+func signedZoneServer(t *testing.T, zone, fqdn string, txt []string,
+	sign func(key *dns.DNSKEY, priv crypto.Signer, rrset []dns.RR) *dns.RRSIG) string {
+	t.Helper()
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	require.NoError(t, err)
+	var rrset []dns.RR
+	for _, value := range txt {
+		rrset = append(rrset, &dns.TXT{
+			Hdr: dns.RR_Header{Name: fqdn, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 120},
+			Txt: []string{value},
+		})
+	}
+	answer := append([]dns.RR(nil), rrset...)
+	if sign != nil {
+		answer = append(answer, sign(key, priv.(crypto.Signer), rrset))
+	}
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch r.Question[0].Qtype {
+		case dns.TypeDNSKEY:
+			m.Answer = []dns.RR{key}
+		case dns.TypeTXT:
+			m.Answer = answer
+		}
+		_ = w.WriteMsg(m)
+	})}
+	var started sync.WaitGroup
+	started.Add(1)
+	server.NotifyStartedFunc = started.Done
+	go func() { _ = server.ActivateAndServe() }()
+	started.Wait()
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+// rrsig returns a signer of RRSIGs valid from inception to expiration.
+func rrsig(t *testing.T, inception, expiration time.Time) func(*dns.DNSKEY, crypto.Signer, []dns.RR) *dns.RRSIG {
+	return func(key *dns.DNSKEY, priv crypto.Signer, rrset []dns.RR) *dns.RRSIG {
+		sig := &dns.RRSIG{
+			Hdr:        dns.RR_Header{Name: rrset[0].Header().Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: 120},
+			KeyTag:     key.KeyTag(),
+			SignerName: key.Hdr.Name,
+			Algorithm:  key.Algorithm,
+			Inception:  uint32(inception.Unix()),
+			Expiration: uint32(expiration.Unix()),
+		}
+		require.NoError(t, sig.Sign(priv, rrset))
+		return sig
+	}
+}
+
+func TestVerifyTXTSignature(t *testing.T) {
+	const zone, fqdn = "example.com.", "_acme-challenge.example.com."
+	now := time.Now()
+	valid := rrsig(t, now.Add(-time.Hour), now.Add(time.Hour))
+
+	testCases := []struct {
+		desc string
+		sign func(*dns.DNSKEY, crypto.Signer, []dns.RR) *dns.RRSIG
+		err  string
+	}{
+		{desc: "signed", sign: valid},
+		{desc: "not signed", err: "TXT records of _acme-challenge.example.com. are not signed yet"},
+		{desc: "expired", sign: rrsig(t, now.Add(-2*time.Hour), now.Add(-time.Hour)), err: "outside its validity period"},
+		{desc: "signature of the old rrset", err: "RRSIG of _acme-challenge.example.com.",
+			sign: func(key *dns.DNSKEY, priv crypto.Signer, rrset []dns.RR) *dns.RRSIG {
+				return valid(key, priv, rrset[:1])
+			}},
+	}
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			addr := signedZoneServer(t, zone, fqdn, []string{"other", "token-A"}, test.sign)
+			err := verifyTXTSignature(context.Background(), addr, "example.com", fqdn)
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, test.err)
+			}
+		})
+	}
+}
+
+func TestWaitForPropagationSigned(t *testing.T) {
+	setForTest(t, &propagationPollInterval, time.Millisecond)
+	for _, signed := range []bool{false, true} {
+		mock := newMockSDK("example.com")
+		mock.zones["example.com"].nameservers = []string{"ns1.gcorelabs.net"}
+		mock.zones["example.com"].dnssec = signed
+		solver := solverWithMock(mock)
+		solver.lookupTXT = func(context.Context, string, string) ([]string, error) {
+			return []string{"token-A"}, nil
+		}
+		verified := 0
+		solver.verifyTXTSignature = func(_ context.Context, nameserver, zone, fqdn string) error {
+			assert.Equal(t, "ns1.gcorelabs.net", nameserver)
+			assert.Equal(t, "example.com", zone)
+			assert.Equal(t, "_acme-challenge.example.com.", fqdn)
+			if verified++; verified < 3 {
+				return assert.AnError
+			}
+			return nil
+		}
+
+		cfg := `{"apiToken":"t","verifyPropagation":true}`
+		require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "token-A", cfg)))
+		if signed {
+			assert.Equal(t, 3, verified, "a signed zone waits for the signature")
+		} else {
+			assert.Zero(t, verified)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultDrainTimeout is how long shutdown waits for Present and CleanUp
+// calls in flight by default, below the 30s termination grace period of pods.
+const defaultDrainTimeout = 25 * time.Second
+
+// errShuttingDown rejects Present and CleanUp calls arriving after shutdown
+// began. cert-manager retries them, possibly on another replica.
+var errShuttingDown = errors.New("webhook is shutting down, the challenge will be retried")
+
+// inflightOps tracks the Present and CleanUp calls in flight, so shutdown
+// lets them finish instead of leaving an RRSet half updated.
+type inflightOps struct {
+	mu       sync.Mutex
+	ops      sync.WaitGroup
+	count    int
+	draining bool
+	// drained is closed once draining ended, nil until it began.
+	drained chan struct{}
+}
+
+// start registers a call, false once draining began.
+func (f *inflightOps) start() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.draining {
+		return false
+	}
+	f.ops.Add(1)
+	f.count++
+	return true
+}
+
+func (f *inflightOps) finish() {
+	f.mu.Lock()
+	f.count--
+	f.mu.Unlock()
+	f.ops.Done()
+}
+
+// drain rejects new calls and waits up to timeout for those in flight. It
+// returns how many are still running.
+func (f *inflightOps) drain(timeout time.Duration) int {
+	f.mu.Lock()
+	f.draining = true
+	f.drained = make(chan struct{})
+	drained := f.drained
+	f.mu.Unlock()
+	defer close(drained)
+
+	done := make(chan struct{})
+	go func() {
+		f.ops.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.count
+}
+
+// wait blocks until a drain that began has ended.
+func (f *inflightOps) wait() {
+	f.mu.Lock()
+	drained := f.drained
+	f.mu.Unlock()
+	if drained != nil {
+		<-drained
+	}
+}
+
+// drainOnStop drains the calls in flight once stopCh is closed.
+func (c *gcoreDNSProviderSolver) drainOnStop(stopCh <-chan struct{}) {
+	go func() {
+		<-stopCh
+		c.log.Info("shutting down, waiting for challenges in flight", "timeout", c.drainTimeout.String())
+		if left := c.inflight.drain(c.drainTimeout); left > 0 {
+			c.log.Info("drain timeout reached, challenges are still in flight", "count", left)
+		}
+	}()
+}
+
+// drainTimeoutFromEnv reads how long shutdown waits for the calls in flight
+// from GCORE_DRAIN_TIMEOUT, 0 not waiting.
+func drainTimeoutFromEnv() (time.Duration, error) {
+	v := os.Getenv(drainTimeoutEnvVar)
+	if v == "" {
+		return defaultDrainTimeout, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative duration, got %q", drainTimeoutEnvVar, v)
+	}
+	return d, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingAPI holds record creations until release is closed.
+type blockingAPI struct {
+	*mockSDK
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingAPI) CreateRRSet(ctx context.Context, zone, name, recordType string, record dnssdk.RRSet) error {
+	close(b.started)
+	<-b.release
+	return b.mockSDK.CreateRRSet(ctx, zone, name, recordType, record)
+}
+
+func TestDrainOnStop(t *testing.T) {
+	api := &blockingAPI{mockSDK: newMockSDK("example.com"), started: make(chan struct{}),
+		release: make(chan struct{})}
+	solver := &gcoreDNSProviderSolver{
+		newSDK:       func(gcoreDNSProviderConfig, string) (dnsAPI, error) { return api, nil },
+		log:          logr.Discard(),
+		drainTimeout: time.Minute,
+	}
+	cfg := `{"apiToken":"t"}`
+	presented := make(chan error)
+	go func() { presented <- solver.Present(challenge("_acme-challenge.example.com.", "a", cfg)) }()
+	<-api.started
+
+	stopCh := make(chan struct{})
+	solver.drainOnStop(stopCh)
+	close(stopCh)
+	// New calls are turned away once the drain began.
+	require.Eventually(t, func() bool {
+		return solver.Present(challenge("_acme-challenge.example.com.", "b", cfg)) == errShuttingDown
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, errShuttingDown, solver.CleanUp(challenge("_acme-challenge.example.com.", "a", cfg)))
+
+	waited := make(chan struct{})
+	go func() {
+		solver.inflight.wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("drain ended with a Present in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(api.release)
+	require.NoError(t, <-presented)
+	<-waited
+	assert.Equal(t, []string{"a"}, api.contents("example.com", "_acme-challenge.example.com"))
+}
+
+func TestDrainTimeout(t *testing.T) {
+	t.Parallel()
+
+	var ops inflightOps
+	require.True(t, ops.start())
+	assert.Equal(t, 1, ops.drain(10*time.Millisecond))
+	ops.wait()
+	assert.False(t, ops.start())
+	ops.finish()
+
+	// Without a drain there is nothing to wait for.
+	var idle inflightOps
+	idle.wait()
+	assert.Equal(t, 0, idle.drain(time.Minute))
+}
+
+func TestDrainTimeoutFromEnv(t *testing.T) {
+	t.Setenv(drainTimeoutEnvVar, "")
+	d, err := drainTimeoutFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, defaultDrainTimeout, d)
+
+	t.Setenv(drainTimeoutEnvVar, "0s")
+	d, err = drainTimeoutFromEnv()
+	require.NoError(t, err)
+	assert.Zero(t, d)
+
+	t.Setenv(drainTimeoutEnvVar, "-1s")
+	_, err = drainTimeoutFromEnv()
+	assert.EqualError(t, err, `GCORE_DRAIN_TIMEOUT must be a non-negative duration, got "-1s"`)
+}
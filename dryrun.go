@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/go-logr/logr"
+)
+
+// dryRunFlag makes every challenge a dry run, as if each issuer set dryRun.
+const dryRunFlag = "--dry-run"
+
+// dryRunKey identifies an RRSet changed in a dry run.
+type dryRunKey struct {
+	zone, name, recordType string
+}
+
+// dryRunAPI logs the changes a challenge would make instead of making them,
+// and passes the lookups on to dnsAPI. Later reads of a changed RRSet see
+// the change, so the challenge takes the same path it would take for real:
+// creating an RRSet that exists fails like it does in the API, and CleanUp
+// sees its record gone once the deletion was logged.
+type dryRunAPI struct {
+	dnsAPI
+	log logr.Logger
+
+	mu sync.Mutex
+	// rrsets holds the RRSets changed so far, nil for deleted ones.
+	rrsets map[dryRunKey]*dnssdk.RRSet
+}
+
+// dryRunWrap returns api logging its changes instead of making them when
+// cfg or the solver asks for a dry run, marking cfg as one.
+func (c *gcoreDNSProviderSolver) dryRunWrap(ctx context.Context, api dnsAPI, cfg *gcoreDNSProviderConfig) dnsAPI {
+	if !cfg.DryRun && !c.dryRun {
+		return api
+	}
+	cfg.DryRun = true
+	return newDryRunAPI(api, c.logger(ctx))
+}
+
+// dryRunContext marks the lines logged for a challenge of cfg as a dry run.
+func dryRunContext(ctx context.Context, cfg gcoreDNSProviderConfig) context.Context {
+	log, err := logr.FromContext(ctx)
+	if !cfg.DryRun || err != nil {
+		return ctx
+	}
+	return logr.NewContext(ctx, log.WithValues("dryRun", true))
+}
+
+func newDryRunAPI(api dnsAPI, log logr.Logger) *dryRunAPI {
+	return &dryRunAPI{dnsAPI: api, log: log, rrsets: map[dryRunKey]*dnssdk.RRSet{}}
+}
+
+// changed returns the RRSet as changed in the dry run, nil if it was deleted,
+// and whether it was changed at all.
+func (d *dryRunAPI) changed(zone, name, recordType string) (*dnssdk.RRSet, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	rrset, ok := d.rrsets[dryRunKey{zone, name, recordType}]
+	return rrset, ok
+}
+
+func (d *dryRunAPI) change(zone, name, recordType string, rrset *dnssdk.RRSet) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rrsets[dryRunKey{zone, name, recordType}] = rrset
+}
+
+func (d *dryRunAPI) RRSet(ctx context.Context, zone, name, recordType string) (dnssdk.RRSet, error) {
+	rrset, _, err := d.RRSetWithVersion(ctx, zone, name, recordType)
+	return rrset, err
+}
+
+func (d *dryRunAPI) RRSetWithVersion(ctx context.Context, zone, name, recordType string) (dnssdk.RRSet,
+	rrsetVersion, error) {
+	rrset, ok := d.changed(zone, name, recordType)
+	switch {
+	case !ok:
+		return d.dnsAPI.RRSetWithVersion(ctx, zone, name, recordType)
+	case rrset == nil:
+		return dnssdk.RRSet{}, rrsetVersion{}, dnssdk.APIError{StatusCode: http.StatusNotFound,
+			Message: "rrset not found"}
+	}
+	return *rrset, rrsetVersion{}, nil
+}
+
+func (d *dryRunAPI) CreateRRSet(ctx context.Context, zone, name, recordType string, record dnssdk.RRSet) error {
+	_, err := d.RRSet(ctx, zone, name, recordType)
+	if err == nil {
+		return dnssdk.APIError{StatusCode: http.StatusConflict, Message: "rrset already exists"}
+	}
+	if !isNotFound(err) {
+		return err
+	}
+	d.logRRSet("create", zone, name, recordType, record)
+	d.change(zone, name, recordType, &record)
+	return nil
+}
+
+func (d *dryRunAPI) UpdateRRSet(_ context.Context, zone, name, recordType string, record dnssdk.RRSet) error {
+	d.logRRSet("update", zone, name, recordType, record)
+	d.change(zone, name, recordType, &record)
+	return nil
+}
+
+func (d *dryRunAPI) UpdateRRSetIfMatch(ctx context.Context, zone, name, recordType string, record dnssdk.RRSet,
+	_ rrsetVersion) error {
+	return d.UpdateRRSet(ctx, zone, name, recordType, record)
+}
+
+func (d *dryRunAPI) DeleteRRSet(_ context.Context, zone, name, recordType string) error {
+	d.log.Info("dry run: would delete rrset", "zone", zone, "name", name, "type", recordType)
+	d.change(zone, name, recordType, nil)
+	return nil
+}
+
+func (d *dryRunAPI) DeleteRRSetIfMatch(ctx context.Context, zone, name, recordType string, _ rrsetVersion) error {
+	return d.DeleteRRSet(ctx, zone, name, recordType)
+}
+
+func (d *dryRunAPI) CreateZone(_ context.Context, name string) (uint64, error) {
+	d.log.Info("dry run: would create zone", "zone", name)
+	return 0, nil
+}
+
+func (d *dryRunAPI) EnableZone(_ context.Context, name string) error {
+	d.log.Info("dry run: would enable zone", "zone", name)
+	return nil
+}
+
+// logRRSet logs the RRSet a create or update would write.
+func (d *dryRunAPI) logRRSet(op, zone, name, recordType string, record dnssdk.RRSet) {
+	var values []string
+	for _, rr := range record.Records {
+		for _, content := range rr.Content {
+			values = append(values, fmt.Sprint(content))
+		}
+	}
+	d.log.Info("dry run: would "+op+" rrset", "zone", zone, "name", name, "type", recordType, "ttl", record.TTL,
+		"values", values)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dryRunSolver returns a solver on mock whose log lines and audit records
+// are collected.
+func dryRunSolver(mock *mockSDK) (*gcoreDNSProviderSolver, func() string, *bytes.Buffer) {
+	var mu sync.Mutex
+	var lines []string
+	solver := solverWithMock(mock)
+	solver.log = funcr.New(func(prefix, args string) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, args)
+	}, funcr.Options{})
+	var audit bytes.Buffer
+	solver.audit = &auditLog{w: &audit, now: time.Now}
+	return solver, func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return strings.Join(lines, "\n")
+	}, &audit
+}
+
+func TestDryRun(t *testing.T) {
+	t.Parallel()
+
+	mock := newMockSDK("example.com")
+	solver, logged, audit := dryRunSolver(mock)
+	cfg := `{"apiToken":"t","dryRun":true,"verifyPropagation":true,"propagationTimeout":5}`
+
+	// Propagation is not verified, the record never reaches DNS.
+	ch := challenge("_acme-challenge.example.com.", "key", cfg)
+	require.NoError(t, solver.Present(ch))
+	assert.Nil(t, mock.contents("example.com", "_acme-challenge.example.com"))
+	assert.Contains(t, logged(), `"msg"="dry run: would create rrset"`)
+	assert.Contains(t, logged(), `"zone"="example.com" "name"="_acme-challenge.example.com" "type"="TXT" "ttl"=300 `+
+		`"values"=["key"]`)
+	assert.Contains(t, logged(), `"msg"="presented"`)
+	assert.Contains(t, logged(), `"dryRun"=true`)
+
+	// A CleanUp of a record that exists logs its removal and keeps it.
+	require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "key", `{"apiToken":"t"}`)))
+	writes := mock.writes
+	require.NoError(t, solver.CleanUp(ch))
+	assert.Equal(t, []string{"key"}, mock.contents("example.com", "_acme-challenge.example.com"))
+	assert.Contains(t, logged(), `"msg"="dry run: would delete rrset"`)
+
+	// Creating the existing RRSet fails like in the API, so the record is
+	// added to it.
+	other := challenge("_acme-challenge.example.com.", "other", cfg)
+	require.NoError(t, solver.Present(other))
+	assert.Equal(t, []string{"key"}, mock.contents("example.com", "_acme-challenge.example.com"))
+	assert.Contains(t, logged(), `"msg"="dry run: would update rrset"`)
+	assert.Contains(t, logged(), `"values"=["key" "other"]`)
+
+	assert.Equal(t, writes, mock.writes)
+	assert.NotContains(t, audit.String(), `"action":"delete"`)
+	assert.NotContains(t, audit.String(), `"action":"update"`)
+}
+
+func TestDryRunFlag(t *testing.T) {
+	t.Setenv(apiTokenEnvVar, "t")
+
+	mock := newMockSDK("example.com")
+	solver, logged, _ := dryRunSolver(mock)
+	solver.dryRun = true
+	solver.writeScope = newCache[scopeCacheKey, struct{}](time.Minute, 10)
+
+	ch := challenge("_acme-challenge.example.com.", "key", `{"apiToken":"t","verifyWriteScope":true}`)
+	require.NoError(t, solver.Present(ch))
+	assert.Nil(t, mock.contents("example.com", "_acme-challenge.example.com"))
+	assert.Zero(t, mock.writes)
+	assert.Contains(t, logged(), `"zone"="example.com" "name"="_cert-manager-write-probe.example.com" "type"="TXT" `+
+		`"ttl"=300 "values"=["probe"]`)
+	assert.Contains(t, logged(), `"msg"="dry run: would delete rrset"`)
+	// The probe proved nothing, so it runs again for real challenges.
+	assert.Zero(t, solver.writeScope.Stats().Size)
+
+	// The webhook's own writes, e.g. of the stale record collector, are dry
+	// runs as well.
+	api, cfg, err := solver.ambientAPI(staleGCIntervalEnvVar)
+	require.NoError(t, err)
+	assert.True(t, cfg.DryRun)
+	require.NoError(t, api.DeleteRRSet(t.Context(), "example.com", "_acme-challenge.example.com", txtType))
+	assert.Zero(t, mock.writes+mock.deletes)
+}
+
+func TestDryRunCreateZone(t *testing.T) {
+	t.Parallel()
+
+	mock := newMockSDK("example.com")
+	solver, logged, _ := dryRunSolver(mock)
+	ch := challenge("_acme-challenge.www.customer.com.", "key", `{"apiToken":"t","dryRun":true,"allowZoneCreation":true}`)
+	require.NoError(t, solver.Present(ch))
+	assert.NotContains(t, mock.zones, "customer.com")
+	assert.Contains(t, logged(), `"msg"="dry run: would create zone"`)
+	assert.NotContains(t, logged(), "created zone for challenge record")
+}
+
+func TestDryRunCheck(t *testing.T) {
+	t.Parallel()
+
+	mock := newMockSDK("example.com")
+	out, ok := runDiagnose(t, mock, "--zone=example.com", "--dry-run")
+	assert.True(t, ok)
+	assert.Contains(t, out, "write:       skipped, dry run\n")
+	assert.Zero(t, mock.writes)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+)
+
+// Error classes of failed challenges. They tell from the Challenge status
+// whether to fix the credentials, create, delegate or enable the zone, or
+// just wait for the webhook to retry. errZoneNotFound and errZoneDisabled
+// are classes as well.
+var (
+	errAuth           = errors.New("authentication failed")
+	errRateLimited    = errors.New("rate limited")
+	errAPIUnavailable = errors.New("G-Core API unavailable")
+)
+
+// classSummaries are the leading sentences of classified errors.
+var classSummaries = map[error]string{
+	errAuth:           "G-Core API rejected the credentials, check the API token and its permissions",
+	errZoneDisabled:   "G-Core zone is disabled, enable it in the G-Core account or set enableDisabledZones",
+	errZoneNotFound:   "no G-Core zone found for the record, check that the zone exists in the account and the domain is delegated to G-Core",
+	errRateLimited:    "G-Core API rate limit reached, the challenge is retried later",
+	errAPIUnavailable: "G-Core API unavailable, the challenge is retried later",
+}
+
+// classifiedError is an error of a failed challenge led by what to do about
+// it. It matches both its class and the errors it wraps.
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string {
+	return classSummaries[e.class] + ": " + e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.class, e.err}
+}
+
+// errorClass returns the class of err, nil when it has none.
+func errorClass(err error) error {
+	var classified *classifiedError
+	if errors.As(err, &classified) {
+		return classified.class
+	}
+	var apiErr dnssdk.APIError
+	hasAPIErr := errors.As(err, &apiErr)
+	switch {
+	case errors.Is(err, errZoneDisabled):
+		return errZoneDisabled
+	case hasAPIErr && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden):
+		return errAuth
+	case errors.Is(err, errZoneNotFound):
+		return errZoneNotFound
+	case isRateLimited(err):
+		return errRateLimited
+	case errors.Is(err, errAPIUnreachable), errors.Is(err, errProviderUnavailable), isProviderFailure(err):
+		return errAPIUnavailable
+	}
+	return nil
+}
+
+// classifyError leads err with the summary of its class, if it has one.
+func classifyError(err error) error {
+	var classified *classifiedError
+	if err == nil || errors.As(err, &classified) {
+		return err
+	}
+	class := errorClass(err)
+	if class == nil {
+		return err
+	}
+	return &classifiedError{class: class, err: err}
+}
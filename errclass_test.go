@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorClass(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		err   error
+		class error
+	}{
+		{desc: "unauthorized", err: dnssdk.APIError{StatusCode: http.StatusUnauthorized}, class: errAuth},
+		{desc: "forbidden", err: fmt.Errorf("fetch rrset: %w", dnssdk.APIError{StatusCode: http.StatusForbidden}),
+			class: errAuth},
+		{desc: "zone not found", err: fmt.Errorf("zone %q %w", "example.com", errZoneNotFound), class: errZoneNotFound},
+		{desc: "rate limited", err: dnssdk.APIError{StatusCode: http.StatusTooManyRequests}, class: errRateLimited},
+		{desc: "server error", err: dnssdk.APIError{StatusCode: http.StatusBadGateway}, class: errAPIUnavailable},
+		{desc: "unreachable", err: fmt.Errorf("%w: %w", errAPIUnreachable, syscall.ECONNREFUSED), class: errAPIUnavailable},
+		{desc: "circuit open", err: fmt.Errorf("%w at api", errProviderUnavailable), class: errAPIUnavailable},
+		{desc: "timeout", err: &url.Error{Op: "Get", URL: "https://api.gcore.com", Err: &timeoutError{}},
+			class: errAPIUnavailable},
+		{desc: "bad request", err: dnssdk.APIError{StatusCode: http.StatusBadRequest}},
+		{desc: "config", err: errors.New("invalid solver config: ttl must be between 0 and 86400")},
+	}
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.class, errorClass(test.err))
+			err := classifyError(test.err)
+			assert.ErrorIs(t, err, test.err)
+			if test.class == nil {
+				assert.Equal(t, test.err, err)
+				return
+			}
+			assert.ErrorIs(t, err, test.class)
+			assert.True(t, strings.HasPrefix(err.Error(), classSummaries[test.class]+": "), err.Error())
+			assert.Equal(t, err, classifyError(err), "errors are classified once")
+		})
+	}
+	assert.NoError(t, classifyError(nil))
+}
+
+// timeoutError is a net.Error that timed out.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestPresentClassifiedErrors(t *testing.T) {
+	setForTest(t, &retryBackoff, time.Millisecond)
+	mock := newMockSDK("example.com")
+	solver := solverWithMock(mock)
+	solver.failures = newFailureLog(10)
+
+	err := solver.Present(challenge("_acme-challenge.example.org.", "key", `{"apiToken":"t"}`))
+	require.ErrorIs(t, err, errZoneNotFound)
+	assert.True(t, strings.HasPrefix(err.Error(), "no G-Core zone found for the record"), err.Error())
+
+	forbidden := &flakyAPI{mockSDK: mock, failures: 1, err: dnssdk.APIError{StatusCode: http.StatusForbidden, Message: "forbidden"}}
+	solver.newSDK = func(gcoreDNSProviderConfig, string) (dnsAPI, error) { return forbidden, nil }
+	err = solver.CleanUp(challenge("_acme-challenge.example.com.", "key", `{"apiToken":"t","zoneDiscovery":"probe"}`))
+	require.ErrorIs(t, err, errAuth)
+	assert.True(t, strings.HasPrefix(err.Error(), "G-Core API rejected the credentials"), err.Error())
+
+	failures := solver.failures.List()
+	require.Len(t, failures, 2)
+	assert.Equal(t, errAuth.Error(), failures[0].Class)
+	assert.Equal(t, errZoneNotFound.Error(), failures[1].Class)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+	cminformers "github.com/cert-manager/cert-manager/pkg/client/informers/externalversions"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+// Reasons of the Warning Events of failed challenges.
+const (
+	reasonPresentFailed = "PresentFailed"
+	reasonCleanUpFailed = "CleanUpFailed"
+)
+
+const (
+	eventComponent = "cert-manager-webhook-gcore"
+	// eventLookupTimeout bounds finding the objects an Event is about, so
+	// a slow Kubernetes API does not hold up the challenge.
+	eventLookupTimeout = 5 * time.Second
+	// maxEventMessage is the longest message the Kubernetes API accepts.
+	maxEventMessage = 1024
+	// challengeUIDIndex indexes the cached Challenges by UID, the only
+	// thing besides the issuer namespace a request tells about them.
+	challengeUIDIndex = "uid"
+)
+
+// startEventRecorder sends the Events of failed challenges to the
+// Kubernetes API, and caches the Challenges of all namespaces to find the
+// ones they are about, until stopCh is closed.
+func (c *gcoreDNSProviderSolver) startEventRecorder(client kubernetes.Interface, cm cmclient.Interface,
+	stopCh <-chan struct{}) error {
+	informer := cminformers.NewSharedInformerFactory(cm, 0).Acme().V1().Challenges().Informer()
+	if err := informer.AddIndexers(toolscache.Indexers{challengeUIDIndex: challengeUID}); err != nil {
+		return fmt.Errorf("challenge informer: %w", err)
+	}
+	go informer.Run(stopCh)
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	c.events = broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: eventComponent})
+	c.certManager = cm
+	c.challenges = informer.GetIndexer()
+	go func() {
+		<-stopCh
+		broadcaster.Shutdown()
+	}()
+	return nil
+}
+
+// challengeUID is the challengeUIDIndex function.
+func challengeUID(obj any) ([]string, error) {
+	challenge, ok := obj.(*cmacme.Challenge)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %T", obj)
+	}
+	return []string{string(challenge.UID)}, nil
+}
+
+// recordEvent emits a Warning Event describing err on the Challenge of ch
+// and on the Certificate it is solved for, so the cause shows in kubectl
+// describe. Objects that can't be found are skipped.
+func (c *gcoreDNSProviderSolver) recordEvent(ctx context.Context, reason string, ch *v1alpha1.ChallengeRequest,
+	err error) {
+	if c.events == nil || c.certManager == nil || c.challenges == nil {
+		return
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, eventLookupTimeout)
+	defer cancel()
+	refs, lookupErr := c.challengeObjects(lookupCtx, ch)
+	if lookupErr != nil {
+		c.logger(ctx).V(1).Info("no object found to report the failure on", "error", lookupErr.Error())
+	}
+	msg := err.Error()
+	if len(msg) > maxEventMessage {
+		msg = msg[:maxEventMessage-3] + "..."
+	}
+	for _, ref := range refs {
+		c.events.Event(ref, corev1.EventTypeWarning, reason, msg)
+	}
+}
+
+// challengeObjects returns references to the Challenge of ch and, following
+// its owners, the Certificate it is solved for, as far as they are found.
+// The request only identifies the Challenge by UID: its ResourceNamespace
+// is the issuer's, which for a ClusterIssuer is cert-manager's cluster
+// resource namespace rather than the Challenge's, so the Challenge is
+// looked up in the cache of all namespaces.
+func (c *gcoreDNSProviderSolver) challengeObjects(ctx context.Context,
+	ch *v1alpha1.ChallengeRequest) ([]*corev1.ObjectReference, error) {
+	objs, err := c.challenges.ByIndex(challengeUIDIndex, string(ch.UID))
+	if err != nil {
+		return nil, fmt.Errorf("find challenge: %w", err)
+	}
+	if len(objs) == 0 {
+		return nil, fmt.Errorf("challenge %s not found", ch.UID)
+	}
+	challenge := objs[0].(*cmacme.Challenge)
+	namespace := challenge.Namespace
+	refs := []*corev1.ObjectReference{objectReference(cmacme.SchemeGroupVersion.String(), "Challenge",
+		challenge.ObjectMeta)}
+
+	order := ownerName(challenge.ObjectMeta, "Order")
+	if order == "" {
+		return refs, nil
+	}
+	o, err := c.certManager.AcmeV1().Orders(namespace).Get(ctx, order, metaV1.GetOptions{})
+	if err != nil {
+		return refs, fmt.Errorf("get order %s: %w", order, err)
+	}
+	request := ownerName(o.ObjectMeta, "CertificateRequest")
+	if request == "" {
+		return refs, nil
+	}
+	cr, err := c.certManager.CertmanagerV1().CertificateRequests(namespace).Get(ctx, request,
+		metaV1.GetOptions{})
+	if err != nil {
+		return refs, fmt.Errorf("get certificaterequest %s: %w", request, err)
+	}
+	certificate := cr.Annotations[cmapi.CertificateNameKey]
+	if certificate == "" {
+		return refs, nil
+	}
+	cert, err := c.certManager.CertmanagerV1().Certificates(namespace).Get(ctx, certificate,
+		metaV1.GetOptions{})
+	if err != nil {
+		return refs, fmt.Errorf("get certificate %s: %w", certificate, err)
+	}
+	return append(refs, objectReference(cmapi.SchemeGroupVersion.String(), "Certificate", cert.ObjectMeta)), nil
+}
+
+// ownerName returns the name of the controlling owner of the given kind.
+func ownerName(meta metaV1.ObjectMeta, kind string) string {
+	for _, owner := range meta.OwnerReferences {
+		if owner.Kind == kind && owner.Controller != nil && *owner.Controller {
+			return owner.Name
+		}
+	}
+	return ""
+}
+
+func objectReference(apiVersion, kind string, meta metaV1.ObjectMeta) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion:      apiVersion,
+		Kind:            kind,
+		Namespace:       meta.Namespace,
+		Name:            meta.Name,
+		UID:             meta.UID,
+		ResourceVersion: meta.ResourceVersion,
+	}
+}
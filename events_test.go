@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmfake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+// controlledBy returns the owner reference of a controlling owner.
+func controlledBy(kind, name string) []metaV1.OwnerReference {
+	controller := true
+	return []metaV1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+// certificateObjects are a Certificate and the objects cert-manager creates
+// to solve its challenge.
+func certificateObjects() []runtime.Object {
+	return []runtime.Object{
+		&cmapi.Certificate{ObjectMeta: metaV1.ObjectMeta{Name: "web", Namespace: "team-a", UID: "cert-uid"}},
+		&cmapi.CertificateRequest{ObjectMeta: metaV1.ObjectMeta{Name: "web-1", Namespace: "team-a",
+			Annotations: map[string]string{cmapi.CertificateNameKey: "web"}}},
+		&cmacme.Order{ObjectMeta: metaV1.ObjectMeta{Name: "web-1-123", Namespace: "team-a",
+			OwnerReferences: controlledBy("CertificateRequest", "web-1")}},
+		&cmacme.Challenge{ObjectMeta: metaV1.ObjectMeta{Name: "web-1-123-456", Namespace: "team-a", UID: "challenge-uid",
+			OwnerReferences: controlledBy("Order", "web-1-123")}},
+	}
+}
+
+// challengeCache returns the Challenges among objs indexed as the informer
+// of startEventRecorder does.
+func challengeCache(t *testing.T, objs ...runtime.Object) toolscache.Indexer {
+	t.Helper()
+	indexer := toolscache.NewIndexer(toolscache.MetaNamespaceKeyFunc,
+		toolscache.Indexers{challengeUIDIndex: challengeUID})
+	for _, obj := range objs {
+		if challenge, ok := obj.(*cmacme.Challenge); ok {
+			require.NoError(t, indexer.Add(challenge))
+		}
+	}
+	return indexer
+}
+
+func TestRecordEvent(t *testing.T) {
+	t.Parallel()
+
+	solver := solverWithMock(newMockSDK("example.com"))
+	require.NoError(t, solver.startEventRecorder(fake.NewSimpleClientset(),
+		cmfake.NewSimpleClientset(certificateObjects()...), t.Context().Done()))
+	require.Eventually(t, func() bool {
+		return len(solver.challenges.List()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	recorder := record.NewFakeRecorder(10)
+	recorder.IncludeObject = true
+	solver.events = recorder
+
+	// Issued by a ClusterIssuer, so the request has cert-manager's cluster
+	// resource namespace rather than the Challenge's.
+	ch := challenge("_acme-challenge.example.net.", "key", `{"apiToken":"t"}`)
+	ch.ResourceNamespace, ch.UID = "cert-manager", "challenge-uid"
+	require.Error(t, solver.Present(ch))
+
+	require.Len(t, recorder.Events, 2)
+	for _, kind := range []string{"Challenge", "Certificate"} {
+		event := <-recorder.Events
+		assert.True(t, strings.HasPrefix(event, "Warning PresentFailed no G-Core zone found for the record"), event)
+		assert.Contains(t, event, "involvedObject{kind="+kind)
+	}
+
+	// Successful challenges report nothing.
+	ch.ResolvedFQDN = "_acme-challenge.example.com."
+	require.NoError(t, solver.Present(ch))
+	require.NoError(t, solver.CleanUp(ch))
+	assert.Empty(t, recorder.Events)
+}
+
+func TestChallengeObjects(t *testing.T) {
+	t.Parallel()
+
+	solver := &gcoreDNSProviderSolver{certManager: cmfake.NewSimpleClientset(certificateObjects()...),
+		challenges: challengeCache(t, certificateObjects()...)}
+	ch := challenge("_acme-challenge.example.com.", "key", `{}`)
+	ch.ResourceNamespace, ch.UID = "team-a", "challenge-uid"
+	refs, err := solver.challengeObjects(t.Context(), ch)
+	require.NoError(t, err)
+	require.Len(t, refs, 2)
+
+	// A ClusterIssuer's challenge has another ResourceNamespace.
+	ch.ResourceNamespace = "cert-manager"
+	clusterRefs, err := solver.challengeObjects(t.Context(), ch)
+	require.NoError(t, err)
+	assert.Equal(t, refs, clusterRefs)
+	assert.Equal(t, "team-a", refs[1].Namespace)
+	assert.Equal(t, "acme.cert-manager.io/v1", refs[0].APIVersion)
+	assert.Equal(t, "web-1-123-456", refs[0].Name)
+	assert.Equal(t, "Certificate", refs[1].Kind)
+	assert.Equal(t, "web", refs[1].Name)
+	assert.EqualValues(t, "cert-uid", refs[1].UID)
+
+	// A Challenge without the owners cert-manager sets is still reported.
+	solver.certManager = cmfake.NewSimpleClientset(certificateObjects()[3])
+	refs, err = solver.challengeObjects(t.Context(), ch)
+	assert.ErrorContains(t, err, "get order web-1-123")
+	require.Len(t, refs, 1)
+
+	ch.UID = "other-uid"
+	_, err = solver.challengeObjects(t.Context(), ch)
+	assert.EqualError(t, err, "challenge other-uid not found")
+}
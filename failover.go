@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+)
+
+// endpointDownFor is how long an endpoint that could not be reached is only
+// tried after the others.
+const endpointDownFor = 30 * time.Second
+
+// failoverEndpoint is one base URL of the API and its health.
+type failoverEndpoint struct {
+	url       string
+	api       dnsAPI
+	downUntil time.Time
+}
+
+// failoverAPI spreads calls over several endpoints of the same API, the
+// configured endpoint followed by its fallbackEndpoints. Calls go to the
+// first endpoint that is up; one that can't be reached is marked down and
+// the call moves on to the next, so a regional outage does not block
+// challenges. Only unreachable endpoints are failed over from, as a request
+// that may have arrived must not be sent twice.
+type failoverAPI struct {
+	mu        sync.Mutex
+	endpoints []*failoverEndpoint
+	now       func() time.Time
+}
+
+// newFailoverAPI returns a failoverAPI trying the endpoints in order.
+func newFailoverAPI(endpoints []*failoverEndpoint) *failoverAPI {
+	return &failoverAPI{endpoints: endpoints, now: time.Now}
+}
+
+// order returns the endpoints that are up followed by those that are down,
+// each in their configured order.
+func (f *failoverAPI) order() []*failoverEndpoint {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	var up, down []*failoverEndpoint
+	for _, endpoint := range f.endpoints {
+		if now.Before(endpoint.downUntil) {
+			down = append(down, endpoint)
+		} else {
+			up = append(up, endpoint)
+		}
+	}
+	return append(up, down...)
+}
+
+// mark records whether endpoint could be reached.
+func (f *failoverAPI) mark(endpoint *failoverEndpoint, reachable bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if reachable {
+		endpoint.downUntil = time.Time{}
+	} else {
+		endpoint.downUntil = f.now().Add(endpointDownFor)
+	}
+}
+
+// failoverCall runs call on the endpoints in order until one can be reached.
+func failoverCall[T any](f *failoverAPI, call func(dnsAPI) (T, error)) (T, error) {
+	var res T
+	var err error
+	for _, endpoint := range f.order() {
+		res, err = call(endpoint.api)
+		reachable := err == nil || !isUnreachable(err)
+		f.mark(endpoint, reachable)
+		if reachable {
+			break
+		}
+	}
+	return res, err
+}
+
+// do adapts calls returning only an error to failoverCall.
+func (f *failoverAPI) do(call func(dnsAPI) error) error {
+	_, err := failoverCall(f, func(api dnsAPI) (struct{}, error) { return struct{}{}, call(api) })
+	return err
+}
+
+// CloseIdleConnections drops the pooled connections of every endpoint.
+func (f *failoverAPI) CloseIdleConnections() {
+	for _, endpoint := range f.endpoints {
+		if closer, ok := endpoint.api.(idleConnCloser); ok {
+			closer.CloseIdleConnections()
+		}
+	}
+}
+
+func (f *failoverAPI) Zone(ctx context.Context, name string) (dnssdk.Zone, error) {
+	return failoverCall(f, func(api dnsAPI) (dnssdk.Zone, error) { return api.Zone(ctx, name) })
+}
+
+func (f *failoverAPI) ZoneDetails(ctx context.Context, name string) (zoneDetails, error) {
+	return failoverCall(f, func(api dnsAPI) (zoneDetails, error) { return api.ZoneDetails(ctx, name) })
+}
+
+func (f *failoverAPI) ZonesWithParam(ctx context.Context, param dnssdk.ZonesParam) (dnssdk.ListZones, error) {
+	return failoverCall(f, func(api dnsAPI) (dnssdk.ListZones, error) { return api.ZonesWithParam(ctx, param) })
+}
+
+func (f *failoverAPI) ZoneNameservers(ctx context.Context, name string) ([]string, error) {
+	return failoverCall(f, func(api dnsAPI) ([]string, error) { return api.ZoneNameservers(ctx, name) })
+}
+
+func (f *failoverAPI) CreateZone(ctx context.Context, name string) (uint64, error) {
+	return failoverCall(f, func(api dnsAPI) (uint64, error) { return api.CreateZone(ctx, name) })
+}
+
+func (f *failoverAPI) EnableZone(ctx context.Context, name string) error {
+	return f.do(func(api dnsAPI) error { return api.EnableZone(ctx, name) })
+}
+
+func (f *failoverAPI) RRSet(ctx context.Context, zone, name, recordType string) (dnssdk.RRSet, error) {
+	return failoverCall(f, func(api dnsAPI) (dnssdk.RRSet, error) { return api.RRSet(ctx, zone, name, recordType) })
+}
+
+func (f *failoverAPI) RRSetWithVersion(ctx context.Context, zone, name, recordType string) (dnssdk.RRSet, rrsetVersion, error) {
+	var version rrsetVersion
+	rrset, err := failoverCall(f, func(api dnsAPI) (dnssdk.RRSet, error) {
+		var rrset dnssdk.RRSet
+		var err error
+		rrset, version, err = api.RRSetWithVersion(ctx, zone, name, recordType)
+		return rrset, err
+	})
+	return rrset, version, err
+}
+
+func (f *failoverAPI) CreateRRSet(ctx context.Context, zone, name, recordType string, record dnssdk.RRSet) error {
+	return f.do(func(api dnsAPI) error { return api.CreateRRSet(ctx, zone, name, recordType, record) })
+}
+
+func (f *failoverAPI) UpdateRRSet(ctx context.Context, zone, name, recordType string, record dnssdk.RRSet) error {
+	return f.do(func(api dnsAPI) error { return api.UpdateRRSet(ctx, zone, name, recordType, record) })
+}
+
+func (f *failoverAPI) UpdateRRSetIfMatch(ctx context.Context, zone, name, recordType string,
+	record dnssdk.RRSet, version rrsetVersion) error {
+	return f.do(func(api dnsAPI) error { return api.UpdateRRSetIfMatch(ctx, zone, name, recordType, record, version) })
+}
+
+func (f *failoverAPI) DeleteRRSet(ctx context.Context, zone, name, recordType string) error {
+	return f.do(func(api dnsAPI) error { return api.DeleteRRSet(ctx, zone, name, recordType) })
+}
+
+func (f *failoverAPI) DeleteRRSetIfMatch(ctx context.Context, zone, name, recordType string, version rrsetVersion) error {
+	return f.do(func(api dnsAPI) error { return api.DeleteRRSetIfMatch(ctx, zone, name, recordType, version) })
+}
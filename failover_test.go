@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailoverAPI(t *testing.T) {
+	primary := &flakyAPI{mockSDK: newMockSDK("example.com"), failures: 2, err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}
+	fallback := newMockSDK("example.com")
+	api := newFailoverAPI([]*failoverEndpoint{{url: "primary", api: primary}, {url: "fallback", api: fallback}})
+	now := time.Now()
+	api.now = func() time.Time { return now }
+
+	// The unreachable primary is marked down and the call moves on.
+	_, err := api.Zone(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 1, primary.failures)
+	assert.Equal(t, 1, fallback.zoneLookups)
+	assert.Equal(t, "fallback", api.order()[0].url)
+
+	// While it is down the primary is not tried first.
+	_, err = api.Zone(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 1, primary.failures)
+	assert.Equal(t, 2, fallback.zoneLookups)
+
+	// Once it is due again it is tried first, and stays down when it still
+	// can't be reached.
+	now = now.Add(endpointDownFor)
+	_, err = api.Zone(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Zero(t, primary.failures)
+	assert.Equal(t, 3, fallback.zoneLookups)
+
+	now = now.Add(endpointDownFor)
+	_, err = api.Zone(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 1, primary.zoneLookups)
+	assert.Equal(t, "primary", api.order()[0].url)
+
+	// Answers of the API are not failed over from.
+	_, err = api.Zone(context.Background(), "example.org")
+	assert.True(t, isNotFound(err))
+	assert.Equal(t, 3, fallback.zoneLookups)
+}
+
+func TestFailoverAPIAllDown(t *testing.T) {
+	unreachable := &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+	first := &flakyAPI{mockSDK: newMockSDK("example.com"), failures: 5, err: unreachable}
+	second := &flakyAPI{mockSDK: newMockSDK("example.com"), failures: 5, err: unreachable}
+	api := newFailoverAPI([]*failoverEndpoint{{url: "first", api: first}, {url: "second", api: second}})
+
+	_, err := api.Zone(context.Background(), "example.com")
+	assert.ErrorIs(t, err, syscall.ECONNREFUSED)
+	assert.Equal(t, 4, first.failures)
+	assert.Equal(t, 4, second.failures)
+
+	// Endpoints that are all down are still tried, in order.
+	_, err = api.Zone(context.Background(), "example.com")
+	assert.Error(t, err)
+	assert.Equal(t, 3, first.failures)
+
+	api.CloseIdleConnections()
+	assert.Equal(t, 1, first.closed)
+	assert.Equal(t, 1, second.closed)
+}
+
+func TestNewSDKClientFallbackEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/zones/example.com", r.URL.Path)
+		_, _ = w.Write([]byte(`{"name":"example.com"}`))
+	}))
+	defer server.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	cfg := gcoreDNSProviderConfig{ApiUrl: down.URL, FallbackEndpoints: []string{server.URL}}
+	sdk, err := newSDKClient(cfg, "secret")
+	require.NoError(t, err)
+	require.IsType(t, &failoverAPI{}, sdk)
+	zone, err := sdk.Zone(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, dnssdk.Zone{Name: "example.com"}, zone)
+
+	assert.NotEqual(t, sdkClientKey(cfg, "secret"), sdkClientKey(gcoreDNSProviderConfig{ApiUrl: down.URL}, "secret"))
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+)
+
+// defaultStaleRecordMaxAge is the age after which the stale record collector
+// removes challenge records, far beyond any challenge's lifetime.
+const defaultStaleRecordMaxAge = 24 * time.Hour
+
+// challengeLabel is the first label of ACME challenge record names.
+const challengeLabel = "_acme-challenge"
+
+// runStaleRecordGC removes leftover challenge records every
+// staleGCInterval until stopCh is closed. Records are left behind when
+// CleanUp never ran, e.g. because the webhook crashed or the Challenge was
+// deleted. Only records the webhook created, which carry its ownership note,
+// are removed, from the zones of the GCORE_API_TOKEN account the zone policy
+// permits.
+func (c *gcoreDNSProviderSolver) runStaleRecordGC(stopCh <-chan struct{}) error {
+	sdk, cfg, err := c.ambientAPI(staleGCIntervalEnvVar)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer cancel()
+		for {
+			if sleepContext(ctx, c.staleGCInterval) != nil {
+				return
+			}
+			removed, err := c.collectStaleRecords(ctx, sdk, cfg, time.Now().Add(-c.staleMaxAge))
+			if err != nil {
+				c.log.Error(err, "stale challenge record collection failed", "removed", removed)
+				continue
+			}
+			c.log.V(1).Info("collected stale challenge records", "removed", removed)
+		}
+	}()
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+	return nil
+}
+
+// collectStaleRecords removes the challenge records the webhook created
+// before cutoff and returns how many it removed. A failing zone does not
+// stop the others from being collected.
+func (c *gcoreDNSProviderSolver) collectStaleRecords(ctx context.Context, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig, cutoff time.Time) (int, error) {
+	var zones []string
+	err := eachZone(ctx, sdk, dnssdk.ZonesParam{}, func(zone dnssdk.Zone) bool {
+		name := strings.Trim(zone.Name, ".")
+		if c.zonePolicy.check(name, allowedZonesEnvVar, deniedZonesEnvVar) == nil {
+			zones = append(zones, name)
+		}
+		return true
+	})
+	if err != nil {
+		return 0, fmt.Errorf("list zones: %w", err)
+	}
+	removed := 0
+	var errs []error
+	for _, zone := range zones {
+		z, err := sdk.Zone(ctx, zone)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("get zone %s: %w", zone, err))
+			continue
+		}
+		for _, record := range z.Records {
+			name := strings.Trim(record.Name, ".")
+			label, _, _ := strings.Cut(name, ".")
+			if !strings.EqualFold(record.Type, txtType) || !strings.EqualFold(label, challengeLabel) {
+				continue
+			}
+			n, err := c.collectStaleRRSet(ctx, sdk, cfg, zone, name, cutoff)
+			removed += n
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+// collectStaleRRSet removes the records of one challenge RRSet the webhook
+// created before cutoff. The RRSet is deleted once none are left.
+func (c *gcoreDNSProviderSolver) collectStaleRRSet(ctx context.Context, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig, zone, fqdn string, cutoff time.Time) (int, error) {
+	unlock, err := c.recordLocks.lock(ctx, recordLockKey(cfg, fqdn))
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	rrset, version, err := sdk.RRSetWithVersion(ctx, zone, fqdn, txtType)
+	if isNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("fetch rrset %s: %w", fqdn, err)
+	}
+	var remaining []dnssdk.ResourceRecord
+	for _, record := range rrset.Records {
+		if since, ok := ownedSince(record); !ok || !since.Before(cutoff) {
+			remaining = append(remaining, record)
+		}
+	}
+	removed := len(rrset.Records) - len(remaining)
+	if removed == 0 {
+		return 0, nil
+	}
+	if len(remaining) == 0 {
+		err = sdk.DeleteRRSetIfMatch(ctx, zone, fqdn, txtType, version)
+	} else {
+		rrset.Records = remaining
+		err = sdk.UpdateRRSetIfMatch(ctx, zone, fqdn, txtType, rrset, version)
+	}
+	if isNotFound(err) || isPreconditionFailed(err) {
+		// Another replica's collector or a challenge changed the RRSet since
+		// it was read; the next run looks at it again.
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("remove stale records of %s: %w", fqdn, err)
+	}
+	c.log.Info("removed stale challenge records", "zone", zone, "fqdn", fqdn, "removed", removed)
+	return removed, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectStaleRecords(t *testing.T) {
+	now := time.Now()
+	old, fresh := now.Add(-48*time.Hour), now.Add(-time.Minute)
+	record := func(key string, created time.Time) dnssdk.ResourceRecord {
+		return markOwned(dnssdk.ResourceRecord{Content: []any{key}, Enabled: true}, key, created)
+	}
+	mock := newMockSDK("example.com", "example.net")
+	seed := func(zone, name string, records ...dnssdk.ResourceRecord) {
+		require.NoError(t, mock.UpdateRRSet(context.Background(), zone, name, txtType,
+			dnssdk.RRSet{TTL: 120, Records: records}))
+	}
+	seed("example.com", "_acme-challenge.example.com", record("token-old", old), record("token-new", fresh),
+		dnssdk.ResourceRecord{Content: []any{"by-hand"}, Enabled: true})
+	seed("example.com", "_acme-challenge.www.example.com", record("token-old", old))
+	seed("example.com", "example.com", record("token-old", old))
+	seed("example.net", "_acme-challenge.example.net", record("token-old", old))
+
+	solver := solverWithMock(mock)
+	solver.zonePolicy = zonePolicy{denied: []string{"example.net"}}
+	removed, err := solver.collectStaleRecords(context.Background(), mock, gcoreDNSProviderConfig{},
+		now.Add(-defaultStaleRecordMaxAge))
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	assert.ElementsMatch(t, []string{"token-new", "by-hand"}, mock.contents("example.com", "_acme-challenge.example.com"))
+	assert.Nil(t, mock.contents("example.com", "_acme-challenge.www.example.com"))
+	assert.Equal(t, []string{"token-old"}, mock.contents("example.com", "example.com"),
+		"only challenge records are collected")
+	assert.Equal(t, []string{"token-old"}, mock.contents("example.net", "_acme-challenge.example.net"),
+		"zones the policy denies are left alone")
+	assert.Zero(t, solver.recordLocks.size())
+
+	removed, err = solver.collectStaleRecords(context.Background(), mock, gcoreDNSProviderConfig{},
+		now.Add(-defaultStaleRecordMaxAge))
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+}
+
+func TestRunStaleRecordGC(t *testing.T) {
+	t.Setenv(apiTokenEnvVar, "")
+	solver := &gcoreDNSProviderSolver{staleGCInterval: time.Hour}
+	assert.EqualError(t, solver.runStaleRecordGC(nil),
+		"GCORE_STALE_RECORD_GC_INTERVAL is set but GCORE_API_TOKEN is not")
+
+	t.Setenv(apiTokenEnvVar, "token")
+	mock := newMockSDK("example.com")
+	require.NoError(t, mock.UpdateRRSet(context.Background(), "example.com", "_acme-challenge.example.com", txtType,
+		dnssdk.RRSet{TTL: 120, Records: []dnssdk.ResourceRecord{
+			markOwned(dnssdk.ResourceRecord{Content: []any{"token-old"}}, "token-old", time.Now().Add(-48*time.Hour)),
+		}}))
+	solver = solverWithMock(mock)
+	solver.staleGCInterval, solver.staleMaxAge = time.Millisecond, defaultStaleRecordMaxAge
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	require.NoError(t, solver.runStaleRecordGC(stopCh))
+	assert.Eventually(t, func() bool {
+		return mock.contents("example.com", "_acme-challenge.example.com") == nil
+	}, time.Second, time.Millisecond)
+}
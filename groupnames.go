@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apiserver"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/cmd/server"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/registry/challengepayload"
+	logf "github.com/cert-manager/cert-manager/pkg/logs"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/registry/rest"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/component-base/logs"
+)
+
+// groupNameFlag adds an API group the solver is served under. It may be
+// repeated, e.g. to keep serving a legacy group name while issuers move to
+// a new one.
+const groupNameFlag = "--group-name"
+
+// groupNames returns the API groups to serve, those of GROUP_NAME followed by
+// those of the --group-name flags, and args without the --group-name flags.
+func groupNames(env string, args []string) ([]string, []string, error) {
+	values, rest, err := cutFlag(args, groupNameFlag)
+	if err != nil {
+		return nil, nil, err
+	}
+	var groups []string
+	for _, group := range append([]string{env}, values...) {
+		if group != "" && !slices.Contains(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+	if len(groups) == 0 {
+		return nil, nil, fmt.Errorf("%s or %s must be specified", groupNameEnvVar, groupNameFlag)
+	}
+	return groups, rest, nil
+}
+
+// cutFlag returns the values of the flag in args, given as "flag value" or
+// "flag=value", and args without them. The webhook library parses os.Args
+// itself and rejects flags it does not know.
+func cutFlag(args []string, flag string) ([]string, []string, error) {
+	var values, rest []string
+	for i := 0; i < len(args); i++ {
+		value, isFlag := strings.CutPrefix(args[i], flag+"=")
+		if args[i] == flag {
+			isFlag, value = true, ""
+			if i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+		}
+		if !isFlag {
+			rest = append(rest, args[i])
+			continue
+		}
+		if value == "" {
+			return nil, nil, fmt.Errorf("%s needs a value", flag)
+		}
+		values = append(values, value)
+	}
+	return values, rest, nil
+}
+
+// cutBoolFlag reports whether args set the boolean flag, given bare or as
+// "flag=true|false" with the last one winning, and returns args without it.
+func cutBoolFlag(args []string, flag string) (bool, []string, error) {
+	var enabled bool
+	var rest []string
+	for _, arg := range args {
+		value, isFlag := strings.CutPrefix(arg, flag+"=")
+		if arg == flag {
+			isFlag, value = true, "true"
+		}
+		if !isFlag {
+			rest = append(rest, arg)
+			continue
+		}
+		var err error
+		if enabled, err = strconv.ParseBool(value); err != nil {
+			return false, nil, fmt.Errorf("%s must be true or false, got %q", flag, value)
+		}
+	}
+	return enabled, rest, nil
+}
+
+// runMultiGroupWebhookServer serves the solver under every group like
+// cmd.RunWebhookServer does for a single one, with the solver's readiness
+// check, if any, added to /readyz.
+func runMultiGroupWebhookServer(groups []string, solver webhook.Solver) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	logs.InitLogs()
+	defer logs.FlushLogs()
+	ctx = logf.NewContext(ctx, logf.Log, "acme-dns-webhook")
+
+	o := server.NewWebhookServerOptions(groups[0], solver)
+	cmd := &cobra.Command{
+		Short: "Launch an ACME solver API server",
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Validate(args); err != nil {
+				return err
+			}
+			return runGroups(c.Context(), o, groups, solver)
+		},
+	}
+	logf.AddFlags(o.Logging, cmd.Flags())
+	o.RecommendedOptions.AddFlags(cmd.Flags())
+	if err := cmd.ExecuteContext(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		logf.Log.Error(err, "error executing command")
+		logs.FlushLogs()
+		os.Exit(1)
+	}
+}
+
+// runGroups installs an API group for each of groups and runs the server
+// until ctx is done.
+func runGroups(ctx context.Context, o *server.WebhookServerOptions, groups []string, solver webhook.Solver) error {
+	config, err := o.Config()
+	if err != nil {
+		return err
+	}
+	completed := config.Complete()
+	genericServer, err := completed.GenericConfig.New("challenge-server", genericapiserver.NewEmptyDelegate())
+	if err != nil {
+		return err
+	}
+	if err := genericServer.InstallAPIGroups(groupInfos(groups, solver)...); err != nil {
+		return fmt.Errorf("error installing APIGroups for solvers: %w", err)
+	}
+	if s, ok := solver.(*gcoreDNSProviderSolver); ok && s.readiness != nil {
+		if err := genericServer.AddReadyzChecks(s.readiness); err != nil {
+			return fmt.Errorf("add readiness check: %w", err)
+		}
+	}
+	genericServer.AddPostStartHookOrDie("solver-"+solver.Name()+"-init",
+		func(hookCtx genericapiserver.PostStartHookContext) error {
+			return solver.Initialize(config.GenericConfig.ClientConfig, hookCtx.Done())
+		})
+	return genericServer.PrepareRun().RunWithContext(ctx)
+}
+
+// groupInfos returns an API group serving the solver for each of groups.
+func groupInfos(groups []string, solver webhook.Solver) []*genericapiserver.APIGroupInfo {
+	handler := challengepayload.NewREST(solver)
+	infos := make([]*genericapiserver.APIGroupInfo, 0, len(groups))
+	for _, group := range groups {
+		infos = append(infos, &genericapiserver.APIGroupInfo{
+			PrioritizedVersions: []schema.GroupVersion{{Group: group, Version: "v1alpha1"}},
+			VersionedResourcesStorageMap: map[string]map[string]rest.Storage{
+				"v1alpha1": {solver.Name(): handler},
+			},
+			OptionsExternalVersion: &schema.GroupVersion{Version: "v1"},
+			Scheme:                 apiserver.Scheme,
+			ParameterCodec:         metav1.ParameterCodec,
+			NegotiatedSerializer:   apiserver.Codecs,
+		})
+	}
+	return infos
+}
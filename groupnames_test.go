@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupNames(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		env    string
+		args   []string
+		groups []string
+		rest   []string
+		errMsg string
+	}{
+		{desc: "env only", env: "acme.gcore.com", args: []string{"--secure-port=443"},
+			groups: []string{"acme.gcore.com"}, rest: []string{"--secure-port=443"}},
+		{desc: "env and flags", env: "acme.gcore.com",
+			args:   []string{"--group-name", "acme.legacy.com", "--v=2", "--group-name=acme.old.com"},
+			groups: []string{"acme.gcore.com", "acme.legacy.com", "acme.old.com"}, rest: []string{"--v=2"}},
+		{desc: "flags only", args: []string{"--group-name=acme.gcore.com"}, groups: []string{"acme.gcore.com"}},
+		{desc: "duplicates", env: "acme.gcore.com", args: []string{"--group-name=acme.gcore.com"},
+			groups: []string{"acme.gcore.com"}},
+		{desc: "missing value", env: "acme.gcore.com", args: []string{"--group-name"},
+			errMsg: "--group-name needs a value"},
+		{desc: "empty value", args: []string{"--group-name="}, errMsg: "--group-name needs a value"},
+		{desc: "none", args: []string{"--v=2"}, errMsg: "GROUP_NAME or --group-name must be specified"},
+	}
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			groups, rest, err := groupNames(test.env, test.args)
+			if test.errMsg != "" {
+				assert.EqualError(t, err, test.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.groups, groups)
+			assert.Equal(t, test.rest, rest)
+		})
+	}
+}
+
+func TestGroupInfos(t *testing.T) {
+	infos := groupInfos([]string{"acme.gcore.com", "acme.legacy.com"}, &gcoreDNSProviderSolver{})
+	require.Len(t, infos, 2)
+	for i, group := range []string{"acme.gcore.com", "acme.legacy.com"} {
+		assert.Equal(t, group, infos[i].PrioritizedVersions[0].Group)
+		assert.Equal(t, "v1alpha1", infos[i].PrioritizedVersions[0].Version)
+		assert.Contains(t, infos[i].VersionedResourcesStorageMap["v1alpha1"], providerName)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// idnaProfile maps internationalized names the way resolvers do, but allows
+// the underscores of challenge names like _acme-challenge.
+var idnaProfile = idna.New(idna.MapForLookup(), idna.StrictDomainName(false), idna.Transitional(false))
+
+// asciiDomain returns name the way the G-Core API and DNS know it: punycode,
+// lower case and without the trailing dot, e.g. xn--bcher-kva.example for
+// Bücher.example. A name that is no valid IDN is only lowercased, leaving it
+// to the API to reject.
+func asciiDomain(name string) string {
+	name = strings.Trim(name, ".")
+	if ascii, err := idnaProfile.ToASCII(name); err == nil {
+		return ascii
+	}
+	return strings.ToLower(name)
+}
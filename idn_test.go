@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestASCIIDomain(t *testing.T) {
+	testCases := []struct {
+		name string
+		want string
+	}{
+		{name: "_acme-challenge.example.com.", want: "_acme-challenge.example.com"},
+		{name: "_ACME-Challenge.Example.COM", want: "_acme-challenge.example.com"},
+		{name: "_acme-challenge.Bücher.example.", want: "_acme-challenge.xn--bcher-kva.example"},
+		{name: "_acme-challenge.xn--bcher-kva.example", want: "_acme-challenge.xn--bcher-kva.example"},
+		{name: "_acme-challenge.例え.テスト", want: "_acme-challenge.xn--r8jz45g.xn--zckzah"},
+		{name: "_acme-challenge.ＥＸＡＭＰＬＥ.com", want: "_acme-challenge.example.com"},
+		{name: "-Invalid-.example.com", want: "-invalid-.example.com"},
+	}
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.want, asciiDomain(test.name))
+		})
+	}
+}
+
+func TestPresentIDN(t *testing.T) {
+	mock := newMockSDK("xn--bcher-kva.example")
+	solver := solverWithMock(mock)
+	cfg := `{"apiToken":"t","allowedZones":["bücher.example"],"zoneTTLOverrides":{"Bücher.example":900}}`
+	ch := challenge("_acme-challenge.Bücher.example.", "key", cfg)
+
+	require.NoError(t, solver.Present(ch))
+	assert.Equal(t, []string{"key"}, mock.contents("xn--bcher-kva.example", "_acme-challenge.xn--bcher-kva.example"))
+	assert.Equal(t, 900, mock.zones["xn--bcher-kva.example"].rrsets["_acme-challenge.xn--bcher-kva.example"][txtType].ttl)
+
+	require.NoError(t, solver.CleanUp(ch))
+	assert.Empty(t, mock.contents("xn--bcher-kva.example", "_acme-challenge.xn--bcher-kva.example"))
+
+	assert.True(t, inZone("_acme-challenge.xn--bcher-kva.example", "Bücher.example"))
+	assert.False(t, inZone("_acme-challenge.buecher.example", "Bücher.example"))
+}
@@ -0,0 +1,31 @@
+package main
+
+import "slices"
+
+const (
+	// kubeconfigFlag runs the webhook outside of the cluster, e.g. on a
+	// developer machine, with the kubeconfig file it names. The webhook
+	// library only reads the Secrets and Challenges with it and still looks
+	// for the in-cluster config to delegate authentication and authorization
+	// to the API server.
+	kubeconfigFlag               = "--kubeconfig"
+	authenticationKubeconfigFlag = "--authentication-kubeconfig"
+	authorizationKubeconfigFlag  = "--authorization-kubeconfig"
+)
+
+// kubeconfig adds the kubeconfig file of the last --kubeconfig flag in args
+// as the --authentication-kubeconfig and --authorization-kubeconfig flags,
+// unless they are set.
+func kubeconfig(args []string) ([]string, error) {
+	values, _, err := cutFlag(args, kubeconfigFlag)
+	if err != nil || len(values) == 0 {
+		return args, err
+	}
+	path := values[len(values)-1]
+	for _, flag := range []string{authenticationKubeconfigFlag, authorizationKubeconfigFlag} {
+		if !slices.ContainsFunc(args, func(arg string) bool { return isFlag(arg, flag) }) {
+			args = append(args, flag+"="+path)
+		}
+	}
+	return args, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/cmd/server"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKubeconfig(t *testing.T) {
+	t.Parallel()
+
+	args, err := kubeconfig([]string{"--secure-port=8443", "--kubeconfig", "/old", "--kubeconfig=/home/dev/.kube/config"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--secure-port=8443", "--kubeconfig", "/old", "--kubeconfig=/home/dev/.kube/config",
+		"--authentication-kubeconfig=/home/dev/.kube/config", "--authorization-kubeconfig=/home/dev/.kube/config"}, args)
+
+	args, err = kubeconfig([]string{"--kubeconfig=/dev.yaml", "--authorization-kubeconfig", "/authz.yaml"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--kubeconfig=/dev.yaml", "--authorization-kubeconfig", "/authz.yaml",
+		"--authentication-kubeconfig=/dev.yaml"}, args)
+
+	args, err = kubeconfig([]string{"--secure-port=443"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--secure-port=443"}, args)
+
+	_, err = kubeconfig([]string{"--kubeconfig"})
+	assert.ErrorContains(t, err, "needs a value")
+}
+
+// TestKubeconfigFlags checks that the webhook library reads every kubeconfig
+// it talks to the API server with from the added flags.
+func TestKubeconfigFlags(t *testing.T) {
+	t.Parallel()
+
+	args, err := kubeconfig([]string{"--kubeconfig=/dev.yaml"})
+	require.NoError(t, err)
+	o := server.NewWebhookServerOptions("acme.example.com", solverWithMock(newMockSDK("example.com")))
+	flags := pflag.NewFlagSet("webhook", pflag.ContinueOnError)
+	o.RecommendedOptions.AddFlags(flags)
+	require.NoError(t, flags.Parse(args))
+	assert.Equal(t, "/dev.yaml", o.RecommendedOptions.CoreAPI.CoreAPIKubeconfigPath)
+	assert.Equal(t, "/dev.yaml", o.RecommendedOptions.Authentication.RemoteKubeConfigFile)
+	assert.Equal(t, "/dev.yaml", o.RecommendedOptions.Authorization.RemoteKubeConfigFile)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// logFormatFlag selects text or JSON log lines. It is a shorthand of the
+	// --logging-format flag of the webhook library, whose log levels are set
+	// with -v.
+	logFormatFlag     = "--log-format"
+	loggingFormatFlag = "--logging-format"
+
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// logFormat replaces the --log-format flags in args with the --logging-format
+// flag the webhook library understands. The last one wins.
+func logFormat(args []string) ([]string, error) {
+	values, rest, err := cutFlag(args, logFormatFlag)
+	if err != nil || len(values) == 0 {
+		return rest, err
+	}
+	format := values[len(values)-1]
+	if format != logFormatText && format != logFormatJSON {
+		return nil, fmt.Errorf("%s must be %q or %q, got %q", logFormatFlag, logFormatText, logFormatJSON, format)
+	}
+	return append(rest, loggingFormatFlag+"="+format), nil
+}
+
+// challengeLog returns the logger of ch, adding the fields identifying the
+// challenge to every line.
+func (c *gcoreDNSProviderSolver) challengeLog(ch *v1alpha1.ChallengeRequest) klog.Logger {
+	return c.log.WithValues("fqdn", ch.ResolvedFQDN, "resolvedZone", ch.ResolvedZone, "uid", string(ch.UID))
+}
+
+// challengeContext returns a context for a Present or CleanUp call of ch. It
+// carries the UID of ch and a new request ID, sent with every API request,
+// and the logger of ch, adding the request ID to the lines logged while
+// solving it.
+func (c *gcoreDNSProviderSolver) challengeContext(ch *v1alpha1.ChallengeRequest) context.Context {
+	ids := challengeIDs{uid: string(ch.UID), requestID: newRequestID()}
+	log := c.challengeLog(ch).WithValues("requestID", ids.requestID)
+	return logr.NewContext(withChallengeIDs(context.Background(), ids), log)
+}
+
+// logger returns the logger of the challenge ctx was made for, c.log outside
+// of challenges.
+func (c *gcoreDNSProviderSolver) logger(ctx context.Context) klog.Logger {
+	if log, err := logr.FromContext(ctx); err == nil {
+		return log
+	}
+	return c.log
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogFormat(t *testing.T) {
+	t.Parallel()
+
+	args, err := logFormat([]string{"--secure-port=443", "--log-format", "text", "--log-format=json"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--secure-port=443", "--logging-format=json"}, args)
+
+	args, err = logFormat([]string{"--v=2"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--v=2"}, args)
+
+	_, err = logFormat([]string{"--log-format=yaml"})
+	assert.ErrorContains(t, err, `--log-format must be "text" or "json", got "yaml"`)
+	_, err = logFormat([]string{"--log-format"})
+	assert.ErrorContains(t, err, "needs a value")
+}
+
+func TestChallengeLogFields(t *testing.T) {
+	var lines []string
+	mock := newMockSDK("example.com")
+	solver := solverWithMock(mock)
+	solver.log = funcr.NewJSON(func(obj string) { lines = append(lines, obj) }, funcr.Options{Verbosity: 1})
+
+	ch := challenge("_acme-challenge.example.com.", "key", `{"apiToken":"t","zoneDiscovery":"probe"}`)
+	ch.ResolvedZone = "example.com."
+	ch.UID = "4b1c0c41-5d0e-4f53-9f4e-2c1d0a6f2d11"
+	require.NoError(t, solver.Present(ch))
+	require.NoError(t, solver.Present(ch))
+	require.NoError(t, solver.CleanUp(ch))
+	require.GreaterOrEqual(t, len(lines), 3)
+	for _, line := range lines {
+		assert.True(t, strings.HasPrefix(line, "{"), line)
+		assert.Contains(t, line, `"fqdn":"_acme-challenge.example.com."`)
+		assert.Contains(t, line, `"resolvedZone":"example.com."`)
+		assert.Contains(t, line, `"uid":"4b1c0c41-5d0e-4f53-9f4e-2c1d0a6f2d11"`)
+	}
+
+	// Outside of a challenge the solver's own logger is used.
+	assert.Equal(t, solver.log, solver.logger(context.Background()))
+	assert.NotEqual(t, solver.log, solver.logger(solver.challengeContext(&v1alpha1.ChallengeRequest{})))
+}
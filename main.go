@@ -2,45 +2,204 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
 	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
 	"github.com/cert-manager/cert-manager/pkg/acme/webhook/cmd"
-	certmgrv1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+	"golang.org/x/sync/semaphore"
 
-	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
 )
 
 const (
 	providerName    = "gcore"
 	groupNameEnvVar = "GROUP_NAME"
+	apiTokenEnvVar  = "GCORE_API_TOKEN"
 	txtType         = "TXT"
 )
 
 func main() {
+	if slices.Contains(os.Args[1:], printConfigSchemaFlag) {
+		schema, err := configSchema()
+		if err != nil {
+			panic(err.Error())
+		}
+		_, _ = os.Stdout.Write(schema)
+		return
+	}
+
+	if isChallengeCommand(os.Args[1:]) {
+		os.Exit(runChallengeCommand(os.Args[1:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == checkCommand {
+		os.Exit(runCheckCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
 
-	groupName := os.Getenv(groupNameEnvVar)
-	if groupName == "" {
-		panic(fmt.Sprintf("%s must be specified", groupNameEnvVar))
+	groups, args, err := groupNames(os.Getenv(groupNameEnvVar), os.Args[1:])
+	if err != nil {
+		panic(err.Error())
+	}
+	// The webhook library parses os.Args itself and does not know the
+	// --group-name, --dns-resolvers, --max-concurrent-api-calls,
+	// --log-format, --tls-cert-dir, --debug-http, --enable-pprof and --dry-run
+	// flags.
+	resolvers, args, err := dnsResolvers(args)
+	if err != nil {
+		panic(err.Error())
+	}
+	apiCalls, args, err := maxConcurrentAPICalls(args)
+	if err != nil {
+		panic(err.Error())
+	}
+	args, err = logFormat(args)
+	if err != nil {
+		panic(err.Error())
+	}
+	args, err = tlsCertDir(args)
+	if err != nil {
+		panic(err.Error())
+	}
+	dumpHTTP, args, err := debugHTTP(args)
+	if err != nil {
+		panic(err.Error())
+	}
+	pprof, args, err := cutBoolFlag(args, enablePprofFlag)
+	if err != nil {
+		panic(err.Error())
 	}
+	dryRun, args, err := cutBoolFlag(args, dryRunFlag)
+	if err != nil {
+		panic(err.Error())
+	}
+	args, err = kubeconfig(args)
+	if err != nil {
+		panic(err.Error())
+	}
+	os.Args = append(os.Args[:1], args...)
 
 	// This will register our custom DNS provider with the webhook serving
-	// library, making it available as an API under the provided groupName.
+	// library, making it available as an API under each of the groups.
 	// You can register multiple DNS provider implementations with a single
 	// webhook, where the Name() method will be used to disambiguate between
 	// the different implementations.
-	cmd.RunWebhookServer(groupName,
-		&gcoreDNSProviderSolver{},
-	)
+	solver, err := newSolver(resolvers, apiCalls, dumpHTTP, pprof)
+	if err != nil {
+		panic(err.Error())
+	}
+	solver.dryRun = dryRun
+	solver.metrics.observeCaches(solver.cacheStats)
+	solver.readiness, err = readinessCheckFromEnv(solver)
+	if err != nil {
+		panic(err.Error())
+	}
+	// The webhook library's server takes no extra readiness checks.
+	if len(groups) > 1 || solver.readiness != nil {
+		runMultiGroupWebhookServer(groups, solver)
+	} else {
+		cmd.RunWebhookServer(groups[0], solver)
+	}
+	// The server stops before the challenges it started are done.
+	solver.inflight.wait()
+}
+
+// newSolver returns the solver with the settings of the environment, the
+// --dns-resolvers, --max-concurrent-api-calls, --debug-http and
+// --enable-pprof flags.
+func newSolver(resolvers []string, apiCalls *semaphore.Weighted, dumpHTTP, pprof bool) (*gcoreDNSProviderSolver,
+	error) {
+	cacheTTL, cacheMaxEntries, err := cacheSettingsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	negativeCacheTTL, err := negativeCacheTTLFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	adminAddr, lastErrorsSize, err := adminSettingsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if pprof && adminAddr == "" {
+		return nil, fmt.Errorf("%s needs %s", enablePprofFlag, adminAddrEnvVar)
+	}
+	staleGCInterval, staleMaxAge, err := staleGCSettingsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	breakerThreshold, breakerCooldown, err := breakerSettingsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	tracing, err := setupTracing(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	audit, err := auditLogFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	drainTimeout, err := drainTimeoutFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return &gcoreDNSProviderSolver{
+		zones:        newCache[zoneCacheKey, zoneDetails](cacheTTL, cacheMaxEntries),
+		missingZones: newCache[zoneCacheKey, error](negativeCacheTTL, cacheMaxEntries),
+		nameservers:  newCache[nsCacheKey, []string](cacheTTL, cacheMaxEntries),
+		writeScope:   newCache[scopeCacheKey, struct{}](cacheTTL, cacheMaxEntries),
+		unfiltered:   newCache[string, struct{}](cacheTTL, cacheMaxEntries),
+		bearerTokens: newCache[string, *bearerToken](bearerTokensTTL, cacheMaxEntries),
+		sdkClients:   newCache[string, dnsAPI](sdkClientsTTL, cacheMaxEntries),
+		failures:     newFailureLog(lastErrorsSize),
+		metrics:      newWebhookMetrics(),
+		tracing:      tracing,
+		audit:        audit,
+		drainTimeout: drainTimeout,
+		log:          redactingLogger(klog.Background()),
+		adminAddr:    adminAddr,
+		pprof:        pprof,
+		dnsResolvers: resolvers,
+		apiCalls:     apiCalls,
+		debugHTTP:    dumpHTTP,
+		breakers:     circuitBreakers{threshold: breakerThreshold, cooldown: breakerCooldown},
+
+		allowCrossNamespaceSecrets: os.Getenv(crossNamespaceEnvVar) == "true",
+		ambientNamespaces:          ambientNamespacesFromEnv(),
+		startupCheck:               os.Getenv(startupCheckEnvVar) == "true",
+		startupCheckZone:           os.Getenv(startupCheckZoneEnvVar),
+		staleGCInterval:            staleGCInterval,
+		staleMaxAge:                staleMaxAge,
+		tokenFileDir:               os.Getenv(tokenDirEnvVar),
+		zonePolicy:                 zonePolicyFromEnv(),
+		vaultAddrs:                 vaultAddrsFromEnv(),
+		presentBatches:             presentBatches{window: defaultCoalesceWindow},
+	}, nil
 }
 
 // gcoreDNSProviderSolver implements the provider-specific logic needed to
@@ -48,44 +207,158 @@ func main() {
 // To do so, it must implement the `github.com/cert-manager/cert-manager/pkg/acme/webhook.Solver`
 // interface.
 type gcoreDNSProviderSolver struct {
-	client             *kubernetes.Clientset
-	ttl                int
-	propagationTimeout int
-}
-
-// gcoreDNSProviderConfig is a structure that is used to decode into when
-// solving a DNS01 challenge.
-// This information is provided by cert-manager, and may be a reference to
-// additional configuration that's needed to solve the challenge for this
-// particular certificate or issuer.
-// This typically includes references to Secret resources containing DNS
-// provider credentials, in cases where a 'multi-tenant' DNS solver is being
-// created.
-// If you do *not* require per-issuer or per-certificate configuration to be
-// provided to your webhook, you can skip decoding altogether in favour of
-// using CLI flags or similar to provide configuration.
-// You should not include sensitive information here. If credentials need to
-// be used by your provider here, you should reference a Kubernetes Secret
-// resource and fetch these credentials using a Kubernetes clientset.
-type gcoreDNSProviderConfig struct {
-	// These fields will be set by users in the
-	// `issuer.spec.acme.dns01.providers.webhook.config` field.
-
-	APIKeySecretRef certmgrv1.SecretKeySelector `json:"apiKeySecretRef"`
-
-	// +optional. Base url for API requests
-	ApiUrl string `json:"apiUrl"`
-	// +optional. Permanent token if you don't want to use a k8s secret
-	ApiToken string `json:"apiToken"`
-
-	// +optional
-	TTL int `json:"ttl"`
-	// +optional
-	Timeout int `json:"timeout"`
-	// +optional
-	PropagationTimeout int `json:"propagationTimeout"`
-	// +optional
-	PollingInterval int `json:"pollingInterval"`
+	client kubernetes.Interface
+	// newSDK builds the G-Core API client for a challenge, defaults to newSDKClient.
+	newSDK func(cfg gcoreDNSProviderConfig, token string) (dnsAPI, error)
+	// lookupNS resolves delegated nameservers, defaults to the system
+	// resolver or the --dns-resolvers.
+	lookupNS func(ctx context.Context, name string) ([]*net.NS, error)
+	// lookupSOA returns the authoritative zone of an FQDN, defaults to
+	// cert-manager's SOA lookup through recursiveNameservers.
+	lookupSOA func(ctx context.Context, fqdn string) (string, error)
+	// lookupCNAME returns the CNAME target of an FQDN, empty when it is no
+	// alias, defaults to lookupCNAMETarget.
+	lookupCNAME func(ctx context.Context, fqdn string) (string, error)
+	// lookupTXT asks a nameserver for the TXT records of an FQDN, defaults
+	// to lookupTXTAt.
+	lookupTXT func(ctx context.Context, nameserver, fqdn string) ([]string, error)
+	// verifyTXTSignature checks at a nameserver that the TXT records of an
+	// FQDN carry a valid RRSIG of their zone, defaults to
+	// verifyTXTSignatureAt.
+	verifyTXTSignature func(ctx context.Context, nameserver, zone, fqdn string) error
+
+	// zones and nameservers cache API lookups across challenges, nil disables caching.
+	zones       *cache[zoneCacheKey, zoneDetails]
+	nameservers *cache[nsCacheKey, []string]
+	// missingZones remembers for a short while the record names no zone
+	// was found for, so Present retries for a misconfigured domain don't
+	// repeat the lookups.
+	missingZones *cache[zoneCacheKey, error]
+	// bearerTokens shares the bearer mode token sources between challenges.
+	bearerTokens *cache[string, *bearerToken]
+	// sdkClients shares API clients and their connections between
+	// challenges, nil builds a client per challenge.
+	sdkClients *cache[string, dnsAPI]
+	bearerMu   sync.Mutex
+	// recordLocks serializes the RRSet writes of concurrent challenges.
+	recordLocks recordLocks
+	// presentBatches coalesces the writes of challenges presented together
+	// for the same RRSet.
+	presentBatches presentBatches
+	// breakers fail calls fast while an API keeps failing.
+	breakers circuitBreakers
+	// writeScope remembers zones the credential proved write access to.
+	writeScope *cache[scopeCacheKey, struct{}]
+	// unfiltered remembers the API endpoints that ignored the zone name
+	// filter, whose zones are probed without asking for the filter first.
+	unfiltered *cache[string, struct{}]
+
+	// failures keeps the most recent Present and CleanUp errors for
+	// /debug/last-errors, nil disables recording.
+	failures *failureLog
+	// log receives one line per successful Present and CleanUp.
+	log klog.Logger
+	// configsSeen holds the namespace and config of the issuers whose
+	// deprecated config fields were logged, so they are logged once.
+	configsSeen sync.Map
+	// tokenFileDir is the directory apiTokenFile paths must be in, empty
+	// disables apiTokenFile. tokenFiles shares the readers of those files.
+	tokenFileDir string
+	tokenFiles   map[string]*tokenFile
+	tokenFilesMu sync.Mutex
+	// ambientNamespaces are the namespaces whose issuers may use ambient
+	// credentials, nil leaves the decision to cert-manager alone.
+	ambientNamespaces []string
+	// allowCrossNamespaceSecrets lets apiTokenSecretRef name a namespace
+	// other than the issuer's.
+	allowCrossNamespaceSecrets bool
+	// vaultAddrs are the Vault servers a tokenSource may read from, empty
+	// disables Vault. vaultSources shares the readers of the token sources.
+	vaultAddrs   []string
+	vaultSources map[tokenSource]*vaultSource
+	vaultMu      sync.Mutex
+	// zonePolicy restricts the zones of every issuer, on top of their own
+	// allowedZones and deniedZones.
+	zonePolicy zonePolicy
+	// adminAddr is the listen address of the debug endpoints, empty disables them.
+	adminAddr string
+	// pprof serves the profiles on the admin server.
+	pprof bool
+	// metrics are served on /metrics of the admin server, nil disables them.
+	metrics *webhookMetrics
+	// audit records the changes made to DNS, nil records none.
+	audit *auditLog
+	// inflight tracks the Present and CleanUp calls shutdown waits for, up
+	// to drainTimeout.
+	inflight     inflightOps
+	drainTimeout time.Duration
+	// readiness adds a G-Core API check to /readyz, nil leaves it out.
+	readiness *apiReadiness
+	// events reports failed challenges as Events on their Challenge and
+	// Certificate, found with certManager from the Challenges cached in
+	// challenges; nil until Initialize.
+	events      record.EventRecorder
+	certManager cmclient.Interface
+	challenges  toolscache.Indexer
+	// tracing exports the spans of challenges over OTLP until the webhook
+	// stops, nil when no OTLP endpoint is configured.
+	tracing *sdktrace.TracerProvider
+	// dnsResolvers are the recursive nameservers of --dns-resolvers as
+	// host:port, empty using cert-manager's.
+	dnsResolvers []string
+	// apiCalls limits the G-Core API calls in flight across challenges,
+	// nil leaves them unlimited.
+	apiCalls *semaphore.Weighted
+	// debugHTTP logs the API requests of challenges with their responses.
+	debugHTTP bool
+	// dryRun makes every challenge a dry run, as if its issuer set dryRun.
+	dryRun bool
+	// startupCheck makes Initialize check and log the ambient token's
+	// access to the zones, and to startupCheckZone if set.
+	startupCheck     bool
+	startupCheckZone string
+	// staleGCInterval is how often challenge records older than
+	// staleMaxAge are removed, zero disabling the collector.
+	staleGCInterval time.Duration
+	staleMaxAge     time.Duration
+}
+
+// zoneCacheKey identifies a zone lookup of one G-Core account. Lookups that
+// need the zone meta data are cached apart from plain existence checks, and
+// lookups by zone ID apart from lookups by name.
+type zoneCacheKey struct {
+	account string
+	name    string
+	id      uint64
+	details bool
+	// record and match key the zone a record name resolved to under a
+	// zoneMatch policy.
+	record string
+	match  string
+}
+
+// nsCacheKey identifies a nameserver lookup of one G-Core account.
+type nsCacheKey struct {
+	account string
+	zone    string
+	source  string
+}
+
+// dnsAPI is the part of the G-Core DNS SDK client used by the solver.
+type dnsAPI interface {
+	Zone(ctx context.Context, name string) (dnssdk.Zone, error)
+	RRSet(ctx context.Context, zone, name, recordType string) (dnssdk.RRSet, error)
+	CreateRRSet(ctx context.Context, zone, name, recordType string, record dnssdk.RRSet) error
+	UpdateRRSet(ctx context.Context, zone, name, recordType string, record dnssdk.RRSet) error
+	DeleteRRSet(ctx context.Context, zone, name, recordType string) error
+	ZoneNameservers(ctx context.Context, name string) ([]string, error)
+	ZonesWithParam(ctx context.Context, param dnssdk.ZonesParam) (dnssdk.ListZones, error)
+	ZoneDetails(ctx context.Context, name string) (zoneDetails, error)
+	RRSetWithVersion(ctx context.Context, zone, name, recordType string) (dnssdk.RRSet, rrsetVersion, error)
+	UpdateRRSetIfMatch(ctx context.Context, zone, name, recordType string, record dnssdk.RRSet, version rrsetVersion) error
+	DeleteRRSetIfMatch(ctx context.Context, zone, name, recordType string, version rrsetVersion) error
+	CreateZone(ctx context.Context, name string) (uint64, error)
+	EnableZone(ctx context.Context, name string) error
 }
 
 // Name is used as the name for this DNS solver when referencing it on the ACME
@@ -104,19 +377,58 @@ func (c *gcoreDNSProviderSolver) Name() string {
 // cert-manager itself will later perform a self check to ensure that the
 // solver has correctly configured the DNS provider.
 func (c *gcoreDNSProviderSolver) Present(ch *v1alpha1.ChallengeRequest) error {
-	sdk, err := c.initSDK(ch)
+	if !c.inflight.start() {
+		return errShuttingDown
+	}
+	defer c.inflight.finish()
+	start := time.Now()
+	ctx, span := startSpan(c.challengeContext(ch), "gcore.Present", challengeAttributes(ch)...)
+	err := redactError(classifyError(c.present(ctx, ch)))
+	endSpan(span, err)
+	c.metrics.observeChallenge("present", ch, start, err)
+	if err != nil {
+		c.recordFailure(ctx, "present", ch, err)
+		c.recordEvent(ctx, reasonPresentFailed, ch, err)
+	}
+	return err
+}
+
+func (c *gcoreDNSProviderSolver) present(ctx context.Context, ch *v1alpha1.ChallengeRequest) error {
+	defer c.presentBatches.arrive(pendingKey(ch))()
+	sdk, cfg, err := c.initSDK(ctx, ch)
 	if err != nil {
 		return fmt.Errorf("init sdk: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.propagationTimeout)*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.PropagationTimeout)*time.Second)
 	defer cancel()
 
-	err = c.upsertTxtRecord(ctx, sdk, ch)
+	ctx = dryRunContext(ctx, cfg)
+	zone, name, err := c.upsertTxtRecord(ctx, sdk, cfg, ch)
 	if err != nil {
-		return fmt.Errorf("detect zone: %w", err)
+		return fmt.Errorf("upsert txt record: %w", err)
+	}
+	if cfg.VerifyPropagation && !cfg.DryRun {
+		waitCtx, span := startSpan(ctx, "gcore.PropagationWait", attribute.String("dns.zone", zone))
+		err := c.waitForPropagation(waitCtx, sdk, cfg, zone, name, ch.Key)
+		endSpan(span, err)
+		if err != nil {
+			return fmt.Errorf("verify propagation: %w", err)
+		}
 	}
 
+	// Give the record time to reach the nameservers before cert-manager
+	// starts its self check.
+	if err := sleepContext(ctx, cfg.presentDelayFor(cfg.ttlForZone(zone))); err != nil {
+		return fmt.Errorf("present delay: %w", err)
+	}
+	// Spread the self checks of challenges presented together, e.g. in a
+	// mass renewal, so they don't query the nameservers all at once.
+	if err := sleepContext(ctx, jitter(time.Duration(cfg.SelfCheckJitter)*time.Second)); err != nil {
+		return fmt.Errorf("self check jitter: %w", err)
+	}
+
+	c.logRecord(ctx, "presented", ch, zone, name)
 	return nil
 }
 
@@ -127,73 +439,175 @@ func (c *gcoreDNSProviderSolver) Present(ch *v1alpha1.ChallengeRequest) error {
 // This is in order to facilitate multiple DNS validations for the same domain
 // concurrently.
 func (c *gcoreDNSProviderSolver) CleanUp(ch *v1alpha1.ChallengeRequest) error {
-	sdk, err := c.initSDK(ch)
+	if !c.inflight.start() {
+		return errShuttingDown
+	}
+	defer c.inflight.finish()
+	start := time.Now()
+	ctx, span := startSpan(c.challengeContext(ch), "gcore.CleanUp", challengeAttributes(ch)...)
+	err := redactError(classifyError(c.cleanUp(ctx, ch)))
+	endSpan(span, err)
+	c.metrics.observeChallenge("cleanup", ch, start, err)
+	if err != nil {
+		c.recordFailure(ctx, "cleanup", ch, err)
+		c.recordEvent(ctx, reasonCleanUpFailed, ch, err)
+	}
+	return err
+}
+
+func (c *gcoreDNSProviderSolver) cleanUp(ctx context.Context, ch *v1alpha1.ChallengeRequest) error {
+	sdk, cfg, err := c.initSDK(ctx, ch)
 	if err != nil {
 		return fmt.Errorf("init sdk: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.propagationTimeout)*time.Second)
+	timeout := time.Duration(cfg.PropagationTimeout+cfg.CleanupDelay) * time.Second
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
+	ctx = dryRunContext(ctx, cfg)
+
+	// Give overlapping validations of the same name a chance to finish
+	// before their record disappears.
+	if err := sleepContext(ctx, time.Duration(cfg.CleanupDelay)*time.Second); err != nil {
+		return fmt.Errorf("cleanup delay: %w", err)
+	}
 
-	fqdn := strings.Trim(ch.ResolvedFQDN, ".")
-	zone, err := c.detectZone(ctx, fqdn, sdk)
+	fqdn := cfg.recordName(ch.ResolvedFQDN)
+	// CleanUp may be called for a challenge that was never presented, so an
+	// absent zone, RRSet or record means there is nothing to do. Nothing is
+	// written in those cases.
+	zone, fqdn, err := c.recordZone(ctx, fqdn, sdk, cfg)
+	if errors.Is(err, errZoneNotFound) && !cfg.StrictCleanup {
+		c.logRecord(ctx, "cleaned up", ch, "", fqdn)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("detect zone: %w", err)
 	}
-
-	// Fetch current RRSet
-	rrset, err := sdk.RRSet(ctx, zone, fqdn, txtType)
+	if err := c.checkZone(cfg, zone); err != nil {
+		return err
+	}
+	unlock, err := c.recordLocks.lock(ctx, recordLockKey(cfg, fqdn))
 	if err != nil {
-		// Check if it's a 404-like error (RRSet doesn't exist)
-		// For other errors (network, auth, etc.), we should return the error
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "404") {
-			// RRSet doesn't exist, nothing to clean up
+		return fmt.Errorf("wait for rrset lock: %w", err)
+	}
+	defer unlock()
+
+	// Other challenges for the name may share the RRSet, so only the record
+	// of this challenge is removed. Like in upsertTxtRecord the write is
+	// conditional on the version that was read, and a record another
+	// replica added or removed meanwhile makes us filter a fresh read again.
+	// The API is eventually consistent across regions, so the removal is
+	// read back and written again while the record is still returned.
+	removed := false
+	for attempt := 1; attempt <= conflictAttempts; attempt++ {
+		rrset, version, err := sdk.RRSetWithVersion(ctx, zone, fqdn, txtType)
+		if err != nil && removed && isNotFound(err) {
+			c.logRecord(ctx, "cleaned up", ch, zone, fqdn)
+			return nil
+		}
+		if err != nil {
+			// Only a 404 means the RRSet doesn't exist, other errors
+			// (network, auth, etc.) are returned.
+			if isNotFound(err) {
+				// RRSet doesn't exist, nothing to clean up. The zone may be
+				// gone as well, so it is looked up again next time.
+				c.forgetZone(cfg, zone)
+				if cfg.StrictCleanup {
+					return fmt.Errorf("strict cleanup: rrset %s %s not found", fqdn, txtType)
+				}
+				c.logRecord(ctx, "cleaned up", ch, zone, fqdn)
+				return nil
+			}
+			// For other errors, return them
+			return fmt.Errorf("fetch rrset: %w", err)
+		}
+
+		remaining, found, unowned := cfg.withoutChallengeRecord(rrset.Records, ch.Key)
+		if removed && !found {
+			c.logRecord(ctx, "cleaned up", ch, zone, fqdn)
+			return nil
+		}
+		if removed {
+			// The read may have been served by a replica the removal has
+			// not reached yet, so give it time before writing again.
+			if err := sleepContext(ctx, cleanupRecheckInterval); err != nil {
+				return fmt.Errorf("challenge record still in rrset %s %s: %w", fqdn, txtType, err)
+			}
+		}
+		if !found && unowned {
+			if cfg.StrictCleanup {
+				return fmt.Errorf("strict cleanup: challenge record in rrset %s %s lacks the ownership note", fqdn, txtType)
+			}
+			c.logger(ctx).Info("keeping challenge record without ownership note", "recordName", fqdn, "zone", zone,
+				"contentHash", contentHash(ch.Key))
+			return nil
+		}
+		if !found {
+			if cfg.StrictCleanup {
+				return fmt.Errorf("strict cleanup: challenge record not found in rrset %s %s", fqdn, txtType)
+			}
+			c.logRecord(ctx, "cleaned up", ch, zone, fqdn)
+			return nil
+		}
+
+		// If no records remain, delete the entire RRSet
+		if len(remaining) == 0 {
+			err = sdk.DeleteRRSetIfMatch(ctx, zone, fqdn, txtType, version)
+		} else {
+			rrset.Records = sortRecords(remaining)
+			err = sdk.UpdateRRSetIfMatch(ctx, zone, fqdn, txtType, rrset, version)
+		}
+		if isPreconditionFailed(err) || isConflict(err) {
+			continue
+		}
+		if isNotFound(err) {
+			// The RRSet was deleted meanwhile, which removed the record too.
+			c.forgetZone(cfg, zone)
+			c.logRecord(ctx, "cleaned up", ch, zone, fqdn)
 			return nil
 		}
-		// For other errors, return them
-		return fmt.Errorf("fetch rrset: %w", err)
+		if err != nil && len(remaining) == 0 {
+			return fmt.Errorf("delete rrset: %w", err)
+		}
+		if err != nil {
+			return fmt.Errorf("update rrset: %w", err)
+		}
+		removed = true
 	}
+	if removed {
+		return fmt.Errorf("cleanup: challenge record still in rrset %s %s after %d attempts", fqdn, txtType, conflictAttempts)
+	}
+	return fmt.Errorf("cleanup: %s %s changed concurrently %d times", fqdn, txtType, conflictAttempts)
+}
 
-	// Filter out only the record matching ch.Key
-	var remaining []dnssdk.ResourceRecord
-	for _, record := range rrset.Records {
-		// Skip records with no content or empty content
+// cleanupRecheckInterval is the wait before removing a challenge record
+// again that the API still returned after it was removed.
+var cleanupRecheckInterval = time.Second
+
+// withoutChallengeRecord returns records without the challenge record of
+// key, and whether it was found. Records without content and ones that are
+// no string are kept. unowned reports a matching record that is kept
+// because the webhook did not create it, e.g. one added by hand.
+func (cfg gcoreDNSProviderConfig) withoutChallengeRecord(records []dnssdk.ResourceRecord,
+	key string) (remaining []dnssdk.ResourceRecord, found, unowned bool) {
+	for _, record := range records {
 		if len(record.Content) == 0 {
 			continue
 		}
-		
-		// Check if this record contains the challenge key
-		content, ok := record.Content[0].(string)
-		if !ok {
-			// Preserve records with non-string content
+		content, ok := recordSchema.decode(record)
+		if !ok || !cfg.matchesKey(content, key) {
 			remaining = append(remaining, record)
 			continue
 		}
-		
-		if content != ch.Key {
-			// Preserve records that don't match the challenge key
+		if !owned(record) && !cfg.CleanupUnowned {
+			unowned = true
 			remaining = append(remaining, record)
+			continue
 		}
-		// If content == ch.Key, skip this record (remove it)
-	}
-
-	// If no records remain, delete the entire RRSet
-	if len(remaining) == 0 {
-		err = sdk.DeleteRRSet(ctx, zone, fqdn, txtType)
-		if err != nil {
-			return fmt.Errorf("delete rrset: %w", err)
-		}
-		return nil
+		found = true
 	}
-
-	// Otherwise, update with remaining records
-	rrset.Records = remaining
-	err = sdk.UpdateRRSet(ctx, zone, fqdn, txtType, rrset)
-	if err != nil {
-		return fmt.Errorf("update rrset: %w", err)
-	}
-
-	return nil
+	return remaining, found, unowned
 }
 
 // Initialize will be called when the webhook first starts.
@@ -205,137 +619,734 @@ func (c *gcoreDNSProviderSolver) CleanUp(ch *v1alpha1.ChallengeRequest) error {
 // provider accounts.
 // The stopCh can be used to handle early termination of the webhook, in cases
 // where a SIGTERM or similar signal is sent to the webhook process.
-func (c *gcoreDNSProviderSolver) Initialize(kubeClientConfig *rest.Config, _ <-chan struct{}) error {
+func (c *gcoreDNSProviderSolver) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
 	cl, err := kubernetes.NewForConfig(kubeClientConfig)
 	if err != nil {
 		return fmt.Errorf("client: %w", err)
 	}
 	c.client = cl
+	cm, err := cmclient.NewForConfig(kubeClientConfig)
+	if err != nil {
+		return fmt.Errorf("cert-manager client: %w", err)
+	}
+	if err := c.startEventRecorder(cl, cm, stopCh); err != nil {
+		return err
+	}
+	c.drainOnStop(stopCh)
+	if c.startupCheck {
+		if err := c.runStartupCheck(stopCh); err != nil {
+			return fmt.Errorf("startup token check: %w", err)
+		}
+	}
+	if c.tracing != nil {
+		go func() {
+			<-stopCh
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = c.tracing.Shutdown(ctx)
+		}()
+	}
+	if c.staleGCInterval > 0 {
+		if err := c.runStaleRecordGC(stopCh); err != nil {
+			return fmt.Errorf("stale record collector: %w", err)
+		}
+	}
+	// The debug endpoints are optional, challenges are still solved when
+	// they can't be served.
+	if c.adminAddr != "" {
+		if err := c.startAdminServer(c.adminAddr, stopCh); err != nil {
+			c.log.Error(err, "admin server unavailable, continuing without debug endpoints")
+		}
+	}
 	return nil
 }
 
-func (c *gcoreDNSProviderSolver) upsertTxtRecord(ctx context.Context, sdk *dnssdk.Client, ch *v1alpha1.ChallengeRequest) error {
-	fqdn := strings.Trim(ch.ResolvedFQDN, ".")
-	zone, err := c.detectZone(ctx, fqdn, sdk)
+// upsertTxtRecord adds the challenge record and returns the zone and name it
+// was written to.
+func (c *gcoreDNSProviderSolver) upsertTxtRecord(ctx context.Context, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig, ch *v1alpha1.ChallengeRequest) (string, string, error) {
+	name := cfg.recordName(ch.ResolvedFQDN)
+	zone, fqdn, err := c.recordZone(ctx, name, sdk, cfg)
+	if errors.Is(err, errZoneNotFound) && cfg.AllowZoneCreation {
+		fqdn = name
+		zone, err = c.createZone(ctx, sdk, cfg, fqdn)
+		if err == nil {
+			c.missingZones.Delete(missingZoneKey(cfg, name))
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("detect zone: %w", err)
+		return "", "", fmt.Errorf("detect zone: %w", err)
+	}
+	if err := c.checkZone(cfg, zone); err != nil {
+		return "", "", err
 	}
-	recordsToAdd := []dnssdk.ResourceRecord{{Content: []interface{}{ch.Key}, Enabled: true}}
-	rrset, err := sdk.RRSet(ctx, zone, fqdn, txtType)
+	if cfg.VerifyWriteScope {
+		if err := c.verifyWriteScope(ctx, sdk, cfg, zone); err != nil {
+			return "", "", fmt.Errorf("verify write scope: %w", err)
+		}
+	}
+	// Challenges of the same issuer for the same name, e.g. of a wildcard
+	// and its apex, are written together.
+	batchKey := recordLockKey(cfg, fqdn)
+	if ch.Config != nil {
+		batchKey += "\x00" + string(ch.Config.Raw)
+	}
+	err = c.presentCoalesced(ctx, recordLockKey(cfg, fqdn), batchKey, pendingKey(ch), ch.Key, func(keys []string) error {
+		retry, err := c.checkDisabledZone(ctx, sdk, cfg, zone, c.addTxtRecords(ctx, sdk, cfg, zone, fqdn, keys))
+		if retry {
+			return c.addTxtRecords(ctx, sdk, cfg, zone, fqdn, keys)
+		}
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return zone, fqdn, nil
+}
+
+// addTxtRecords adds the challenge records of keys to the RRSet fqdn in zone.
+func (c *gcoreDNSProviderSolver) addTxtRecords(ctx context.Context, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig, zone, fqdn string, keys []string) error {
+	var recordsToAdd []dnssdk.ResourceRecord
+	for _, key := range keys {
+		recordsToAdd = append(recordsToAdd, markOwned(recordSchema.encode(key), key, time.Now()))
+	}
+
+	// The API has no endpoint appending to an RRSet, but creating one only
+	// succeeds if it does not exist yet. Trying the create first writes the
+	// common single-record case without a read-modify-write window.
+	created := dnssdk.RRSet{TTL: cfg.ttlForZone(zone), Records: recordsToAdd}
+	err := sdk.CreateRRSet(ctx, zone, fqdn, txtType, created)
 	if err == nil {
-		rrset.Records = append(rrset.Records, recordsToAdd...)
-		err = sdk.UpdateRRSet(ctx, zone, fqdn, txtType, rrset)
+		return verifyRRSet(ctx, sdk, cfg, zone, fqdn, created)
+	}
+	if isNotFound(err) {
+		c.forgetZone(cfg, zone)
+	}
+	if !isRRSetExists(err) {
+		return fmt.Errorf("create rrset: %w", err)
+	}
+
+	// Several replicas may append to the same RRSet. When the API versions
+	// RRSets the update is conditional on the version that was read, and a
+	// concurrent change makes us merge again from a fresh read. Otherwise the
+	// read-back in verifyRRSet re-adds a record lost to a concurrent write.
+	for attempt := 1; attempt <= conflictAttempts; attempt++ {
+		rrset, version, err := sdk.RRSetWithVersion(ctx, zone, fqdn, txtType)
+		if err != nil {
+			return fmt.Errorf("fetch rrset: %w", err)
+		}
+		// cert-manager repeats Present until the challenge is marked as
+		// presented, so the record is usually there already. Rewriting the
+		// RRSet anyway would only churn the nameservers.
+		var missing []dnssdk.ResourceRecord
+		for i, key := range keys {
+			if !cfg.hasKey(rrset.Records, key) {
+				missing = append(missing, recordsToAdd[i])
+			}
+		}
+		if len(missing) == 0 {
+			c.logger(ctx).V(1).Info("challenge record already present", "recordName", fqdn, "zone", zone)
+			return nil
+		}
+		rrset.Records = sortRecords(append(rrset.Records, missing...))
+		err = sdk.UpdateRRSetIfMatch(ctx, zone, fqdn, txtType, rrset, version)
+		if isPreconditionFailed(err) {
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("update rrset: %w", err)
 		}
-		return nil
+		return verifyRRSet(ctx, sdk, cfg, zone, fqdn, rrset)
 	}
-	err = sdk.AddZoneRRSet(ctx,
-		zone,
-		fqdn,
-		txtType,
-		recordsToAdd,
-		c.ttl)
-	if err != nil {
-		return fmt.Errorf("add rrset: %w", err)
+	return fmt.Errorf("update rrset: %s %s changed concurrently %d times", fqdn, txtType, conflictAttempts)
+}
+
+// conflictAttempts bounds how often a conditional RRSet update is retried
+// after losing a race against another writer.
+const conflictAttempts = 5
+
+// isPreconditionFailed reports whether a conditional update lost a race.
+func isPreconditionFailed(err error) bool {
+	var apiErr dnssdk.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusPreconditionFailed
+}
+
+// isConflict reports whether a write was refused with 409 Conflict, as the
+// API does while an earlier change of the RRSet is still being applied.
+func isConflict(err error) bool {
+	var apiErr dnssdk.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+// isRRSetExists reports whether a create failed because the RRSet already
+// exists (or the create endpoint is unavailable), so the caller should fall
+// back to updating the existing RRSet.
+func isRRSetExists(err error) bool {
+	var apiErr dnssdk.APIError
+	if !errors.As(err, &apiErr) {
+		return false
 	}
-	return nil
+	switch apiErr.StatusCode {
+	case http.StatusConflict, http.StatusMethodNotAllowed:
+		return true
+	case http.StatusBadRequest:
+		return strings.Contains(strings.ToLower(apiErr.Message), "exist")
+	}
+	return false
 }
 
-func (c *gcoreDNSProviderSolver) initSDK(ch *v1alpha1.ChallengeRequest) (*dnssdk.Client, error) {
+// firstConfigUse reports whether ch is the first challenge of its issuer, as
+// told by the namespace and config it comes with.
+func (c *gcoreDNSProviderSolver) firstConfigUse(ch *v1alpha1.ChallengeRequest) bool {
+	key := ch.ResourceNamespace + "\x00"
+	if ch.Config != nil {
+		key += string(ch.Config.Raw)
+	}
+	_, seen := c.configsSeen.LoadOrStore(key, struct{}{})
+	return !seen
+}
+
+func (c *gcoreDNSProviderSolver) initSDK(ctx context.Context, ch *v1alpha1.ChallengeRequest) (dnsAPI, gcoreDNSProviderConfig, error) {
 	cfg, err := loadConfig(ch.Config)
+	ambient := c.ambientToken(ch)
+	if err != nil && (!errors.Is(err, errNoConfig) || ambient == "") {
+		if errors.Is(err, errNoConfig) && ch.AllowAmbientCredentials && !c.ambientNamespaceAllowed(ch.ResourceNamespace) {
+			err = fmt.Errorf("%w; ambient credentials are not allowed for issuers in namespace %q by %s",
+				err, ch.ResourceNamespace, ambientNSEnvVar)
+		}
+		return nil, cfg, fmt.Errorf("load cfg: %w", err)
+	}
+	if len(cfg.warnings) > 0 && c.firstConfigUse(ch) {
+		for _, warning := range cfg.warnings {
+			c.logger(ctx).Info("deprecated solver config: "+warning, "namespace", ch.ResourceNamespace)
+		}
+	}
+	fqdn := cfg.recordName(ch.ResolvedFQDN)
+	if cfg.FollowCNAME {
+		target, err := c.followCNAME(ctx, fqdn)
+		if err != nil {
+			return nil, cfg, err
+		}
+		fqdn, cfg.cnameTarget = target, target
+	}
+	cfg.useZoneCredential(fqdn)
+	var problems configErrors
+	if ambient == "" {
+		if err := cfg.validateCredentials(); err != nil {
+			if len(cfg.ZoneCredentials) > 0 {
+				err = fmt.Errorf("%w, and no zoneCredentials entry matches %s", err, fqdn)
+			}
+			problems.add(err)
+		}
+	}
+	problems.add(cfg.Validate())
+	if err := problems.err(); err != nil {
+		return nil, cfg, fmt.Errorf("invalid solver config: %w", err)
+	}
+	cfg.setDefaults()
+	token, err := c.resolveToken(ctx, cfg, ch)
 	if err != nil {
-		return nil, fmt.Errorf("load cfg: %w", err)
+		return nil, cfg, fmt.Errorf("get token: %w", err)
+	}
+	cfg.account = accountKey(cfg.ApiUrl, token)
+	switch {
+	case cfg.usesLogin():
+		// The password alone does not identify the account.
+		cfg.account = accountKey(cfg.ApiUrl, cfg.Login.Username+"\x00"+token)
+		cfg.bearer, err = c.loginTokenFor(cfg, token)
+	case cfg.AuthMode == authModeBearer:
+		cfg.bearer, err = c.bearerTokenFor(cfg, token)
+	case cfg.ApiToken == "" && cfg.APITokenFile != "":
+		cfg.tokenFile, _ = c.tokenFileFor(cfg.APITokenFile)
+	}
+	if err != nil {
+		return nil, cfg, err
+	}
+	if cfg.ClientID != 0 {
+		// A reseller token reaches every client account, so the lookups of
+		// each client are cached apart.
+		cfg.account = accountKey(cfg.account, strconv.FormatUint(cfg.ClientID, 10))
+	}
+	if cfg.InsecureSkipVerify {
+		c.logger(ctx).Info("insecureSkipVerify is set, the G-Core API certificate is not verified")
+	}
+	cfg.metrics = c.metrics
+	cfg.traceRequests = c.tracing != nil
+	if c.debugHTTP {
+		cfg.debugHTTP = c.log
+	}
+	sdk, err := c.sdkClient(cfg, token)
+	if err != nil {
+		return nil, cfg, err
+	}
+	policy := cfg.retryPolicy()
+	api := &retryingAPI{api: sdk, apiURL: cfg.ApiUrl, policy: &policy, breaker: c.breakers.get(cfg.ApiUrl),
+		limiter: c.apiCalls}
+	// dryRunWrap marks cfg as a dry run, so it must return before cfg is
+	// read.
+	wrapped := c.dryRunWrap(ctx, c.audit.wrap(api, challengeActor(cfg.account, ch)), &cfg)
+	return wrapped, cfg, nil
+}
+
+// newSDKClient builds a G-Core DNS API client authenticated with a permanent
+// token, or with renewed access tokens in bearer mode. With
+// fallbackEndpoints it fails over between a client per endpoint.
+func newSDKClient(cfg gcoreDNSProviderConfig, token string) (dnsAPI, error) {
+	if len(cfg.FallbackEndpoints) == 0 {
+		return newEndpointClient(cfg, token)
 	}
+	var endpoints []*failoverEndpoint
+	for _, endpoint := range append([]string{cfg.ApiUrl}, cfg.FallbackEndpoints...) {
+		cfg.ApiUrl = endpoint
+		api, err := newEndpointClient(cfg, token)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, &failoverEndpoint{url: endpoint, api: api})
+	}
+	return newFailoverAPI(endpoints), nil
+}
+
+// newEndpointClient builds the API client of cfg.ApiUrl.
+func newEndpointClient(cfg gcoreDNSProviderConfig, token string) (dnsAPI, error) {
 	apiFullUrl := cfg.ApiUrl
 	if apiFullUrl == "" {
-		apiFullUrl = "https://api.gcore.com/dns"
+		apiFullUrl = defaultAPIURL
 	}
 	apiURL, err := url.Parse(apiFullUrl)
 	if err != nil || apiFullUrl == "" {
 		return nil, fmt.Errorf("parse api url %s: %w", apiFullUrl, err)
 	}
-	token := cfg.ApiToken
-	if token == "" {
-		token, err = c.extractApiTokenFromSecret(cfg, ch)
-		if err != nil {
-			return nil, fmt.Errorf("get token: %w", err)
-		}
-	}
 	sdk := dnssdk.NewClient(dnssdk.PermanentAPIKeyAuth(token), func(client *dnssdk.Client) {
 		client.BaseURL = apiURL
 	})
 	if cfg.Timeout > 0 {
 		sdk.HTTPClient.Timeout = time.Duration(cfg.Timeout) * time.Second
 	}
-	if cfg.TTL == 0 {
-		cfg.TTL = 300
+	transport, err := cfg.apiTransport()
+	if err != nil {
+		return nil, err
 	}
-	c.ttl = cfg.TTL
-	if cfg.PropagationTimeout == 0 {
-		cfg.PropagationTimeout = 60 * 5
+	sdk.HTTPClient.Transport = cfg.metrics.instrument(transport, cfg.account)
+	if !cfg.debugHTTP.IsZero() {
+		sdk.HTTPClient.Transport = &dumpTransport{base: sdk.HTTPClient.Transport, log: cfg.debugHTTP}
+	}
+	if cfg.traceRequests {
+		// The requests are traced as children of the API call spans.
+		sdk.HTTPClient.Transport = otelhttp.NewTransport(sdk.HTTPClient.Transport)
+	}
+	switch {
+	case cfg.bearer != nil:
+		// The transport replaces the APIKey header with a current access token.
+		sdk.HTTPClient.Transport = &bearerTransport{base: sdk.HTTPClient.Transport, token: cfg.bearer}
+	case cfg.tokenFile != nil:
+		sdk.HTTPClient.Transport = &tokenFileTransport{base: sdk.HTTPClient.Transport, file: cfg.tokenFile}
+	}
+	if cfg.ClientID != 0 {
+		sdk.HTTPClient.Transport = &clientTransport{base: sdk.HTTPClient.Transport, clientID: cfg.ClientID}
+	}
+	sdk.HTTPClient.Transport = &rateLimitTransport{base: sdk.HTTPClient.Transport}
+	sdk.HTTPClient.Transport = &requestIDTransport{base: sdk.HTTPClient.Transport}
+	return &gcoreClient{Client: sdk, authHeader: "APIKey " + token}, nil
+}
+
+// resolveToken picks the API token for the challenge from the first
+// credential source of tokenProviderSources the config uses. An explicit
+// apiToken wins over apiTokenFile, apiTokenSecretRef, apiKeySecretRef and
+// tokenSource, in that order, and all of them win over the ambient GCORE_API_TOKEN environment
+// variable, which is only used when the challenge allows ambient credentials.
+// With login, the password is returned in place of a token.
+func (c *gcoreDNSProviderSolver) resolveToken(ctx context.Context, cfg gcoreDNSProviderConfig,
+	ch *v1alpha1.ChallengeRequest) (string, error) {
+	provider, err := c.tokenProviderFor(cfg, ch)
+	if err != nil {
+		return "", err
+	}
+	return provider.Token(ctx)
+}
+
+// ambientToken returns the token from the webhook's environment if the
+// challenge allows ambient credentials and its issuer is in one of the
+// ambient namespaces.
+func (c *gcoreDNSProviderSolver) ambientToken(ch *v1alpha1.ChallengeRequest) string {
+	if !ch.AllowAmbientCredentials || !c.ambientNamespaceAllowed(ch.ResourceNamespace) {
+		return ""
 	}
-	c.propagationTimeout = cfg.PropagationTimeout
-	return sdk, nil
+	return os.Getenv(apiTokenEnvVar)
+}
+
+// ambientNamespaceAllowed reports whether issuers of namespace may use
+// ambient credentials. cert-manager resolves ClusterIssuers to its cluster
+// resource namespace, so listing only that namespace limits ambient
+// credentials to ClusterIssuers.
+func (c *gcoreDNSProviderSolver) ambientNamespaceAllowed(namespace string) bool {
+	return c.ambientNamespaces == nil || slices.Contains(c.ambientNamespaces, namespace)
+}
+
+// ambientNamespacesFromEnv reads the namespaces allowed to use ambient
+// credentials from the comma separated GCORE_AMBIENT_CREDENTIALS_NAMESPACES
+// variable.
+func ambientNamespacesFromEnv() []string {
+	return splitList(os.Getenv(ambientNSEnvVar))
 }
 
-func (c *gcoreDNSProviderSolver) extractApiTokenFromSecret(
-	cfg gcoreDNSProviderConfig, ch *v1alpha1.ChallengeRequest) (string, error) {
-	sec, err := c.client.CoreV1().
-		Secrets(ch.ResourceNamespace).
-		Get(context.Background(), cfg.APIKeySecretRef.LocalObjectReference.Name, metaV1.GetOptions{})
+// secretLookupAttempts and secretRetryInterval bound how long a secret that
+// does not exist yet is waited for, e.g. when a GitOps tool applies the secret
+// and the issuer together.
+const secretLookupAttempts = 3
+
+var secretRetryInterval = 2 * time.Second
+
+// readSecret returns the value of a key of a secret, waiting a little for a
+// secret that does not exist yet, as long as ctx allows.
+func (c *gcoreDNSProviderSolver) readSecret(ctx context.Context, namespace, name, key string) (string, error) {
+	if c.client == nil {
+		return "", fmt.Errorf("secret \"%s/%s\" can't be read without a Kubernetes client", namespace, name)
+	}
+	var sec *corev1.Secret
+	var err error
+	for attempt := 1; attempt <= secretLookupAttempts; attempt++ {
+		sec, err = c.client.CoreV1().
+			Secrets(namespace).
+			Get(ctx, name, metaV1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			break
+		}
+		if attempt < secretLookupAttempts {
+			if err := sleepContext(ctx, secretRetryInterval); err != nil {
+				return "", fmt.Errorf("wait for secret %q in namespace %q: %w", name, namespace, err)
+			}
+		}
+	}
+	if apierrors.IsNotFound(err) {
+		return "", fmt.Errorf("secret %q not found in namespace %q after %d attempts",
+			name, namespace, secretLookupAttempts)
+	}
 	if err != nil {
 		return "", fmt.Errorf("extract secret: %w", err)
 	}
 
-	secBytes, ok := sec.Data[cfg.APIKeySecretRef.Key]
+	secBytes, ok := sec.Data[key]
 	if !ok {
-		return "", fmt.Errorf("key %s not found in secret \"%s/%s\"",
-			cfg.APIKeySecretRef.Key,
-			cfg.APIKeySecretRef.LocalObjectReference.Name,
-			ch.ResourceNamespace)
+		return "", fmt.Errorf("key %s not found in secret \"%s/%s\"", key, name, namespace)
 	}
 
 	return string(secBytes), nil
 }
 
-func (c *gcoreDNSProviderSolver) detectZone(ctx context.Context, fqdn string, sdk *dnssdk.Client) (string, error) {
+// errZoneNotFound is returned when the API has no zone for a name.
+var errZoneNotFound = errors.New("not found")
+
+// isNotFound reports whether err is an API 404 response.
+func isNotFound(err error) bool {
+	var apiErr dnssdk.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// detectZone returns the zone fqdn belongs to.
+func (c *gcoreDNSProviderSolver) detectZone(ctx context.Context, fqdn string, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig) (string, error) {
+	_, zone, err := c.findZone(ctx, fqdn, sdk, cfg)
+	return zone, err
+}
+
+// recordZone returns the zone to write the record for fqdn to and the record
+// name within it. A configured zoneID or zoneName skips discovery. With
+// resolveZoneAliases, a name under an alias zone is moved to the canonical
+// zone the API reports for the alias. That no zone was found is cached in
+// missingZones.
+func (c *gcoreDNSProviderSolver) recordZone(ctx context.Context, fqdn string, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig) (string, string, error) {
+	ctx, span := startSpan(ctx, "gcore.ZoneLookup", attribute.String("dns.record", fqdn))
+	key := missingZoneKey(cfg, fqdn)
+	if err, ok := c.missingZones.Get(key); ok {
+		span.SetAttributes(attribute.Bool("gcore.cached", true))
+		endSpan(span, err)
+		return "", "", err
+	}
+	zone, name, err := c.discoverRecordZone(ctx, fqdn, sdk, cfg)
+	if errors.Is(err, errZoneNotFound) {
+		c.missingZones.Set(key, err)
+	}
+	span.SetAttributes(attribute.String("dns.zone", zone))
+	endSpan(span, err)
+	return zone, name, err
+}
+
+// missingZoneKey identifies the zone lookup of fqdn in missingZones.
+func missingZoneKey(cfg gcoreDNSProviderConfig, fqdn string) zoneCacheKey {
+	return zoneCacheKey{account: cfg.account, record: asciiDomain(fqdn), match: cfg.ZoneMatch,
+		name: asciiDomain(cfg.ZoneName), id: cfg.ZoneID}
+}
+
+func (c *gcoreDNSProviderSolver) discoverRecordZone(ctx context.Context, fqdn string, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig) (string, string, error) {
+	if cfg.ZoneID != 0 {
+		zone, err := c.zoneByID(ctx, sdk, cfg)
+		if err != nil {
+			return "", "", err
+		}
+		if !inZone(fqdn, zone) {
+			return "", "", fmt.Errorf("record %s is not in zone %s (zoneID %d)", fqdn, zone, cfg.ZoneID)
+		}
+		return zone, fqdn, nil
+	}
+	if cfg.ZoneName != "" {
+		zone := asciiDomain(cfg.ZoneName)
+		if !inZone(fqdn, zone) {
+			return "", "", fmt.Errorf("record %s is not in zone %s (zoneName)", fqdn, zone)
+		}
+		details, err := c.lookupZone(ctx, sdk, cfg, zone)
+		if isNotFound(err) {
+			return "", "", fmt.Errorf("zoneName %q %w: %w", zone, errZoneNotFound, err)
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if len(cfg.ZoneTagFilter) > 0 && !matchZoneTags(details.Meta, cfg.ZoneTagFilter) {
+			return "", "", fmt.Errorf("zone %s lacks the tags required by zoneTagFilter %v", zone, cfg.ZoneTagFilter)
+		}
+		return zone, fqdn, nil
+	}
+	candidate, zone, err := c.findZone(ctx, fqdn, sdk, cfg)
+	if err != nil {
+		return "", "", err
+	}
+	zone = strings.Trim(zone, ".")
+	if !cfg.ResolveZoneAliases || strings.EqualFold(candidate, zone) {
+		return zone, fqdn, nil
+	}
+	return zone, strings.TrimSuffix(fqdn, candidate) + zone, nil
+}
+
+// findZone returns the zone fqdn belongs to, along with the candidate name
+// it was found under. The two differ when the API resolves an alias zone.
+func (c *gcoreDNSProviderSolver) findZone(ctx context.Context, fqdn string, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig) (string, string, error) {
 	lastErr := fmt.Errorf("empty list")
-	zones := extractAllZones(fqdn)
-	n := len(zones) - 1
-	for i := range zones {
-		dnsZone, err := sdk.Zone(ctx, zones[n-i])
-		if err == nil {
-			return dnsZone.Name, nil
+	absent := true
+	// Candidates are tried in the order of the zoneMatch policy, the
+	// first zone of the account wins.
+	candidates := extractZones(fqdn, cfg.MinZoneLabels)
+	if cfg.ZoneMatch == zoneMatchShallowest {
+		slices.Reverse(candidates)
+	}
+	if cfg.ZoneDiscovery == zoneDiscoverySOA {
+		candidate, zone, err := c.soaZone(ctx, fqdn, sdk, cfg)
+		if err == nil || errors.Is(err, errAPIUnreachable) {
+			return candidate, zone, err
+		}
+		// DNS may not know the zone yet, or point at one of another
+		// account, so the candidates are probed as usual.
+		c.logger(ctx).V(1).Info("soa zone discovery failed, probing candidate zones", "recordName", fqdn, "error", err.Error())
+	}
+	// Alias zones are only resolved by fetching them, so they are probed.
+	if cfg.ZoneDiscovery == zoneDiscoveryFilter && !cfg.ResolveZoneAliases {
+		// The outcome is cached per record name: a cached zone of another
+		// name may be a parent of a zone this name is in.
+		recordKey := zoneCacheKey{account: cfg.account, record: asciiDomain(fqdn), match: cfg.ZoneMatch}
+		if details, ok := c.zones.Get(recordKey); ok && len(cfg.ZoneTagFilter) == 0 {
+			return details.Name, details.Name, nil
+		}
+		var matched []string
+		var err error
+		if _, ok := c.unfiltered.Get(cfg.ApiUrl); ok {
+			err = errZoneFilterIgnored
+		} else {
+			matched, err = filterZones(ctx, sdk, candidates)
+		}
+		switch {
+		case errors.Is(err, errAPIUnreachable):
+			return "", "", fmt.Errorf("filter zones: %w", err)
+		case errors.Is(err, errZoneFilterIgnored):
+			c.unfiltered.Set(cfg.ApiUrl, struct{}{})
+			c.logger(ctx).V(1).Info("zone name filter ignored, probing candidate zones", "recordName", fqdn, "error", err.Error())
+		case err != nil:
+			c.logger(ctx).V(1).Info("filtered zone query failed, probing candidate zones", "recordName", fqdn, "error", err.Error())
+		case len(matched) == 0:
+			return "", "", fmt.Errorf("zone %q %w in filtered zone list", fqdn, errZoneNotFound)
+		case len(cfg.ZoneTagFilter) == 0:
+			c.zones.Set(recordKey, zoneDetails{Name: matched[0]})
+			return matched[0], matched[0], nil
+		default:
+			candidates = matched
+		}
+	}
+	if cfg.ZoneDiscovery == zoneDiscoveryList {
+		matched, err := listZones(ctx, sdk, candidates, len(cfg.ZoneTagFilter) == 0)
+		if err != nil {
+			return "", "", fmt.Errorf("list zones: %w", err)
+		}
+		if len(matched) == 0 {
+			return "", "", fmt.Errorf("zone %q %w in zone list", fqdn, errZoneNotFound)
+		}
+		if len(cfg.ZoneTagFilter) == 0 {
+			return matched[0], matched[0], nil
+		}
+		candidates = matched
+	}
+	var untagged []string
+	for _, candidate := range candidates {
+		details, err := c.lookupZone(ctx, sdk, cfg, candidate)
+		if errors.Is(err, errAPIUnreachable) {
+			return "", "", err
+		}
+		if err != nil {
+			lastErr = err
+			absent = absent && isNotFound(err)
+			continue
+		}
+		if len(cfg.ZoneTagFilter) == 0 {
+			return candidate, details.Name, nil
+		}
+		// Zones lacking the required tags are skipped so a cluster can't
+		// write to zones that were not opted in.
+		if !matchZoneTags(details.Meta, cfg.ZoneTagFilter) {
+			untagged = append(untagged, details.Name)
+			continue
 		}
-		lastErr = err
+		return candidate, details.Name, nil
 	}
-	return "", fmt.Errorf("zone %q not found: %w", fqdn, lastErr)
+	if len(untagged) > 0 {
+		return "", "", fmt.Errorf("zone %s matches %q but lacks the tags required by zoneTagFilter %v",
+			strings.Join(untagged, ", "), fqdn, cfg.ZoneTagFilter)
+	}
+	if absent {
+		return "", "", fmt.Errorf("zone %q %w: %w", fqdn, errZoneNotFound, lastErr)
+	}
+	return "", "", fmt.Errorf("zone %q not found: %w", fqdn, lastErr)
 }
 
-// loadConfig is a small helper function that decodes JSON configuration into
-// the typed config struct.
-func loadConfig(cfgJSON *extapi.JSON) (gcoreDNSProviderConfig, error) {
-	cfg := gcoreDNSProviderConfig{}
-	// handle the 'base case' where no configuration has been provided
-	if cfgJSON == nil {
-		return cfg, nil
+// lookupZone fetches a candidate zone, going through the zone cache. The zone
+// meta data is only requested when a zoneTagFilter needs it.
+func (c *gcoreDNSProviderSolver) lookupZone(ctx context.Context, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig, name string) (zoneDetails, error) {
+	key := zoneCacheKey{account: cfg.account, name: name, details: len(cfg.ZoneTagFilter) > 0}
+	if details, ok := c.zones.Get(key); ok {
+		return details, nil
 	}
-	if err := json.Unmarshal(cfgJSON.Raw, &cfg); err != nil {
-		return cfg, fmt.Errorf("error decoding solver config: %v", err)
+	var details zoneDetails
+	if key.details {
+		var err error
+		details, err = sdk.ZoneDetails(ctx, name)
+		if err != nil {
+			return zoneDetails{}, err
+		}
+	} else {
+		dnsZone, err := sdk.Zone(ctx, name)
+		if err != nil {
+			return zoneDetails{}, err
+		}
+		details.Name = dnsZone.Name
 	}
+	c.zones.Set(key, details)
+	return details, nil
+}
+
+// forgetZone drops the cached lookups of an account resolving to zone, after
+// the API reported the zone missing, e.g. because it was deleted or moved
+// to another account. The next challenge looks the zone up again.
+func (c *gcoreDNSProviderSolver) forgetZone(cfg gcoreDNSProviderConfig, zone string) {
+	c.zones.DeleteFunc(func(key zoneCacheKey, details zoneDetails) bool {
+		return key.account == cfg.account &&
+			(strings.EqualFold(key.name, zone) || strings.EqualFold(strings.Trim(details.Name, "."), zone))
+	})
+}
 
-	return cfg, nil
+// zoneByID returns the name of the configured zoneID, going through the zone
+// cache so the name is fetched once.
+func (c *gcoreDNSProviderSolver) zoneByID(ctx context.Context, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig) (string, error) {
+	key := zoneCacheKey{account: cfg.account, id: cfg.ZoneID}
+	if details, ok := c.zones.Get(key); ok {
+		return details.Name, nil
+	}
+	name, err := zoneNameByID(ctx, sdk, cfg.ZoneID)
+	if err != nil {
+		return "", err
+	}
+	c.zones.Set(key, zoneDetails{Name: name})
+	return name, nil
+}
+
+// inZone reports whether the record name fqdn is zone or below it. Both may
+// be internationalized names in unicode or punycode.
+func inZone(fqdn, zone string) bool {
+	return strings.HasSuffix("."+asciiDomain(fqdn), "."+asciiDomain(zone))
+}
+
+// matchZoneTags reports whether the zone meta carries every tag of the filter.
+func matchZoneTags(meta map[string]any, filter map[string]string) bool {
+	for key, value := range filter {
+		tag, ok := meta[key]
+		if !ok || fmt.Sprint(tag) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// accountKey identifies the G-Core account behind an API url and token
+// without keeping the token itself around.
+func accountKey(apiURL, token string) string {
+	sum := sha256.Sum256([]byte(apiURL + "\x00" + token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// logRecord logs the outcome of a challenge as a single line. The key is
+// represented by a short hash, enough to correlate lines but not to recover it.
+func (c *gcoreDNSProviderSolver) logRecord(ctx context.Context, msg string, ch *v1alpha1.ChallengeRequest,
+	zone, name string) {
+	c.logger(ctx).Info(msg, "zone", zone, "recordName", name, "contentHash", contentHash(ch.Key))
+}
+
+// contentHash returns a short, non-reversible fingerprint of a record content.
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:6])
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// jitter returns a random duration in [0, limit].
+func jitter(limit time.Duration) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+	return rand.N(limit + 1)
 }
 
 func extractAllZones(fqdn string) []string {
+	return extractZones(fqdn, defaultMinZoneLabels)
+}
+
+// extractZones returns the parent domains of fqdn that could be its zone,
+// longest first, leaving out candidates with fewer than minLabels labels.
+func extractZones(fqdn string, minLabels int) []string {
+	if minLabels < defaultMinZoneLabels {
+		minLabels = defaultMinZoneLabels
+	}
 	parts := strings.Split(strings.Trim(fqdn, "."), ".")
-	if len(parts) < 3 {
+	if len(parts) <= minLabels {
 		return nil
 	}
 
 	var zones []string
-	for i := 1; i < len(parts)-1; i++ {
+	for i := 1; i <= len(parts)-minLabels; i++ {
 		zones = append(zones, strings.Join(parts[i:], "."))
 	}
 
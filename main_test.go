@@ -1,12 +1,33 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"net/http"
 	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	certmgrv1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
 	dns "github.com/cert-manager/cert-manager/test/acme"
+	"github.com/go-logr/logr/funcr"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 var (
@@ -26,8 +47,8 @@ func TestRunsSuite(t *testing.T) {
 		dns.SetAllowAmbientCredentials(false),
 		dns.SetManifestPath("testdata/gcore"),
 
-		// Disable the extended test to create several records for the same Record DNS Name
-		dns.SetStrict(false),
+		// Run the extended tests presenting several records for the same name
+		dns.SetStrict(true),
 		// Increase the poll interval to 10s
 		dns.SetPollInterval(pollTime),
 		// Increase the limit from 2 min to 5 min
@@ -66,6 +87,49 @@ func Test_extractAllZones(t *testing.T) {
 	}
 }
 
+func Test_extractZones(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		fqdn      string
+		minLabels int
+		expected  []string
+	}{
+		{
+			desc:      "default minimum",
+			fqdn:      "_acme-challenge.my.test.domain.com.",
+			minLabels: 2,
+			expected:  []string{"my.test.domain.com", "test.domain.com", "domain.com"},
+		},
+		{
+			desc:      "raised minimum",
+			fqdn:      "_acme-challenge.my.test.domain.co.uk.",
+			minLabels: 3,
+			expected:  []string{"my.test.domain.co.uk", "test.domain.co.uk", "domain.co.uk"},
+		},
+		{
+			desc:      "minimum above depth",
+			fqdn:      "_acme-challenge.domain.co.uk.",
+			minLabels: 4,
+		},
+		{
+			desc:      "minimum below default",
+			fqdn:      "_acme-challenge.domain.com.",
+			minLabels: 1,
+			expected:  []string{"domain.com"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got := extractZones(test.fqdn, test.minLabels)
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
 func TestConcurrentCleanup(t *testing.T) {
 	t.Run("cleanup_removes_only_matching_record", func(t *testing.T) {
 		// Simulate scenario where there are 3 TXT records for the same FQDN
@@ -296,22 +360,1132 @@ func TestConcurrentCleanup(t *testing.T) {
 	})
 }
 
+func TestPresentUpsert(t *testing.T) {
+	const fqdn = "_acme-challenge.example.com."
+
+	t.Run("create without prior read", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		require.NoError(t, solverWithMock(mock).Present(challenge(fqdn, "token-A", `{"apiToken":"t"}`)))
+		assert.Equal(t, 1, mock.creates)
+		assert.Equal(t, 1, mock.writes)
+		assert.Equal(t, 1, mock.reads, "only the verification read is expected")
+		assert.Equal(t, []string{"token-A"}, mock.contents("example.com", "_acme-challenge.example.com"))
+	})
+
+	t.Run("existing rrset falls back to read-modify-write", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		solver := solverWithMock(mock)
+		require.NoError(t, solver.Present(challenge(fqdn, "token-A", `{"apiToken":"t"}`)))
+		require.NoError(t, solver.Present(challenge(fqdn, "token-B", `{"apiToken":"t"}`)))
+		assert.Equal(t, 2, mock.creates)
+		assert.Equal(t, 2, mock.writes)
+		assert.Equal(t, []string{"token-A", "token-B"}, mock.contents("example.com", "_acme-challenge.example.com"))
+	})
+
+	t.Run("present record is not written again", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		solver := solverWithMock(mock)
+		require.NoError(t, solver.Present(challenge(fqdn, "token-A", `{"apiToken":"t"}`)))
+		require.NoError(t, solver.Present(challenge(fqdn, "token-B", `{"apiToken":"t"}`)))
+		writes := mock.writes
+		require.NoError(t, solver.Present(challenge(fqdn, "token-A", `{"apiToken":"t"}`)))
+		assert.Equal(t, writes, mock.writes)
+		assert.Equal(t, []string{"token-A", "token-B"}, mock.contents("example.com", "_acme-challenge.example.com"))
+	})
+}
+
+func TestPresentReplicasRace(t *testing.T) {
+	const fqdn = "_acme-challenge.example.com."
+	mock := newMockSDK("example.com")
+	// two replicas share the API but nothing in-process
+	replicaA, replicaB := solverWithMock(mock), solverWithMock(mock)
+	require.NoError(t, replicaA.Present(challenge(fqdn, "token-X", `{"apiToken":"t"}`)))
+
+	// replica B appends its record after replica A read the RRSet but before
+	// A's conditional write lands
+	mock.beforeIfMatch = func() {
+		assert.NoError(t, replicaB.Present(challenge(fqdn, "token-B", `{"apiToken":"t"}`)))
+	}
+	require.NoError(t, replicaA.Present(challenge(fqdn, "token-A", `{"apiToken":"t"}`)))
+
+	assert.ElementsMatch(t, []string{"token-X", "token-A", "token-B"},
+		mock.contents("example.com", "_acme-challenge.example.com"))
+}
+
+// TestSharedRRSet follows the strict extended conformance test: challenges
+// for one name share an RRSet, and each Present and CleanUp only adds or
+// removes its own value.
+func TestSharedRRSet(t *testing.T) {
+	const fqdn = "_acme-challenge.example.com."
+	cfg := `{"apiToken":"t"}`
+
+	t.Run("deleting one record retains others", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		solver := solverWithMock(mock)
+		ch, ch2 := challenge(fqdn, "testingkey", cfg), challenge(fqdn, "anothertestingkey", cfg)
+		require.NoError(t, solver.Present(ch))
+		require.NoError(t, solver.Present(ch2))
+		assert.ElementsMatch(t, []string{"testingkey", "anothertestingkey"},
+			mock.contents("example.com", "_acme-challenge.example.com"))
+
+		// The suite cleans the second challenge up twice.
+		require.NoError(t, solver.CleanUp(ch2))
+		require.NoError(t, solver.CleanUp(ch2))
+		assert.Equal(t, []string{"testingkey"}, mock.contents("example.com", "_acme-challenge.example.com"))
+
+		require.NoError(t, solver.CleanUp(ch))
+		assert.Empty(t, mock.contents("example.com", "_acme-challenge.example.com"))
+		assert.Equal(t, 1, mock.deletes)
+	})
+
+	t.Run("concurrent challenges", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		solver := solverWithMock(mock)
+		var keys []string
+		for i := range 10 {
+			keys = append(keys, fmt.Sprintf("token-%d", i))
+		}
+		var wg sync.WaitGroup
+		for _, key := range keys {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, solver.Present(challenge(fqdn, key, cfg)))
+			}()
+		}
+		wg.Wait()
+		assert.ElementsMatch(t, keys, mock.contents("example.com", "_acme-challenge.example.com"))
+
+		for _, key := range keys[:5] {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, solver.CleanUp(challenge(fqdn, key, cfg)))
+			}()
+		}
+		wg.Wait()
+		assert.ElementsMatch(t, keys[5:], mock.contents("example.com", "_acme-challenge.example.com"))
+	})
+
+	t.Run("cleanup merges a concurrent present", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		replicaA, replicaB := solverWithMock(mock), solverWithMock(mock)
+		require.NoError(t, replicaA.Present(challenge(fqdn, "token-A", cfg)))
+		require.NoError(t, replicaA.Present(challenge(fqdn, "token-X", cfg)))
+
+		mock.beforeIfMatch = func() {
+			assert.NoError(t, replicaB.Present(challenge(fqdn, "token-B", cfg)))
+		}
+		require.NoError(t, replicaA.CleanUp(challenge(fqdn, "token-A", cfg)))
+		assert.ElementsMatch(t, []string{"token-X", "token-B"},
+			mock.contents("example.com", "_acme-challenge.example.com"))
+	})
+
+	t.Run("deleting the last record keeps a concurrent present", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		replicaA, replicaB := solverWithMock(mock), solverWithMock(mock)
+		require.NoError(t, replicaA.Present(challenge(fqdn, "token-A", cfg)))
+
+		mock.beforeIfMatch = func() {
+			assert.NoError(t, replicaB.Present(challenge(fqdn, "token-B", cfg)))
+		}
+		require.NoError(t, replicaA.CleanUp(challenge(fqdn, "token-A", cfg)))
+		assert.Equal(t, []string{"token-B"}, mock.contents("example.com", "_acme-challenge.example.com"))
+		assert.Zero(t, mock.deletes)
+	})
+}
+
+// laggingAPI is an eventually consistent mockSDK: the first dropped
+// conditional writes succeed without being applied, and the first conflicts
+// ones are refused with 409 Conflict.
+type laggingAPI struct {
+	*mockSDK
+	dropped   int
+	conflicts int
+}
+
+func (l *laggingAPI) lag() (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conflicts > 0 {
+		l.conflicts--
+		return true, dnssdk.APIError{StatusCode: http.StatusConflict, Message: "change in progress"}
+	}
+	if l.dropped > 0 {
+		l.dropped--
+		return true, nil
+	}
+	return false, nil
+}
+
+func (l *laggingAPI) UpdateRRSetIfMatch(ctx context.Context, zone, name, recordType string,
+	record dnssdk.RRSet, version rrsetVersion) error {
+	if lagged, err := l.lag(); lagged {
+		return err
+	}
+	return l.mockSDK.UpdateRRSetIfMatch(ctx, zone, name, recordType, record, version)
+}
+
+func (l *laggingAPI) DeleteRRSetIfMatch(ctx context.Context, zone, name, recordType string, version rrsetVersion) error {
+	if lagged, err := l.lag(); lagged {
+		return err
+	}
+	return l.mockSDK.DeleteRRSetIfMatch(ctx, zone, name, recordType, version)
+}
+
+func TestCleanUpEventualConsistency(t *testing.T) {
+	setForTest(t, &cleanupRecheckInterval, time.Millisecond)
+	const fqdn = "_acme-challenge.example.com."
+	cfg := `{"apiToken":"t","strictCleanup":true}`
+
+	testCases := []struct {
+		desc      string
+		keys      []string
+		dropped   int
+		conflicts int
+		remaining []string
+		err       string
+	}{
+		{desc: "dropped delete", keys: []string{"token-A"}, dropped: 2},
+		{desc: "dropped update", keys: []string{"token-A", "token-B"}, dropped: 1, remaining: []string{"token-B"}},
+		{desc: "conflicts", keys: []string{"token-A"}, conflicts: 3},
+		{desc: "never applied", keys: []string{"token-A"}, dropped: conflictAttempts, remaining: []string{"token-A"},
+			err: "challenge record still in rrset _acme-challenge.example.com TXT after 5 attempts"},
+	}
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			mock := newMockSDK("example.com")
+			for _, key := range test.keys {
+				require.NoError(t, solverWithMock(mock).Present(challenge(fqdn, key, cfg)))
+			}
+			api := &laggingAPI{mockSDK: mock, dropped: test.dropped, conflicts: test.conflicts}
+			solver := &gcoreDNSProviderSolver{
+				newSDK: func(gcoreDNSProviderConfig, string) (dnsAPI, error) { return api, nil },
+			}
+			err := solver.CleanUp(challenge(fqdn, "token-A", cfg))
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.remaining, mock.contents("example.com", "_acme-challenge.example.com"))
+		})
+	}
+
+	t.Run("rrset deleted meanwhile", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		solver := solverWithMock(mock)
+		require.NoError(t, solver.Present(challenge(fqdn, "token-A", cfg)))
+		require.NoError(t, solver.Present(challenge(fqdn, "token-B", cfg)))
+		api := &notFoundOnWriteAPI{mockSDK: mock}
+		solver.newSDK = func(gcoreDNSProviderConfig, string) (dnsAPI, error) { return api, nil }
+		require.NoError(t, solver.CleanUp(challenge(fqdn, "token-A", cfg)))
+	})
+}
+
+// notFoundOnWriteAPI answers conditional updates with 404 Not Found, as if
+// the RRSet had been deleted since it was read.
+type notFoundOnWriteAPI struct {
+	*mockSDK
+}
+
+func (notFoundOnWriteAPI) UpdateRRSetIfMatch(context.Context, string, string, string, dnssdk.RRSet, rrsetVersion) error {
+	return dnssdk.APIError{StatusCode: http.StatusNotFound, Message: "rrset not found"}
+}
+
+func TestPresentZoneTTLOverrides(t *testing.T) {
+	mock := newMockSDK("example.com", "example.org")
+	solver := solverWithMock(mock)
+	cfg := `{"apiToken":"t","ttl":120,"zoneTTLOverrides":{"example.org.":600}}`
+
+	require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "token-A", cfg)))
+	require.NoError(t, solver.Present(challenge("_acme-challenge.example.org.", "token-A", cfg)))
+
+	assert.Equal(t, 120, mock.zones["example.com"].rrsets["_acme-challenge.example.com"][txtType].ttl)
+	assert.Equal(t, 600, mock.zones["example.org"].rrsets["_acme-challenge.example.org"][txtType].ttl)
+}
+
+func TestPresentRecordNameSuffix(t *testing.T) {
+	mock := newMockSDK("example.com", "delegated.example.net")
+	solver := solverWithMock(mock)
+	cfg := `{"apiToken":"t","recordNameSuffix":"delegated.example.net."}`
+
+	require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "token-A", cfg)))
+	assert.Equal(t, []string{"token-A"},
+		mock.contents("delegated.example.net", "_acme-challenge.example.com.delegated.example.net"))
+	assert.Empty(t, mock.zones["example.com"].rrsets)
+
+	require.NoError(t, solver.CleanUp(challenge("_acme-challenge.example.com.", "token-A", cfg)))
+	assert.Empty(t, mock.contents("delegated.example.net", "_acme-challenge.example.com.delegated.example.net"))
+}
+
+func TestPresentZoneAlias(t *testing.T) {
+	mock := newMockSDK("example.com")
+	// The API answers lookups of the alias zone with the canonical zone.
+	mock.zones["example-alias.com"] = mock.zones["example.com"]
+	solver := solverWithMock(mock)
+
+	cfg := `{"apiToken":"t","resolveZoneAliases":true}`
+	require.NoError(t, solver.Present(challenge("_acme-challenge.www.example-alias.com.", "token-A", cfg)))
+	assert.Equal(t, []string{"token-A"}, mock.contents("example.com", "_acme-challenge.www.example.com"))
+	require.NoError(t, solver.CleanUp(challenge("_acme-challenge.www.example-alias.com.", "token-A", cfg)))
+	assert.Empty(t, mock.zones["example.com"].rrsets["_acme-challenge.www.example.com"])
+
+	// Without the flag the name is kept as is.
+	require.NoError(t, solver.Present(challenge("_acme-challenge.www.example-alias.com.", "token-B", `{"apiToken":"t"}`)))
+	assert.Equal(t, []string{"token-B"}, mock.contents("example.com", "_acme-challenge.www.example-alias.com"))
+}
+
+func TestLogRecord(t *testing.T) {
+	var lines []string
+	mock := newMockSDK("example.com")
+	solver := solverWithMock(mock)
+	solver.log = funcr.New(func(prefix, args string) {
+		lines = append(lines, prefix+args)
+	}, funcr.Options{})
+
+	key := "LHDhK3oGRvkiefQnx7OOczTY5Tic_xZ6HcMOc_gmtoM"
+	ch := challenge("_acme-challenge.example.com.", key, `{"apiToken":"t"}`)
+	require.NoError(t, solver.Present(ch))
+	require.Len(t, lines, 1)
+	requestID := regexp.MustCompile(`"requestID"="[0-9a-f-]{36}" `)
+	assert.Regexp(t, requestID, lines[0])
+	assert.Equal(t, `"level"=0 "msg"="presented" "fqdn"="_acme-challenge.example.com." "resolvedZone"="" "uid"="" `+
+		`"zone"="example.com" "recordName"="_acme-challenge.example.com" "contentHash"="`+contentHash(key)+`"`,
+		requestID.ReplaceAllString(lines[0], ""))
+
+	require.NoError(t, solver.CleanUp(ch))
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[1], `"level"=0 "msg"="cleaned up" `), lines[1])
+
+	for _, line := range lines {
+		assert.NotContains(t, line, key)
+		assert.NotContains(t, line, "\n")
+	}
+	assert.Len(t, contentHash(key), 12)
+	assert.NotEqual(t, contentHash(key), contentHash(key+"x"))
+}
+
+func TestPresentZoneID(t *testing.T) {
+	mock := newMockSDK("example.com", "sub.example.com")
+	mock.zones["example.com"].id = 42
+	solver := solverWithMock(mock)
+	solver.zones = newCache[zoneCacheKey, zoneDetails](time.Minute, 10)
+
+	cfg := `{"apiToken":"t","zoneID":42}`
+	require.NoError(t, solver.Present(challenge("_acme-challenge.sub.example.com.", "token-A", cfg)))
+	require.NoError(t, solver.CleanUp(challenge("_acme-challenge.sub.example.com.", "token-A", cfg)))
+	require.NoError(t, solver.Present(challenge("_acme-challenge.www.example.com.", "token-B", cfg)))
+	assert.Equal(t, []string{"token-B"}, mock.contents("example.com", "_acme-challenge.www.example.com"))
+	assert.Zero(t, mock.zoneLookups)
+	assert.Equal(t, 1, mock.listCalls)
+
+	err := solver.Present(challenge("_acme-challenge.example.org.", "token-C", cfg))
+	assert.ErrorContains(t, err, "not in zone example.com")
+	err = solver.Present(challenge("_acme-challenge.example.com.", "token-C", `{"apiToken":"t","zoneID":7}`))
+	assert.ErrorContains(t, err, "zone with id 7 not found")
+
+	_, err = loadConfig(&extapi.JSON{Raw: []byte(`{"zoneID":"example.com"}`)})
+	assert.Error(t, err)
+	_, err = loadConfig(&extapi.JSON{Raw: []byte(`{"zoneID":-1}`)})
+	assert.Error(t, err)
+}
+
+func TestPresentZoneName(t *testing.T) {
+	mock := newMockSDK("example.com", "sub.example.com")
+	solver := solverWithMock(mock)
+
+	cfg := `{"apiToken":"t","zoneName":"example.com."}`
+	require.NoError(t, solver.Present(challenge("_acme-challenge.www.sub.example.com.", "token-A", cfg)))
+	assert.Equal(t, []string{"token-A"}, mock.contents("example.com", "_acme-challenge.www.sub.example.com"))
+	assert.Nil(t, mock.contents("sub.example.com", "_acme-challenge.www.sub.example.com"))
+	require.NoError(t, solver.CleanUp(challenge("_acme-challenge.www.sub.example.com.", "token-A", cfg)))
+	assert.Nil(t, mock.contents("example.com", "_acme-challenge.www.sub.example.com"))
+	assert.Equal(t, 2, mock.zoneLookups, "only the pinned zone is looked up")
+
+	err := solver.Present(challenge("_acme-challenge.example.org.", "token-B", cfg))
+	assert.ErrorContains(t, err, "record _acme-challenge.example.org is not in zone example.com (zoneName)")
+	err = solver.Present(challenge("_acme-challenge.www.example.net.", "token-B", `{"apiToken":"t","zoneName":"example.net"}`))
+	assert.ErrorIs(t, err, errZoneNotFound)
+
+	assert.ErrorContains(t, gcoreDNSProviderConfig{ZoneID: 1, ZoneName: "example.com"}.Validate(), "mutually exclusive")
+}
+
+func Test_isRRSetExists(t *testing.T) {
+	assert.True(t, isRRSetExists(dnssdk.APIError{StatusCode: http.StatusConflict}))
+	assert.True(t, isRRSetExists(fmt.Errorf("wrapped: %w",
+		dnssdk.APIError{StatusCode: http.StatusBadRequest, Message: "rrset already exists"})))
+	assert.False(t, isRRSetExists(dnssdk.APIError{StatusCode: http.StatusBadRequest, Message: "invalid ttl"}))
+	assert.False(t, isRRSetExists(dnssdk.APIError{StatusCode: http.StatusInternalServerError}))
+	assert.False(t, isRRSetExists(fmt.Errorf("send request: connection refused")))
+}
+
+func TestCleanUpStrict(t *testing.T) {
+	const fqdn = "_acme-challenge.example.com."
+	testCases := []struct {
+		desc    string
+		strict  bool
+		present []string
+		errMsg  string
+	}{
+		{
+			desc:    "lenient missing record",
+			present: []string{"token-B"},
+		},
+		{
+			desc: "lenient missing rrset",
+		},
+		{
+			desc:    "strict missing record",
+			strict:  true,
+			present: []string{"token-B"},
+			errMsg:  "challenge record not found",
+		},
+		{
+			desc:   "strict missing rrset",
+			strict: true,
+			errMsg: "rrset _acme-challenge.example.com TXT not found",
+		},
+		{
+			desc:    "strict existing record",
+			strict:  true,
+			present: []string{"token-A", "token-B"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			mock := newMockSDK("example.com")
+			solver := solverWithMock(mock)
+			for _, key := range test.present {
+				require.NoError(t, solver.Present(challenge(fqdn, key, `{"apiToken":"t"}`)))
+			}
+
+			cfg := fmt.Sprintf(`{"apiToken":"t","strictCleanup":%t}`, test.strict)
+			err := solver.CleanUp(challenge(fqdn, "token-A", cfg))
+			if test.errMsg != "" {
+				assert.ErrorContains(t, err, test.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotContains(t, mock.contents("example.com", "_acme-challenge.example.com"), "token-A")
+		})
+	}
+}
+
+func TestCleanUpNeverPresented(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		zones []string
+		fqdn  string
+	}{
+		{desc: "absent zone", zones: []string{"example.org"}, fqdn: "_acme-challenge.example.com."},
+		{desc: "absent rrset", zones: []string{"example.com"}, fqdn: "_acme-challenge.example.com."},
+		{desc: "absent record", zones: []string{"example.com"}, fqdn: "_acme-challenge.www.example.com."},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			mock := newMockSDK(test.zones...)
+			solver := solverWithMock(mock)
+			if test.desc == "absent record" {
+				require.NoError(t, solver.Present(challenge(test.fqdn, "token-B", `{"apiToken":"t"}`)))
+			}
+			writes, creates := mock.writes, mock.creates
+
+			require.NoError(t, solver.CleanUp(challenge(test.fqdn, "token-A", `{"apiToken":"t"}`)))
+			assert.Equal(t, writes, mock.writes)
+			assert.Equal(t, creates, mock.creates)
+			assert.Zero(t, mock.deletes)
+		})
+	}
+
+	t.Run("zone lookup failure", func(t *testing.T) {
+		t.Parallel()
+		solver := &gcoreDNSProviderSolver{
+			newSDK: func(gcoreDNSProviderConfig, string) (dnsAPI, error) {
+				return &flakyAPI{mockSDK: newMockSDK("example.com"), failures: 100,
+					err: dnssdk.APIError{StatusCode: http.StatusForbidden, Message: "forbidden"}}, nil
+			},
+		}
+		err := solver.CleanUp(challenge("_acme-challenge.example.com.", "token-A",
+			`{"apiToken":"t","zoneDiscovery":"probe"}`))
+		assert.ErrorContains(t, err, "forbidden")
+	})
+
+	t.Run("rrset lookup failure mentioning 404", func(t *testing.T) {
+		t.Parallel()
+		mock := newMockSDK("example.com")
+		solver := &gcoreDNSProviderSolver{
+			newSDK: func(gcoreDNSProviderConfig, string) (dnsAPI, error) {
+				return &rrsetErrorAPI{mockSDK: mock,
+					err: dnssdk.APIError{StatusCode: http.StatusBadRequest, Message: "ttl 404 is not allowed"}}, nil
+			},
+		}
+		err := solver.CleanUp(challenge("_acme-challenge.example.com.", "token-A", `{"apiToken":"t"}`))
+		assert.ErrorContains(t, err, "fetch rrset: ")
+	})
+}
+
+// rrsetErrorAPI fails every RRSet lookup with err.
+type rrsetErrorAPI struct {
+	*mockSDK
+	err error
+}
+
+func (r *rrsetErrorAPI) RRSetWithVersion(context.Context, string, string, string) (dnssdk.RRSet, rrsetVersion,
+	error) {
+	return dnssdk.RRSet{}, rrsetVersion{}, r.err
+}
+
+func TestCleanUpMatchMode(t *testing.T) {
+	const fqdn = "_acme-challenge.example.com."
+	const key = "LHDhK3oGRvkiefQnx7OOczTY5Tic_xZ6HcMOc_gmtoM"
+	stored := []string{key, `"` + key + `"`, key + "-other"}
+	testCases := []struct {
+		mode string
+		want []string
+	}{
+		{mode: "exact", want: []string{`"` + key + `"`, key + "-other"}},
+		{mode: "normalized", want: []string{key + "-other"}},
+		{mode: "prefix", want: nil},
+	}
+	for _, test := range testCases {
+		test := test
+		t.Run(test.mode, func(t *testing.T) {
+			t.Parallel()
+			mock := newMockSDK("example.com")
+			solver := solverWithMock(mock)
+			for _, content := range stored {
+				require.NoError(t, solver.Present(challenge(fqdn, content, `{"apiToken":"t"}`)))
+			}
+			cfg := fmt.Sprintf(`{"apiToken":"t","cleanupMatchMode":%q}`, test.mode)
+			require.NoError(t, solver.CleanUp(challenge(fqdn, key, cfg)))
+			assert.ElementsMatch(t, test.want, mock.contents("example.com", "_acme-challenge.example.com"))
+		})
+	}
+}
+
+func TestCleanUpDelay(t *testing.T) {
+	const fqdn = "_acme-challenge.example.com."
+	mock := newMockSDK("example.com")
+	solver := solverWithMock(mock)
+	require.NoError(t, solver.Present(challenge(fqdn, "token-A", `{"apiToken":"t"}`)))
+
+	start := time.Now()
+	require.NoError(t, solver.CleanUp(challenge(fqdn, "token-A", `{"apiToken":"t","cleanupDelay":1}`)))
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.Empty(t, mock.contents("example.com", "_acme-challenge.example.com"))
+}
+
+func TestSleepContext(t *testing.T) {
+	t.Run("waits", func(t *testing.T) {
+		start := time.Now()
+		require.NoError(t, sleepContext(context.Background(), 20*time.Millisecond))
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := sleepContext(ctx, time.Minute)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Minute)
+	})
+}
+
+func TestJitter(t *testing.T) {
+	assert.Zero(t, jitter(0))
+	assert.Zero(t, jitter(-time.Second))
+	for i := 0; i < 1000; i++ {
+		d := jitter(50 * time.Millisecond)
+		assert.GreaterOrEqual(t, d, time.Duration(0))
+		assert.LessOrEqual(t, d, 50*time.Millisecond)
+	}
+	assert.ErrorContains(t, gcoreDNSProviderConfig{SelfCheckJitter: -1}.Validate(), "selfCheckJitter")
+}
+
+func TestDetectZoneTagFilter(t *testing.T) {
+	mock := newMockSDK("example.com", "example.net", "sub.example.net", "example.org")
+	mock.zones["example.com"].meta = map[string]any{"cert-manager": "enabled"}
+	mock.zones["sub.example.net"].meta = map[string]any{"cert-manager": "enabled"}
+	mock.zones["example.org"].meta = map[string]any{"cert-manager": "disabled"}
+	solver := solverWithMock(mock)
+	cfg := gcoreDNSProviderConfig{ZoneTagFilter: map[string]string{"cert-manager": "enabled"}}
+
+	testCases := []struct {
+		desc     string
+		fqdn     string
+		cfg      gcoreDNSProviderConfig
+		expected string
+		errMsg   string
+	}{
+		{
+			desc:     "tagged zone",
+			fqdn:     "_acme-challenge.example.com",
+			cfg:      cfg,
+			expected: "example.com",
+		},
+		{
+			desc:     "untagged parent zone is skipped",
+			fqdn:     "_acme-challenge.www.sub.example.net",
+			cfg:      cfg,
+			expected: "sub.example.net",
+		},
+		{
+			desc:   "untagged zone",
+			fqdn:   "_acme-challenge.example.org",
+			cfg:    cfg,
+			errMsg: "zone example.org matches",
+		},
+		{
+			desc:     "no filter",
+			fqdn:     "_acme-challenge.example.org",
+			expected: "example.org",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := solver.detectZone(context.Background(), test.fqdn, mock, test.cfg)
+			if test.errMsg != "" {
+				assert.ErrorContains(t, err, test.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
 // Mock types for testing
 type mockSDK struct {
+	mu    sync.Mutex
 	zones map[string]*mockZone
+	// lossyWrites makes that many following writes drop their last record.
+	lossyWrites int
+	writes      int
+	zoneLookups int
+	creates     int
+	deletes     int
+	reads       int
+	// beforeIfMatch runs once right before the next conditional update.
+	beforeIfMatch func()
+	// listing is the zone list served by ZonesWithParam, the zones map's
+	// names when nil. ignoreNameFilter serves it unfiltered.
+	listing          []string
+	ignoreNameFilter bool
+	// maxPageSize caps the zone list pages below the requested limit, like
+	// an API enforcing its own maximum, when set.
+	maxPageSize int
+	listCalls   int
 }
 
 type mockZone struct {
-	name   string
-	rrsets map[string]map[string]*mockRRSet // fqdn -> type -> rrset
+	name        string
+	id          uint64
+	nameservers []string
+	meta        map[string]any
+	disabled    bool
+	dnssec      bool
+	rrsets      map[string]map[string]*mockRRSet // fqdn -> type -> rrset
 }
 
 type mockRRSet struct {
 	fqdn       string
 	recordType string
+	ttl        int
+	version    int
 	records    []mockRecord
 }
 
 type mockRecord struct {
 	content string
+	meta    map[string]any
+}
+
+func TestResolveToken(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metaV1.ObjectMeta{Name: "gcore-api-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("secret-token")},
+	}
+	solver := &gcoreDNSProviderSolver{client: fake.NewSimpleClientset(secret)}
+	secretRef := certmgrv1.SecretKeySelector{
+		LocalObjectReference: certmgrv1.LocalObjectReference{Name: "gcore-api-token"},
+		Key:                  "token",
+	}
+
+	testCases := []struct {
+		desc     string
+		cfg      gcoreDNSProviderConfig
+		ambient  bool
+		env      string
+		expected string
+		wantErr  bool
+	}{
+		{
+			desc:     "explicit token wins over env",
+			cfg:      gcoreDNSProviderConfig{ApiToken: "config-token"},
+			ambient:  true,
+			env:      "env-token",
+			expected: "config-token",
+		},
+		{
+			desc:     "secret ref wins over env",
+			cfg:      gcoreDNSProviderConfig{APIKeySecretRef: secretRef},
+			ambient:  true,
+			env:      "env-token",
+			expected: "secret-token",
+		},
+		{
+			desc:     "env token with ambient credentials",
+			ambient:  true,
+			env:      "env-token",
+			expected: "env-token",
+		},
+		{
+			desc:    "env token without ambient credentials",
+			env:     "env-token",
+			wantErr: true,
+		},
+		{
+			desc:    "ambient credentials without env token",
+			ambient: true,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Setenv(apiTokenEnvVar, test.env)
+			ch := &v1alpha1.ChallengeRequest{
+				ResourceNamespace:       "default",
+				AllowAmbientCredentials: test.ambient,
+			}
+
+			got, err := solver.resolveToken(t.Context(), test.cfg, ch)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestResolveTokenSecretRef(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{Name: "gcore", Namespace: "default"},
+			Data:       map[string][]byte{"token": []byte("default-token")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{Name: "gcore", Namespace: "dns"},
+			Data:       map[string][]byte{"token": []byte("dns-token")},
+		},
+	)
+	ch := &v1alpha1.ChallengeRequest{ResourceNamespace: "default"}
+	testCases := []struct {
+		desc       string
+		ref        secretRef
+		crossNS    bool
+		expected   string
+		errMessage string
+	}{
+		{desc: "issuer namespace", ref: secretRef{Name: "gcore", Key: "token"}, expected: "default-token"},
+		{desc: "same namespace", ref: secretRef{Name: "gcore", Namespace: "default", Key: "token"}, expected: "default-token"},
+		{desc: "other namespace allowed", ref: secretRef{Name: "gcore", Namespace: "dns", Key: "token"},
+			crossNS: true, expected: "dns-token"},
+		{desc: "other namespace denied", ref: secretRef{Name: "gcore", Namespace: "dns", Key: "token"},
+			errMessage: `apiTokenSecretRef namespace "dns" differs from the issuer's namespace "default"`},
+		{desc: "missing key", ref: secretRef{Name: "gcore", Key: "other"},
+			errMessage: `key other not found in secret "gcore/default"`},
+	}
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			solver := &gcoreDNSProviderSolver{client: client, allowCrossNamespaceSecrets: test.crossNS}
+			cfg := gcoreDNSProviderConfig{
+				APITokenSecretRef: test.ref,
+				APIKeySecretRef: certmgrv1.SecretKeySelector{
+					LocalObjectReference: certmgrv1.LocalObjectReference{Name: "unused"},
+					Key:                  "token",
+				},
+			}
+			got, err := solver.resolveToken(t.Context(), cfg, ch)
+			if test.errMessage != "" {
+				assert.ErrorContains(t, err, test.errMessage)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestExtractApiTokenFromSecretRetry(t *testing.T) {
+	setForTest(t, &secretRetryInterval, time.Millisecond)
+	cfg := gcoreDNSProviderConfig{APIKeySecretRef: certmgrv1.SecretKeySelector{
+		LocalObjectReference: certmgrv1.LocalObjectReference{Name: "gcore-api-token"},
+		Key:                  "token",
+	}}
+	ch := &v1alpha1.ChallengeRequest{ResourceNamespace: "default"}
+
+	t.Run("secret appears on second read", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		reads := 0
+		client.PrependReactor("get", "secrets", func(k8stesting.Action) (bool, runtime.Object, error) {
+			reads++
+			if reads == 1 {
+				return true, nil, apierrors.NewNotFound(corev1.Resource("secrets"), "gcore-api-token")
+			}
+			return true, &corev1.Secret{Data: map[string][]byte{"token": []byte("secret-token")}}, nil
+		})
+		solver := &gcoreDNSProviderSolver{client: client}
+
+		token, err := solver.resolveToken(t.Context(), cfg, ch)
+		require.NoError(t, err)
+		assert.Equal(t, "secret-token", token)
+		assert.Equal(t, 2, reads)
+	})
+
+	t.Run("secret never appears", func(t *testing.T) {
+		solver := &gcoreDNSProviderSolver{client: fake.NewSimpleClientset()}
+
+		_, err := solver.resolveToken(t.Context(), cfg, ch)
+		assert.EqualError(t, err, `secret "gcore-api-token" not found in namespace "default" after 3 attempts`)
+	})
+
+	t.Run("challenge ends while waiting", func(t *testing.T) {
+		setForTest(t, &secretRetryInterval, time.Hour)
+		solver := &gcoreDNSProviderSolver{client: fake.NewSimpleClientset()}
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := solver.resolveToken(ctx, cfg, ch)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestInitSDKAmbientCredentials(t *testing.T) {
+	t.Setenv(apiTokenEnvVar, "env-token")
+	solver := &gcoreDNSProviderSolver{}
+
+	_, _, err := solver.initSDK(t.Context(), &v1alpha1.ChallengeRequest{AllowAmbientCredentials: true})
+	assert.NoError(t, err)
+
+	_, _, err = solver.initSDK(t.Context(), &v1alpha1.ChallengeRequest{})
+	assert.ErrorIs(t, err, errNoConfig)
+
+	// Only issuers of the listed namespaces may use ambient credentials.
+	t.Setenv(ambientNSEnvVar, "cert-manager, dns")
+	solver.ambientNamespaces = ambientNamespacesFromEnv()
+	_, _, err = solver.initSDK(t.Context(), &v1alpha1.ChallengeRequest{AllowAmbientCredentials: true, ResourceNamespace: "dns"})
+	assert.NoError(t, err)
+	_, _, err = solver.initSDK(t.Context(), &v1alpha1.ChallengeRequest{AllowAmbientCredentials: true, ResourceNamespace: "team-a"})
+	assert.ErrorIs(t, err, errNoConfig)
+	assert.ErrorContains(t, err, `ambient credentials are not allowed for issuers in namespace "team-a"`)
+	_, err = solver.resolveToken(t.Context(), gcoreDNSProviderConfig{},
+		&v1alpha1.ChallengeRequest{AllowAmbientCredentials: true, ResourceNamespace: "team-a"})
+	assert.EqualError(t, err, `no api token configured and ambient credentials are not allowed for issuers `+
+		`in namespace "team-a", see GCORE_AMBIENT_CREDENTIALS_NAMESPACES`)
+}
+
+// newMockSDK returns a mock with empty zones of the given names.
+func newMockSDK(zones ...string) *mockSDK {
+	m := &mockSDK{zones: map[string]*mockZone{}}
+	for _, name := range zones {
+		m.zones[name] = &mockZone{name: name, rrsets: map[string]map[string]*mockRRSet{}}
+	}
+	return m
+}
+
+// solverWithMock returns a solver whose challenges are served by the mock.
+func solverWithMock(m *mockSDK) *gcoreDNSProviderSolver {
+	return &gcoreDNSProviderSolver{
+		newSDK: func(gcoreDNSProviderConfig, string) (dnsAPI, error) { return m, nil },
+	}
+}
+
+// rejectingAPI returns a handler of G-Core API calls that appends the header
+// of each request to seen and rejects it with "stop here", for tests of how
+// the requests are made rather than of their outcome.
+func rejectingAPI(header string, seen *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*seen = append(*seen, r.Header.Get(header))
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error":"stop here"}`))
+	}
+}
+
+// challenge returns a challenge request for fqdn/key with the given JSON config.
+func challenge(fqdn, key, cfgJSON string) *v1alpha1.ChallengeRequest {
+	return &v1alpha1.ChallengeRequest{
+		ResolvedFQDN: fqdn,
+		Key:          key,
+		Config:       &extapi.JSON{Raw: []byte(cfgJSON)},
+	}
+}
+
+// setForTest sets *p to v until t and its subtests are done.
+func setForTest[T any](t *testing.T, p *T, v T) {
+	t.Helper()
+	old := *p
+	*p = v
+	t.Cleanup(func() { *p = old })
+}
+
+// contents returns the record contents stored for fqdn, nil if there is no RRSet.
+func (m *mockSDK) contents(zone, fqdn string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rrset, ok := m.zones[zone].rrsets[fqdn][txtType]
+	if !ok {
+		return nil
+	}
+	res := make([]string, 0, len(rrset.records))
+	for _, record := range rrset.records {
+		res = append(res, record.content)
+	}
+	return res
+}
+
+func mockNotFound(what string) error {
+	return dnssdk.APIError{StatusCode: http.StatusNotFound, Message: what + " not found"}
+}
+
+func (m *mockSDK) Zone(_ context.Context, name string) (dnssdk.Zone, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.zoneLookups++
+	zone, ok := m.zones[name]
+	if !ok {
+		return dnssdk.Zone{}, fmt.Errorf("get zone %s: %w", name, mockNotFound("zone"))
+	}
+	res := dnssdk.Zone{Name: zone.name}
+	for name, rrsets := range zone.rrsets {
+		for recordType := range rrsets {
+			res.Records = append(res.Records, dnssdk.ZoneRecord{Name: name, Type: recordType})
+		}
+	}
+	return res, nil
+}
+
+func (m *mockSDK) RRSet(_ context.Context, zone, name, recordType string) (dnssdk.RRSet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reads++
+	z, ok := m.zones[zone]
+	if !ok {
+		return dnssdk.RRSet{}, mockNotFound("zone")
+	}
+	rrset, ok := z.rrsets[name][recordType]
+	if !ok {
+		return dnssdk.RRSet{}, mockNotFound("rrset")
+	}
+	res := dnssdk.RRSet{Type: recordType, TTL: rrset.ttl}
+	for _, record := range rrset.records {
+		res.Records = append(res.Records,
+			dnssdk.ResourceRecord{Content: []any{record.content}, Meta: record.meta, Enabled: true})
+	}
+	return res, nil
+}
+
+func (m *mockSDK) CreateRRSet(ctx context.Context, zone, name, recordType string, record dnssdk.RRSet) error {
+	m.mu.Lock()
+	m.creates++
+	exists := false
+	if z, ok := m.zones[zone]; ok {
+		_, exists = z.rrsets[name][recordType]
+	}
+	m.mu.Unlock()
+	if exists {
+		return dnssdk.APIError{StatusCode: http.StatusConflict, Message: "rrset already exists"}
+	}
+	return m.UpdateRRSet(ctx, zone, name, recordType, record)
+}
+
+func (m *mockSDK) UpdateRRSet(_ context.Context, zone, name, recordType string, record dnssdk.RRSet) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	z, ok := m.zones[zone]
+	if !ok {
+		return mockNotFound("zone")
+	}
+	if z.disabled {
+		return dnssdk.APIError{StatusCode: http.StatusForbidden, Message: "forbidden"}
+	}
+	m.writes++
+	records := record.Records
+	if m.lossyWrites > 0 && len(records) > 0 {
+		m.lossyWrites--
+		records = records[:len(records)-1]
+	}
+	rrset := &mockRRSet{fqdn: name, recordType: recordType, ttl: record.TTL}
+	if old, ok := z.rrsets[name][recordType]; ok {
+		rrset.version = old.version + 1
+	}
+	for _, r := range records {
+		rrset.records = append(rrset.records, mockRecord{content: r.ContentToString(), meta: r.Meta})
+	}
+	if z.rrsets[name] == nil {
+		z.rrsets[name] = map[string]*mockRRSet{}
+	}
+	z.rrsets[name][recordType] = rrset
+	return nil
+}
+
+func (m *mockSDK) DeleteRRSet(_ context.Context, zone, name, recordType string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deletes++
+	if z, ok := m.zones[zone]; ok {
+		delete(z.rrsets[name], recordType)
+	}
+	return nil
+}
+
+func (m *mockSDK) CreateZone(_ context.Context, name string) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.zones[name]; ok {
+		return 0, dnssdk.APIError{StatusCode: http.StatusBadRequest, Message: "zone already exists"}
+	}
+	m.zones[name] = &mockZone{name: name, id: uint64(len(m.zones) + 1), rrsets: map[string]map[string]*mockRRSet{}}
+	return m.zones[name].id, nil
+}
+
+func (m *mockSDK) ZoneNameservers(_ context.Context, name string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zone, ok := m.zones[name]
+	if !ok {
+		return nil, mockNotFound("zone")
+	}
+	return zone.nameservers, nil
+}
+
+func (m *mockSDK) ZoneDetails(_ context.Context, name string) (zoneDetails, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.zoneLookups++
+	zone, ok := m.zones[name]
+	if !ok {
+		return zoneDetails{}, mockNotFound("zone")
+	}
+	enabled := !zone.disabled
+	return zoneDetails{Name: zone.name, Meta: zone.meta, Enabled: &enabled, DNSSECEnabled: zone.dnssec}, nil
+}
+
+func (m *mockSDK) EnableZone(_ context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zone, ok := m.zones[name]
+	if !ok {
+		return mockNotFound("zone")
+	}
+	zone.disabled = false
+	return nil
+}
+
+func (m *mockSDK) RRSetWithVersion(ctx context.Context, zone, name, recordType string) (dnssdk.RRSet, rrsetVersion, error) {
+	rrset, err := m.RRSet(ctx, zone, name, recordType)
+	if err != nil {
+		return rrset, rrsetVersion{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return rrset, rrsetVersion{etag: fmt.Sprint(m.zones[zone].rrsets[name][recordType].version)}, nil
+}
+
+func (m *mockSDK) UpdateRRSetIfMatch(ctx context.Context, zone, name, recordType string,
+	record dnssdk.RRSet, version rrsetVersion) error {
+	if err := m.checkVersion(zone, name, recordType, version); err != nil {
+		return err
+	}
+	return m.UpdateRRSet(ctx, zone, name, recordType, record)
+}
+
+// checkVersion runs beforeIfMatch and fails with 412 unless version is the
+// current one of the RRSet or has no ETag.
+func (m *mockSDK) checkVersion(zone, name, recordType string, version rrsetVersion) error {
+	m.mu.Lock()
+	hook := m.beforeIfMatch
+	m.beforeIfMatch = nil
+	m.mu.Unlock()
+	if hook != nil {
+		hook()
+	}
+	m.mu.Lock()
+	current := ""
+	if rrset, ok := m.zones[zone].rrsets[name][recordType]; ok {
+		current = fmt.Sprint(rrset.version)
+	}
+	m.mu.Unlock()
+	if version.etag != "" && version.etag != current {
+		return dnssdk.APIError{StatusCode: http.StatusPreconditionFailed, Message: "rrset was modified"}
+	}
+	return nil
+}
+
+func (m *mockSDK) DeleteRRSetIfMatch(ctx context.Context, zone, name, recordType string, version rrsetVersion) error {
+	if err := m.checkVersion(zone, name, recordType, version); err != nil {
+		return err
+	}
+	return m.DeleteRRSet(ctx, zone, name, recordType)
+}
+
+func (m *mockSDK) ZonesWithParam(_ context.Context, param dnssdk.ZonesParam) (dnssdk.ListZones, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listCalls++
+	listing := m.listing
+	if listing == nil {
+		for name := range m.zones {
+			listing = append(listing, name)
+		}
+		sort.Strings(listing)
+	}
+	if len(param.Name) > 0 && !m.ignoreNameFilter {
+		var filtered []string
+		for _, name := range listing {
+			if slices.Contains(param.Name, name) {
+				filtered = append(filtered, name)
+			}
+		}
+		listing = filtered
+	}
+	if len(param.ID) > 0 {
+		var filtered []string
+		for _, name := range listing {
+			if slices.Contains(param.ID, m.zones[name].id) {
+				filtered = append(filtered, name)
+			}
+		}
+		listing = filtered
+	}
+	res := dnssdk.ListZones{TotalAmount: len(listing)}
+	start := min(int(param.Offset), len(listing))
+	limit := int(param.Limit)
+	if m.maxPageSize > 0 {
+		limit = min(limit, m.maxPageSize)
+	}
+	end := min(start+limit, len(listing))
+	for _, name := range listing[start:end] {
+		res.Zones = append(res.Zones, dnssdk.Zone{Name: name})
+	}
+	return res, nil
 }
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// webhookMetrics are the Prometheus metrics served on /metrics of the admin
+// server. A nil webhookMetrics records nothing.
+type webhookMetrics struct {
+	registry *prometheus.Registry
+
+	apiRequests        *prometheus.CounterVec
+	apiRequestDuration *prometheus.HistogramVec
+	challenges         *prometheus.CounterVec
+	challengeDuration  *prometheus.HistogramVec
+	presents           *prometheus.CounterVec
+	cleanups           *prometheus.CounterVec
+	errors             *prometheus.CounterVec
+	quotaLimit         *prometheus.GaugeVec
+	quotaRemaining     *prometheus.GaugeVec
+	quotaReset         *prometheus.GaugeVec
+}
+
+func newWebhookMetrics() *webhookMetrics {
+	m := &webhookMetrics{
+		registry: prometheus.NewRegistry(),
+		apiRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gcore_webhook_api_requests_total",
+			Help: "G-Core API requests by HTTP method and status code, including retries.",
+		}, []string{"method", "code"}),
+		apiRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gcore_webhook_api_request_duration_seconds",
+			Help:    "Duration of G-Core API requests by HTTP method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+		challenges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gcore_webhook_challenges_total",
+			Help: "Present and CleanUp calls by operation and result, success or the class of the error.",
+		}, []string{"operation", "result"}),
+		challengeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "gcore_webhook_challenge_duration_seconds",
+			Help: "Duration of Present and CleanUp calls by operation, including propagation waits.",
+			// Presents wait for propagation, so they take up to minutes.
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+		}, []string{"operation"}),
+		presents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gcore_webhook_present_total",
+			Help: "Present calls by the zone cert-manager resolved for the record.",
+		}, []string{"zone"}),
+		cleanups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gcore_webhook_cleanup_total",
+			Help: "CleanUp calls by the zone cert-manager resolved for the record.",
+		}, []string{"zone"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gcore_webhook_errors_total",
+			Help: "Failed Present and CleanUp calls by operation, resolved zone and error class.",
+		}, []string{"operation", "zone", "class"}),
+		quotaLimit: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "gcore_webhook_api_quota_limit",
+			Help: "Requests the G-Core API allows per window, by account, as of the last response reporting it.",
+		}, []string{"account"}),
+		quotaRemaining: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "gcore_webhook_api_quota_remaining",
+			Help: "Requests left in the G-Core API's window, by account, as of the last response reporting it.",
+		}, []string{"account"}),
+		quotaReset: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "gcore_webhook_api_quota_reset_timestamp_seconds",
+			Help: "Unix time the G-Core API's window resets at, by account, as of the last response reporting it.",
+		}, []string{"account"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.apiRequests, m.apiRequestDuration, m.challenges, m.challengeDuration,
+		m.presents, m.cleanups, m.errors, m.quotaLimit, m.quotaRemaining, m.quotaReset,
+	)
+	return m
+}
+
+// instrument counts and times the API requests made through base, and
+// records the quota the responses report for account. Requests failing
+// without a response are counted with code "error".
+func (m *webhookMetrics) instrument(base http.RoundTripper, account string) http.RoundTripper {
+	if m == nil {
+		return base
+	}
+	return &metricsTransport{base: base, metrics: m, account: account}
+}
+
+type metricsTransport struct {
+	base    http.RoundTripper
+	metrics *webhookMetrics
+	account string
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	t.metrics.apiRequestDuration.WithLabelValues(req.Method).Observe(time.Since(start).Seconds())
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+		t.metrics.observeQuota(t.account, resp.Header, time.Now())
+	}
+	t.metrics.apiRequests.WithLabelValues(req.Method, code).Inc()
+	return resp, err
+}
+
+// observeQuota records the rate limit headers of a response, in the
+// X-RateLimit-* or the RateLimit-* form. Missing headers leave the gauges
+// as they were.
+func (m *webhookMetrics) observeQuota(account string, header http.Header, now time.Time) {
+	if v, ok := rateLimitHeader(header, "Limit"); ok {
+		m.quotaLimit.WithLabelValues(account).Set(v)
+	}
+	if v, ok := rateLimitHeader(header, "Remaining"); ok {
+		m.quotaRemaining.WithLabelValues(account).Set(v)
+	}
+	if v, ok := rateLimitHeader(header, "Reset"); ok {
+		// The reset is either the seconds left in the window or, past a
+		// billion, a Unix time.
+		if v < 1e9 {
+			v += float64(now.Unix())
+		}
+		m.quotaReset.WithLabelValues(account).Set(v)
+	}
+}
+
+// rateLimitHeader returns the value of the X-RateLimit-<field> or
+// RateLimit-<field> header. Only the first number counts in values with
+// several limits or parameters, such as "100, 1000;w=3600".
+func rateLimitHeader(header http.Header, field string) (float64, bool) {
+	value := header.Get("X-RateLimit-" + field)
+	if value == "" {
+		value = header.Get("RateLimit-" + field)
+	}
+	value, _, _ = strings.Cut(value, ",")
+	value, _, _ = strings.Cut(value, ";")
+	v, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return float64(v), true
+}
+
+// observeChallenge records the outcome of a Present or CleanUp call of ch
+// started at start.
+func (m *webhookMetrics) observeChallenge(op string, ch *v1alpha1.ChallengeRequest, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	result := challengeResult(err)
+	m.challengeDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	m.challenges.WithLabelValues(op, result).Inc()
+	zone := strings.ToLower(strings.Trim(ch.ResolvedZone, "."))
+	switch op {
+	case "present":
+		m.presents.WithLabelValues(zone).Inc()
+	case "cleanup":
+		m.cleanups.WithLabelValues(zone).Inc()
+	}
+	if err != nil {
+		m.errors.WithLabelValues(op, zone, result).Inc()
+	}
+}
+
+// challengeResult is the result label of a challenge that ended with err:
+// "success", the class of the error or "error" for unclassified ones.
+func challengeResult(err error) string {
+	if err == nil {
+		return "success"
+	}
+	if class := errorClass(err); class != nil {
+		return classLabels[class]
+	}
+	return "error"
+}
+
+// classLabels are the metric labels of the error classes.
+var classLabels = map[error]string{
+	errAuth:           "auth",
+	errRateLimited:    "rate_limited",
+	errAPIUnavailable: "api_unavailable",
+	errZoneNotFound:   "zone_not_found",
+	errZoneDisabled:   "zone_disabled",
+}
+
+// observeCaches exports the lookup counters and sizes stats returns for
+// each cache, read when the metrics are scraped.
+func (m *webhookMetrics) observeCaches(stats func() map[string]cacheStats) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(&cacheCollector{stats: stats})
+}
+
+var (
+	cacheHitsDesc = prometheus.NewDesc("gcore_webhook_cache_hits_total",
+		"Lookups answered from the cache, by cache.", []string{"cache"}, nil)
+	cacheMissesDesc = prometheus.NewDesc("gcore_webhook_cache_misses_total",
+		"Lookups not found in the cache or expired there, by cache.", []string{"cache"}, nil)
+	cacheEvictionsDesc = prometheus.NewDesc("gcore_webhook_cache_evictions_total",
+		"Entries evicted from a full cache, by cache.", []string{"cache"}, nil)
+	cacheEntriesDesc = prometheus.NewDesc("gcore_webhook_cache_entries",
+		"Entries held by the cache, including expired ones not dropped yet, by cache.", []string{"cache"}, nil)
+)
+
+// cacheCollector reports the cacheStats of the solver's caches.
+type cacheCollector struct {
+	stats func() map[string]cacheStats
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheEvictionsDesc
+	ch <- cacheEntriesDesc
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, stats := range c.stats() {
+		ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(stats.Hits), name)
+		ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(stats.Misses), name)
+		ch <- prometheus.MustNewConstMetric(cacheEvictionsDesc, prometheus.CounterValue, float64(stats.Evictions),
+			name)
+		ch <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(stats.Size), name)
+	}
+}
+
+// ServeHTTP serves the metrics in the Prometheus exposition format.
+func (m *webhookMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m == nil {
+		http.NotFound(w, r)
+		return
+	}
+	promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scrape returns the metrics served by m.
+func scrape(t *testing.T, m *webhookMetrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+func TestMetricsAPIRequests(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"zone is not found"}`))
+	}))
+	defer server.Close()
+
+	m := newWebhookMetrics()
+	sdk, err := newSDKClient(gcoreDNSProviderConfig{ApiUrl: server.URL, metrics: m}, "t")
+	require.NoError(t, err)
+	_, err = sdk.Zone(t.Context(), "example.com")
+	require.Error(t, err)
+
+	unreachable, err := newSDKClient(gcoreDNSProviderConfig{ApiUrl: "http://127.0.0.1:1", metrics: m}, "t")
+	require.NoError(t, err)
+	_, err = unreachable.Zone(t.Context(), "example.com")
+	require.Error(t, err)
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `gcore_webhook_api_requests_total{code="404",method="GET"} 1`)
+	assert.Contains(t, body, `gcore_webhook_api_requests_total{code="error",method="GET"} 1`)
+	assert.Contains(t, body, `gcore_webhook_api_request_duration_seconds_count{method="GET"} 2`)
+}
+
+func TestMetricsChallenges(t *testing.T) {
+	t.Parallel()
+
+	mock := newMockSDK("example.com")
+	solver := solverWithMock(mock)
+	solver.metrics = newWebhookMetrics()
+	inZone := func(fqdn, zone string) *v1alpha1.ChallengeRequest {
+		ch := challenge(fqdn, "key", `{"apiToken":"t"}`)
+		ch.ResolvedZone = zone
+		return ch
+	}
+	require.NoError(t, solver.Present(inZone("_acme-challenge.example.com.", "example.com.")))
+	require.NoError(t, solver.CleanUp(inZone("_acme-challenge.example.com.", "example.com.")))
+	require.Error(t, solver.Present(inZone("_acme-challenge.example.net.", "Example.net.")))
+
+	body := scrape(t, solver.metrics)
+	assert.Contains(t, body, `gcore_webhook_challenges_total{operation="present",result="success"} 1`)
+	assert.Contains(t, body, `gcore_webhook_challenges_total{operation="cleanup",result="success"} 1`)
+	assert.Contains(t, body, `gcore_webhook_challenges_total{operation="present",result="zone_not_found"} 1`)
+	assert.Contains(t, body, `gcore_webhook_challenge_duration_seconds_count{operation="present"} 2`)
+	assert.Contains(t, body, `gcore_webhook_present_total{zone="example.com"} 1`)
+	assert.Contains(t, body, `gcore_webhook_present_total{zone="example.net"} 1`)
+	assert.Contains(t, body, `gcore_webhook_cleanup_total{zone="example.com"} 1`)
+	assert.Contains(t, body, `gcore_webhook_errors_total{class="zone_not_found",operation="present",zone="example.net"} 1`)
+	assert.NotContains(t, body, `gcore_webhook_errors_total{class="zone_not_found",operation="present",zone="example.com"}`)
+}
+
+func TestMetricsCaches(t *testing.T) {
+	t.Parallel()
+
+	m := newWebhookMetrics()
+	solver := solverWithMock(newMockSDK("example.com"))
+	solver.zones = newCache[zoneCacheKey, zoneDetails](time.Minute, 10)
+	m.observeCaches(solver.cacheStats)
+	cfg := `{"apiToken":"t"}`
+	require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "a", cfg)))
+	require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "b", cfg)))
+
+	out := scrape(t, m)
+	assert.Contains(t, out, `gcore_webhook_cache_hits_total{cache="zones"} 1`)
+	assert.Contains(t, out, `gcore_webhook_cache_misses_total{cache="zones"} 1`)
+	assert.Contains(t, out, `gcore_webhook_cache_evictions_total{cache="zones"} 0`)
+	assert.Contains(t, out, `gcore_webhook_cache_entries{cache="zones"} 1`)
+	assert.Contains(t, out, `gcore_webhook_cache_entries{cache="nameservers"} 0`)
+}
+
+func TestMetricsQuota(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1700000000, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/zones/example.com":
+			w.Header().Set("X-RateLimit-Limit", "100")
+			w.Header().Set("X-RateLimit-Remaining", "42")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(time.Minute).Unix(), 10))
+		case "/v2/zones/example.net":
+			// The draft standard headers, with the reset in seconds.
+			w.Header().Set("RateLimit-Limit", "100, 100;w=60")
+			w.Header().Set("RateLimit-Remaining", "7")
+		}
+		_, _ = w.Write([]byte(`{"name":"example.com"}`))
+	}))
+	defer server.Close()
+
+	m := newWebhookMetrics()
+	a, err := newSDKClient(gcoreDNSProviderConfig{ApiUrl: server.URL, metrics: m, account: "a"}, "t")
+	require.NoError(t, err)
+	b, err := newSDKClient(gcoreDNSProviderConfig{ApiUrl: server.URL, metrics: m, account: "b"}, "u")
+	require.NoError(t, err)
+	_, err = a.Zone(t.Context(), "example.com")
+	require.NoError(t, err)
+	_, err = b.Zone(t.Context(), "example.net")
+	require.NoError(t, err)
+	// Responses without the headers keep the last reported quota.
+	_, err = a.Zone(t.Context(), "example.org")
+	require.NoError(t, err)
+
+	out := scrape(t, m)
+	assert.Contains(t, out, `gcore_webhook_api_quota_limit{account="a"} 100`)
+	assert.Contains(t, out, `gcore_webhook_api_quota_remaining{account="a"} 42`)
+	assert.Contains(t, out, `gcore_webhook_api_quota_reset_timestamp_seconds{account="a"} 1.70000006e+09`)
+	assert.Contains(t, out, `gcore_webhook_api_quota_limit{account="b"} 100`)
+	assert.Contains(t, out, `gcore_webhook_api_quota_remaining{account="b"} 7`)
+	assert.NotContains(t, out, `gcore_webhook_api_quota_reset_timestamp_seconds{account="b"}`)
+}
+
+func TestObserveQuotaResetSeconds(t *testing.T) {
+	t.Parallel()
+
+	m := newWebhookMetrics()
+	m.observeQuota("a", http.Header{"Ratelimit-Reset": {"30"}, "X-Ratelimit-Remaining": {"soon"}},
+		time.Unix(1700000000, 0))
+	out := scrape(t, m)
+	assert.Contains(t, out, `gcore_webhook_api_quota_reset_timestamp_seconds{account="a"} 1.70000003e+09`)
+	assert.NotContains(t, out, `gcore_webhook_api_quota_remaining{`)
+}
+
+func TestChallengeResult(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "success", challengeResult(nil))
+	assert.Equal(t, "error", challengeResult(errors.New("invalid config")))
+	assert.Equal(t, "auth", challengeResult(classifyError(fmt.Errorf("zone: %w",
+		dnssdk.APIError{StatusCode: http.StatusForbidden}))))
+	assert.Equal(t, "rate_limited", challengeResult(dnssdk.APIError{StatusCode: http.StatusTooManyRequests}))
+	for class := range classSummaries {
+		assert.NotEmpty(t, classLabels[class], class.Error())
+	}
+
+	// Without metrics nothing is recorded and /metrics is not served.
+	var disabled *webhookMetrics
+	disabled.observeChallenge("present", &v1alpha1.ChallengeRequest{}, time.Now(), nil)
+	assert.Equal(t, http.DefaultTransport, disabled.instrument(http.DefaultTransport, "account"))
+	rec := httptest.NewRecorder()
+	disabled.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+	"github.com/miekg/dns"
+)
+
+// propagationPollInterval is the wait between two rounds of asking the
+// authoritative nameservers for a presented record.
+var propagationPollInterval = 2 * time.Second
+
+// authoritativeNameservers returns the nameservers serving zone, used to check
+// that a presented record is visible at the source. Depending on cfg.NSSource
+// they are taken from the G-Core API or looked up in the live delegation, which
+// matters when the NS listed by G-Core differ from the delegated ones.
+func (c *gcoreDNSProviderSolver) authoritativeNameservers(ctx context.Context, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig, zone string) ([]string, error) {
+	key := nsCacheKey{account: cfg.account, zone: zone, source: cfg.NSSource}
+	if nameservers, ok := c.nameservers.Get(key); ok {
+		return nameservers, nil
+	}
+	var nameservers []string
+	switch cfg.NSSource {
+	case nsSourceDNS:
+		lookupNS := c.lookupNS
+		if lookupNS == nil && len(c.dnsResolvers) > 0 {
+			lookupNS = lookupNSVia(c.dnsResolvers)
+		} else if lookupNS == nil {
+			lookupNS = net.DefaultResolver.LookupNS
+		}
+		records, err := lookupNS(ctx, zone)
+		if err != nil {
+			return nil, fmt.Errorf("lookup ns %s: %w", zone, err)
+		}
+		for _, record := range records {
+			nameservers = append(nameservers, record.Host)
+		}
+	default:
+		var err error
+		nameservers, err = sdk.ZoneNameservers(ctx, zone)
+		if err != nil {
+			return nil, fmt.Errorf("zone nameservers %s: %w", zone, err)
+		}
+	}
+	if len(nameservers) == 0 {
+		return nil, fmt.Errorf("no nameservers found for zone %s (nsSource %s)", zone, cfg.NSSource)
+	}
+	for i, ns := range nameservers {
+		nameservers[i] = strings.ToLower(strings.TrimSuffix(ns, "."))
+	}
+	sort.Strings(nameservers)
+	c.nameservers.Set(key, nameservers)
+	return nameservers, nil
+}
+
+// waitForPropagation waits until every authoritative nameserver of zone
+// answers the TXT record fqdn with key, so that the ACME server does not
+// validate before the record reached the nameserver it happens to ask. In a
+// DNSSEC-signed zone the answer must also be signed, as validating resolvers
+// fail on an RRSet that was not re-signed yet. It gives up when ctx is done.
+func (c *gcoreDNSProviderSolver) waitForPropagation(ctx context.Context, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig, zone, fqdn, key string) error {
+	pending, err := c.authoritativeNameservers(ctx, sdk, cfg, zone)
+	if err != nil {
+		return err
+	}
+	signed, err := zoneSigned(ctx, sdk, zone)
+	if err != nil {
+		return err
+	}
+	lookupTXT := c.lookupTXT
+	if lookupTXT == nil {
+		lookupTXT = lookupTXTAt
+	}
+	verifySignature := c.verifyTXTSignature
+	if verifySignature == nil {
+		verifySignature = verifyTXTSignatureAt
+	}
+	fqdn = strings.Trim(fqdn, ".") + "."
+	for {
+		var missing []string
+		for _, nameserver := range pending {
+			values, err := lookupTXT(ctx, nameserver, fqdn)
+			if err != nil || !slices.Contains(values, key) {
+				missing = append(missing, nameserver)
+				continue
+			}
+			if signed {
+				if err := verifySignature(ctx, nameserver, zone, fqdn); err != nil {
+					c.logger(ctx).V(1).Info("challenge record not signed yet", "recordName", fqdn, "nameserver", nameserver,
+						"error", err.Error())
+					missing = append(missing, nameserver)
+				}
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		pending = missing
+		if err := sleepContext(ctx, propagationPollInterval); err != nil {
+			return fmt.Errorf("record %s not visible at %s: %w", fqdn, strings.Join(pending, ", "), err)
+		}
+	}
+}
+
+// lookupTXTAt asks nameserver directly for the TXT records of fqdn. Their
+// character strings are joined and decoded from presentation format.
+func lookupTXTAt(ctx context.Context, nameserver, fqdn string) ([]string, error) {
+	msg, err := util.DNSQuery(ctx, fqdn, dns.TypeTXT, []string{net.JoinHostPort(nameserver, "53")}, false)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, rr := range msg.Answer {
+		if txt, ok := rr.(*dns.TXT); ok {
+			var value strings.Builder
+			for _, s := range txt.Txt {
+				value.WriteString(unescapeTXT(s))
+			}
+			values = append(values, value.String())
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthoritativeNameservers(t *testing.T) {
+	mock := newMockSDK("example.com")
+	mock.zones["example.com"].nameservers = []string{"ns2.gcorelabs.net", "ns1.gcorelabs.net"}
+
+	solver := solverWithMock(mock)
+	solver.lookupNS = func(_ context.Context, name string) ([]*net.NS, error) {
+		if name != "example.com" {
+			return nil, errors.New("no such host")
+		}
+		return []*net.NS{{Host: "NS1.Delegated.Example."}, {Host: "ns2.delegated.example."}}, nil
+	}
+
+	testCases := []struct {
+		desc     string
+		cfg      gcoreDNSProviderConfig
+		zone     string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			desc:     "api source",
+			cfg:      gcoreDNSProviderConfig{NSSource: nsSourceAPI},
+			zone:     "example.com",
+			expected: []string{"ns1.gcorelabs.net", "ns2.gcorelabs.net"},
+		},
+		{
+			desc:     "dns source",
+			cfg:      gcoreDNSProviderConfig{NSSource: nsSourceDNS},
+			zone:     "example.com",
+			expected: []string{"ns1.delegated.example", "ns2.delegated.example"},
+		},
+		{
+			desc:    "api source unknown zone",
+			cfg:     gcoreDNSProviderConfig{NSSource: nsSourceAPI},
+			zone:    "example.org",
+			wantErr: true,
+		},
+		{
+			desc:    "dns source lookup failure",
+			cfg:     gcoreDNSProviderConfig{NSSource: nsSourceDNS},
+			zone:    "example.org",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := solver.authoritativeNameservers(context.Background(), mock, test.cfg, test.zone)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestWaitForPropagation(t *testing.T) {
+	setForTest(t, &propagationPollInterval, time.Millisecond)
+	mock := newMockSDK("example.com")
+	mock.zones["example.com"].nameservers = []string{"ns1.gcorelabs.net", "ns2.gcorelabs.net"}
+	var mu sync.Mutex
+	asked := map[string]int{}
+	solver := solverWithMock(mock)
+	solver.lookupTXT = func(_ context.Context, nameserver, fqdn string) ([]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, "_acme-challenge.example.com.", fqdn)
+		asked[nameserver]++
+		switch {
+		case nameserver == "ns1.gcorelabs.net":
+			return []string{"other", "token-A"}, nil
+		case nameserver == "ns2.gcorelabs.net" && asked[nameserver] > 2:
+			return []string{"token-A"}, nil
+		}
+		return nil, errors.New("i/o timeout")
+	}
+
+	cfg := `{"apiToken":"t","verifyPropagation":true}`
+	require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "token-A", cfg)))
+	assert.Equal(t, map[string]int{"ns1.gcorelabs.net": 1, "ns2.gcorelabs.net": 3}, asked)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := solver.waitForPropagation(ctx, mock, gcoreDNSProviderConfig{NSSource: nsSourceAPI},
+		"example.com", "_acme-challenge.example.com", "token-B")
+	assert.ErrorContains(t, err, "record _acme-challenge.example.com. not visible at ns1.gcorelabs.net, ns2.gcorelabs.net")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
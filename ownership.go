@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+)
+
+// ownerNote marks the records the webhook created. It is stored in the
+// notes meta field of the record, the free text G-Core keeps per record,
+// together with a hash of the challenge key the record was created for and
+// its creation time.
+const ownerNote = "managed-by: cert-manager-webhook-gcore"
+
+// ownerNoteMeta is the record meta field holding the ownerNote.
+const ownerNoteMeta = "notes"
+
+// ownerNoteCreated prefixes the creation time in the ownerNote.
+const ownerNoteCreated = "; created: "
+
+// markOwned stamps record as created by the webhook for key at now.
+func markOwned(record dnssdk.ResourceRecord, key string, now time.Time) dnssdk.ResourceRecord {
+	if record.Meta == nil {
+		record.Meta = map[string]any{}
+	}
+	record.Meta[ownerNoteMeta] = ownerNote + "; challenge: " + contentHash(key) +
+		ownerNoteCreated + now.UTC().Format(time.RFC3339)
+	return record
+}
+
+// owned reports whether record carries the ownerNote of the webhook.
+func owned(record dnssdk.ResourceRecord) bool {
+	note, _ := record.Meta[ownerNoteMeta].(string)
+	return strings.HasPrefix(note, ownerNote)
+}
+
+// ownedSince returns when the webhook created record, ok is false for
+// records it did not create or without a creation time.
+func ownedSince(record dnssdk.ResourceRecord) (time.Time, bool) {
+	if !owned(record) {
+		return time.Time{}, false
+	}
+	note, _ := record.Meta[ownerNoteMeta].(string)
+	_, created, ok := strings.Cut(note, ownerNoteCreated)
+	if !ok {
+		return time.Time{}, false
+	}
+	since, err := time.Parse(time.RFC3339, created)
+	return since, err == nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanUpOwnership(t *testing.T) {
+	const fqdn = "_acme-challenge.example.com."
+	setup := func(t *testing.T) (*mockSDK, *gcoreDNSProviderSolver) {
+		mock := newMockSDK("example.com")
+		// a record added by hand that happens to hold the challenge key
+		require.NoError(t, mock.UpdateRRSet(context.Background(), "example.com", "_acme-challenge.example.com", txtType,
+			dnssdk.RRSet{TTL: 120, Records: []dnssdk.ResourceRecord{{Content: []any{"token-M"}, Enabled: true}}}))
+		solver := solverWithMock(mock)
+		require.NoError(t, solver.Present(challenge(fqdn, "token-A", `{"apiToken":"t"}`)))
+		return mock, solver
+	}
+
+	t.Run("only owned records are removed", func(t *testing.T) {
+		mock, solver := setup(t)
+		rrset, err := mock.RRSet(context.Background(), "example.com", "_acme-challenge.example.com", txtType)
+		require.NoError(t, err)
+		assert.Equal(t, []bool{true, false}, []bool{owned(rrset.Records[0]), owned(rrset.Records[1])})
+
+		require.NoError(t, solver.CleanUp(challenge(fqdn, "token-M", `{"apiToken":"t"}`)))
+		require.NoError(t, solver.CleanUp(challenge(fqdn, "token-A", `{"apiToken":"t"}`)))
+		assert.Equal(t, []string{"token-M"}, mock.contents("example.com", "_acme-challenge.example.com"))
+	})
+
+	t.Run("strict cleanup", func(t *testing.T) {
+		_, solver := setup(t)
+		err := solver.CleanUp(challenge(fqdn, "token-M", `{"apiToken":"t","strictCleanup":true}`))
+		assert.ErrorContains(t, err, "lacks the ownership note")
+	})
+
+	t.Run("unowned records allowed", func(t *testing.T) {
+		mock, solver := setup(t)
+		require.NoError(t, solver.CleanUp(challenge(fqdn, "token-M", `{"apiToken":"t","cleanupUnowned":true}`)))
+		assert.Equal(t, []string{"token-A"}, mock.contents("example.com", "_acme-challenge.example.com"))
+	})
+}
+
+func TestMarkOwned(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 30, 0, 0, time.UTC)
+	record := markOwned(dnssdk.ResourceRecord{Content: []any{"key"}, Meta: map[string]any{"countries": []string{"de"}}},
+		"key", now)
+	assert.True(t, owned(record))
+	assert.Equal(t, []string{"de"}, record.Meta["countries"])
+	assert.Equal(t, ownerNote+"; challenge: "+contentHash("key")+"; created: 2026-10-15T12:30:00Z",
+		record.Meta[ownerNoteMeta])
+	since, ok := ownedSince(record)
+	assert.True(t, ok)
+	assert.Equal(t, now, since)
+
+	_, ok = ownedSince(dnssdk.ResourceRecord{Meta: map[string]any{ownerNoteMeta: ownerNote}})
+	assert.False(t, ok, "records marked without creation time have no age")
+	assert.False(t, owned(dnssdk.ResourceRecord{Content: []any{"key"}}))
+	assert.False(t, owned(dnssdk.ResourceRecord{Meta: map[string]any{ownerNoteMeta: "added by hand"}}))
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// enablePprofFlag serves the net/http/pprof profiles on the admin server, to
+// profile memory and goroutine leaks during mass renewals in production.
+const enablePprofFlag = "--enable-pprof"
+
+// handlePprof adds the pprof endpoints below /debug/pprof/ to mux.
+func handlePprof(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminHandlerPprof(t *testing.T) {
+	t.Parallel()
+
+	get := func(solver *gcoreDNSProviderSolver, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		solver.adminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	solver := solverWithMock(newMockSDK())
+	assert.Equal(t, http.StatusNotFound, get(solver, "/debug/pprof/").Code)
+
+	solver.pprof = true
+	rec := get(solver, "/debug/pprof/goroutine?debug=1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "goroutine profile:")
+	assert.Equal(t, http.StatusOK, get(solver, "/debug/pprof/").Code)
+	// The other endpoints are still served.
+	assert.Equal(t, http.StatusOK, get(solver, "/debug/cache").Code)
+}
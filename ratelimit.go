@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+)
+
+// retryAfterHint passes the Retry-After of a rate limited response from
+// rateLimitTransport to retryCall, as the SDK's APIError drops the headers.
+type retryAfterHint struct {
+	mu   sync.Mutex
+	wait time.Duration
+	set  bool
+}
+
+type retryAfterHintKey struct{}
+
+// withRetryAfterHint returns ctx carrying a new hint for the requests made
+// with it.
+func withRetryAfterHint(ctx context.Context) (context.Context, *retryAfterHint) {
+	hint := &retryAfterHint{}
+	return context.WithValue(ctx, retryAfterHintKey{}, hint), hint
+}
+
+// take returns and clears the last Retry-After seen.
+func (h *retryAfterHint) take() (time.Duration, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	wait, set := h.wait, h.set
+	h.wait, h.set = 0, false
+	return wait, set
+}
+
+// rateLimitTransport records the Retry-After of 429 Too Many Requests
+// responses in the request's retryAfterHint.
+type rateLimitTransport struct {
+	base http.RoundTripper
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+	hint, ok := req.Context().Value(retryAfterHintKey{}).(*retryAfterHint)
+	if !ok {
+		return resp, nil
+	}
+	if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+		hint.mu.Lock()
+		hint.wait, hint.set = wait, true
+		hint.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// parseRetryAfter returns the wait a Retry-After header value asks for,
+// given either in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(at.Sub(now), 0), true
+}
+
+// isRateLimited reports whether err is a 429 Too Many Requests answer.
+func isRateLimited(err error) bool {
+	var apiErr dnssdk.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests
+}
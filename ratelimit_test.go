@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		desc  string
+		value string
+		wait  time.Duration
+		ok    bool
+	}{
+		{desc: "seconds", value: "3", wait: 3 * time.Second, ok: true},
+		{desc: "zero", value: "0", ok: true},
+		{desc: "http date", value: "Wed, 01 May 2024 12:00:10 GMT", wait: 10 * time.Second, ok: true},
+		{desc: "past date", value: "Wed, 01 May 2024 11:00:00 GMT", ok: true},
+		{desc: "empty"},
+		{desc: "negative", value: "-1"},
+		{desc: "garbage", value: "soon"},
+	}
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			wait, ok := parseRetryAfter(test.value, now)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.wait, wait)
+		})
+	}
+}
+
+// rateLimitedServer answers the first limited requests with 429 and
+// retryAfter, then serves the zone example.com.
+func rateLimitedServer(t *testing.T, limited int, retryAfter string) (*httptest.Server, *[]time.Time) {
+	var calls []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, time.Now())
+		if len(calls) <= limited {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":"rate limit exceeded"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"example.com"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	server, calls := rateLimitedServer(t, 1, "1")
+	sdk, err := newSDKClient(gcoreDNSProviderConfig{ApiUrl: server.URL}, "t")
+	require.NoError(t, err)
+	// The backoff alone would retry right away.
+	api := &retryingAPI{api: sdk, apiURL: server.URL,
+		policy: &retryPolicy{maxRetries: 2, initialBackoff: time.Millisecond, maxBackoff: time.Millisecond}}
+
+	_, err = api.ZoneDetails(context.Background(), "example.com")
+	require.NoError(t, err)
+	require.Len(t, *calls, 2)
+	assert.GreaterOrEqual(t, (*calls)[1].Sub((*calls)[0]), time.Second)
+}
+
+func TestRetryAfterBeyondDeadline(t *testing.T) {
+	server, calls := rateLimitedServer(t, 1, "60")
+	sdk, err := newSDKClient(gcoreDNSProviderConfig{ApiUrl: server.URL}, "t")
+	require.NoError(t, err)
+	api := &retryingAPI{api: sdk, apiURL: server.URL}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err = api.ZoneDetails(ctx, "example.com")
+	assert.ErrorContains(t, err, "retry after 1m0s exceeds the deadline")
+	assert.True(t, isRateLimited(err))
+	assert.Less(t, time.Since(start), time.Second, "the call should give up instead of sleeping")
+	assert.Len(t, *calls, 1)
+
+	assert.False(t, isRateLimited(dnssdk.APIError{StatusCode: http.StatusForbidden}))
+}
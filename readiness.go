@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+)
+
+// readinessCheckTimeout bounds the API call of a readiness check. The chart
+// gives the readiness probe more time than that.
+const readinessCheckTimeout = 5 * time.Second
+
+// apiReadiness is a /readyz check of the webhook's API server that lists a
+// single zone with the ambient GCORE_API_TOKEN, so a Deployment whose token
+// was revoked or can't reach the G-Core API reports NotReady instead of
+// failing every challenge. The outcome is kept for interval, so probes don't
+// call the API each time.
+type apiReadiness struct {
+	solver   *gcoreDNSProviderSolver
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+}
+
+func (r *apiReadiness) Name() string {
+	return "gcore-api"
+}
+
+// Check returns why the G-Core API can't be used, nil when it can.
+func (r *apiReadiness) Check(req *http.Request) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if !r.checked.IsZero() && now.Sub(r.checked) < r.interval {
+		return r.err
+	}
+	// A probe that gave up must not cache its cancellation as the outcome.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), readinessCheckTimeout)
+	defer cancel()
+	r.err = redactError(classifyError(r.check(ctx)))
+	r.checked = now
+	if r.err != nil {
+		r.solver.log.Error(r.err, "G-Core API readiness check failed")
+	}
+	return r.err
+}
+
+func (r *apiReadiness) check(ctx context.Context) error {
+	sdk, _, err := r.solver.ambientAPI(readinessCheckIntervalEnvVar)
+	if err != nil {
+		return err
+	}
+	if _, err := sdk.ZonesWithParam(ctx, dnssdk.ZonesParam{Limit: 1}); err != nil {
+		return fmt.Errorf("list zones: %w", err)
+	}
+	return nil
+}
+
+// readinessCheckFromEnv returns the readiness check of
+// GCORE_READINESS_CHECK_INTERVAL (a duration), nil when it is unset or zero.
+// The check needs the ambient GCORE_API_TOKEN.
+func readinessCheckFromEnv(solver *gcoreDNSProviderSolver) (*apiReadiness, error) {
+	v := os.Getenv(readinessCheckIntervalEnvVar)
+	if v == "" {
+		return nil, nil
+	}
+	interval, err := time.ParseDuration(v)
+	if err != nil || interval < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative duration, got %q", readinessCheckIntervalEnvVar, v)
+	}
+	if interval == 0 {
+		return nil, nil
+	}
+	if os.Getenv(apiTokenEnvVar) == "" {
+		return nil, fmt.Errorf("%s is set but %s is not", readinessCheckIntervalEnvVar, apiTokenEnvVar)
+	}
+	return &apiReadiness{solver: solver, interval: interval, now: time.Now}, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadinessCheck(t *testing.T) {
+	t.Setenv(apiTokenEnvVar, "token")
+	api := &flakyListAPI{mockSDK: newMockSDK("example.com"), failures: 1,
+		err: dnssdk.APIError{StatusCode: http.StatusUnauthorized, Message: "unauthorized"}}
+	solver := &gcoreDNSProviderSolver{
+		newSDK: func(gcoreDNSProviderConfig, string) (dnsAPI, error) { return api, nil },
+	}
+	t.Setenv(readinessCheckIntervalEnvVar, "1m")
+	check, err := readinessCheckFromEnv(solver)
+	require.NoError(t, err)
+	now := time.Now()
+	check.now = func() time.Time { return now }
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+	err = check.Check(req)
+	assert.ErrorIs(t, err, errAuth)
+	assert.ErrorContains(t, err, "list zones: 401: unauthorized")
+	assert.Equal(t, 1, api.calls)
+
+	// The failure is reported until the interval passed.
+	now = now.Add(30 * time.Second)
+	assert.ErrorIs(t, check.Check(req), errAuth)
+	assert.Equal(t, 1, api.calls)
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, check.Check(req))
+	assert.NoError(t, check.Check(req))
+	assert.Equal(t, 2, api.calls)
+	assert.Equal(t, 1, api.listCalls)
+}
+
+func TestReadinessCheckFromEnv(t *testing.T) {
+	solver := &gcoreDNSProviderSolver{}
+	t.Setenv(apiTokenEnvVar, "")
+	check, err := readinessCheckFromEnv(solver)
+	require.NoError(t, err)
+	assert.Nil(t, check)
+
+	t.Setenv(readinessCheckIntervalEnvVar, "0s")
+	check, err = readinessCheckFromEnv(solver)
+	require.NoError(t, err)
+	assert.Nil(t, check)
+
+	t.Setenv(readinessCheckIntervalEnvVar, "soon")
+	_, err = readinessCheckFromEnv(solver)
+	assert.EqualError(t, err, `GCORE_READINESS_CHECK_INTERVAL must be a non-negative duration, got "soon"`)
+
+	t.Setenv(readinessCheckIntervalEnvVar, "1m")
+	_, err = readinessCheckFromEnv(solver)
+	assert.EqualError(t, err, "GCORE_READINESS_CHECK_INTERVAL is set but GCORE_API_TOKEN is not")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// recordLocks serializes the writes of one process to an RRSet, so that
+// challenges for the same name, e.g. of a wildcard and its apex, don't lose
+// each other's records in concurrent read-modify-write cycles. Replicas are
+// still guarded by the conditional updates. The zero value is ready to use.
+type recordLocks struct {
+	mu    sync.Mutex
+	locks map[string]*recordLock
+}
+
+// recordLock is the lock of one RRSet, dropped once nobody holds or waits
+// for it.
+type recordLock struct {
+	ch   chan struct{}
+	refs int
+}
+
+// recordLockKey identifies the RRSet fqdn of the account of cfg.
+func recordLockKey(cfg gcoreDNSProviderConfig, fqdn string) string {
+	return cfg.account + "\x00" + strings.ToLower(strings.Trim(fqdn, "."))
+}
+
+// lock waits until key is free or ctx is done, and returns the function
+// releasing it.
+func (l *recordLocks) lock(ctx context.Context, key string) (func(), error) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*recordLock{}
+	}
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &recordLock{ch: make(chan struct{}, 1)}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	select {
+	case lock.ch <- struct{}{}:
+		return func() {
+			<-lock.ch
+			l.release(key, lock)
+		}, nil
+	case <-ctx.Done():
+		l.release(key, lock)
+		return nil, ctx.Err()
+	}
+}
+
+func (l *recordLocks) release(key string, lock *recordLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, key)
+	}
+}
+
+// size returns the number of RRSets locked or waited for.
+func (l *recordLocks) size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.locks)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordLocks(t *testing.T) {
+	var locks recordLocks
+	unlock, err := locks.lock(context.Background(), "a")
+	require.NoError(t, err)
+
+	// other records are not blocked
+	unlockB, err := locks.lock(context.Background(), "b")
+	require.NoError(t, err)
+	unlockB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = locks.lock(ctx, "a")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	acquired := make(chan struct{})
+	go func() {
+		unlock, err := locks.lock(context.Background(), "a")
+		assert.NoError(t, err)
+		close(acquired)
+		unlock()
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired while held")
+	case <-time.After(10 * time.Millisecond):
+	}
+	unlock()
+	<-acquired
+	assert.Eventually(t, func() bool { return locks.size() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestPresentSameNameConcurrently(t *testing.T) {
+	const fqdn = "_acme-challenge.example.com."
+	mock := newMockSDK("example.com")
+	solver := solverWithMock(mock)
+
+	var wg sync.WaitGroup
+	var keys []string
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("token-%d", i)
+		keys = append(keys, key)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, solver.Present(challenge(fqdn, key, `{"apiToken":"t"}`)))
+		}()
+	}
+	wg.Wait()
+	assert.ElementsMatch(t, keys, mock.contents("example.com", "_acme-challenge.example.com"))
+	assert.Zero(t, solver.recordLocks.size())
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/go-logr/logr"
+)
+
+// credentialPattern matches a credential following its name, as in an
+// Authorization header, a query string or a JSON body, e.g.
+// "APIKey 123$abc", "token=abc" or `"apiToken":"abc"`.
+var credentialPattern = regexp.MustCompile(`(?i)(apikey|bearer|token|password)(["']?[\s:=]+["']?)[^\s"',}&]+`)
+
+// redact masks anything that looks like a credential in msg.
+func redact(msg string) string {
+	return credentialPattern.ReplaceAllString(msg, "$1$2[REDACTED]")
+}
+
+// redactedError masks the credentials in the message of err, e.g. an SDK
+// error quoting the request. It still matches what err wraps.
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return redact(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactError wraps err so its message has no credentials, nil staying nil.
+func redactError(err error) error {
+	if _, ok := err.(*redactedError); ok || err == nil {
+		return err
+	}
+	return &redactedError{err: err}
+}
+
+// redactingSink masks credentials in the messages, errors and string values
+// of every line logged through it, wherever they come from.
+type redactingSink struct {
+	logr.LogSink
+}
+
+// redactingLogger returns log with credentials masked in its lines.
+func redactingLogger(log logr.Logger) logr.Logger {
+	sink := log.GetSink()
+	if sink == nil {
+		return log
+	}
+	// The sink reports the caller of the logger, one frame further up.
+	if withCallDepth, ok := sink.(logr.CallDepthLogSink); ok {
+		sink = withCallDepth.WithCallDepth(1)
+	}
+	return log.WithSink(&redactingSink{LogSink: sink})
+}
+
+func (s *redactingSink) Info(level int, msg string, keysAndValues ...any) {
+	s.LogSink.Info(level, redact(msg), redactValues(keysAndValues)...)
+}
+
+func (s *redactingSink) Error(err error, msg string, keysAndValues ...any) {
+	s.LogSink.Error(redactError(err), redact(msg), redactValues(keysAndValues)...)
+}
+
+func (s *redactingSink) WithValues(keysAndValues ...any) logr.LogSink {
+	return &redactingSink{LogSink: s.LogSink.WithValues(redactValues(keysAndValues)...)}
+}
+
+func (s *redactingSink) WithName(name string) logr.LogSink {
+	return &redactingSink{LogSink: s.LogSink.WithName(name)}
+}
+
+func (s *redactingSink) WithCallDepth(depth int) logr.LogSink {
+	if withCallDepth, ok := s.LogSink.(logr.CallDepthLogSink); ok {
+		return &redactingSink{LogSink: withCallDepth.WithCallDepth(depth)}
+	}
+	return s
+}
+
+// redactValues masks credentials in the string and error values of a key
+// value list.
+func redactValues(keysAndValues []any) []any {
+	redacted := make([]any, len(keysAndValues))
+	for i, v := range keysAndValues {
+		switch v := v.(type) {
+		case string:
+			if i%2 == 1 {
+				redacted[i] = redact(v)
+				continue
+			}
+		case error:
+			redacted[i] = redactError(v)
+			continue
+		}
+		redacted[i] = v
+	}
+	return redacted
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replicas returns n solvers sharing the API of mock but nothing in-process,
+// like the pods of a Deployment.
+func replicas(n int, mock *mockSDK) []*gcoreDNSProviderSolver {
+	var solvers []*gcoreDNSProviderSolver
+	for range n {
+		solvers = append(solvers, solverWithMock(mock))
+	}
+	return solvers
+}
+
+func TestReplicasConcurrentChallenges(t *testing.T) {
+	const fqdn = "_acme-challenge.example.com."
+	cfg := `{"apiToken":"t"}`
+	mock := newMockSDK("example.com")
+	pods := replicas(2, mock)
+
+	var keys []string
+	for i := range 20 {
+		keys = append(keys, fmt.Sprintf("token-%d", i))
+	}
+	// cert-manager may send each call to either replica, and retries them.
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, pods[i%2].Present(challenge(fqdn, key, cfg)))
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, pods[(i+1)%2].Present(challenge(fqdn, key, cfg)))
+		}()
+	}
+	wg.Wait()
+	assert.ElementsMatch(t, keys, mock.contents("example.com", "_acme-challenge.example.com"))
+
+	// A challenge is cleaned up by the replica that did not present it.
+	for i, key := range keys[:10] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, pods[(i+1)%2].CleanUp(challenge(fqdn, key, cfg)))
+		}()
+	}
+	wg.Wait()
+	assert.ElementsMatch(t, keys[10:], mock.contents("example.com", "_acme-challenge.example.com"))
+
+	for i, key := range keys[10:] {
+		wg.Add(2)
+		for _, pod := range []*gcoreDNSProviderSolver{pods[i%2], pods[(i+1)%2]} {
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, pod.CleanUp(challenge(fqdn, key, cfg)))
+			}()
+		}
+	}
+	wg.Wait()
+	assert.Empty(t, mock.contents("example.com", "_acme-challenge.example.com"))
+}
+
+// probeRaceAPI has another replica's write probe delete the shared probe
+// record right before the first delete of this one, which then finds it
+// gone.
+type probeRaceAPI struct {
+	*mockSDK
+	raced bool
+}
+
+func (p *probeRaceAPI) DeleteRRSet(ctx context.Context, zone, name, recordType string) error {
+	if !p.raced {
+		p.raced = true
+		if err := p.mockSDK.DeleteRRSet(ctx, zone, name, recordType); err != nil {
+			return err
+		}
+		return dnssdk.APIError{StatusCode: http.StatusNotFound, Message: "rrset not found"}
+	}
+	return p.mockSDK.DeleteRRSet(ctx, zone, name, recordType)
+}
+
+func TestReplicasWriteProbeRace(t *testing.T) {
+	mock := newMockSDK("example.com")
+	api := &probeRaceAPI{mockSDK: mock}
+	solver := solverWithMock(mock)
+	cfg := gcoreDNSProviderConfig{account: "a"}
+	require.NoError(t, solver.verifyWriteScope(t.Context(), api, cfg, "example.com"))
+	assert.Equal(t, 2, mock.creates, "the probe ran again")
+	assert.Nil(t, mock.contents("example.com", writeProbeLabel+".example.com"))
+}
+
+func TestReplicasStaleRecordGC(t *testing.T) {
+	const name = "_acme-challenge.example.com"
+	mock := newMockSDK("example.com")
+	now := time.Now()
+	stale := markOwned(dnssdk.ResourceRecord{Content: []any{"token-old"}, Enabled: true}, "token-old",
+		now.Add(-48*time.Hour))
+	require.NoError(t, mock.UpdateRRSet(t.Context(), "example.com", name, txtType,
+		dnssdk.RRSet{TTL: 120, Records: []dnssdk.ResourceRecord{stale}}))
+
+	pods := replicas(2, mock)
+	cutoff := now.Add(-defaultStaleRecordMaxAge)
+	// Replica B collects the record after replica A read the RRSet.
+	mock.beforeIfMatch = func() {
+		removed, err := pods[1].collectStaleRRSet(t.Context(), mock, gcoreDNSProviderConfig{}, "example.com",
+			name, cutoff)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, removed)
+	}
+	removed, err := pods[0].collectStaleRRSet(t.Context(), mock, gcoreDNSProviderConfig{}, "example.com", name,
+		cutoff)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+	assert.Nil(t, mock.contents("example.com", name))
+}
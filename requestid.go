@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// requestIDHeader carries the ID of the Present or CleanUp call an API
+	// request is made for, to find it in the logs of G-Core support.
+	requestIDHeader = "X-Request-ID"
+	userAgent       = "cert-manager-webhook-gcore"
+)
+
+// challengeIDs identify the challenge and the Present or CleanUp call a
+// context was made for.
+type challengeIDs struct {
+	uid       string
+	requestID string
+}
+
+type challengeIDsKey struct{}
+
+func withChallengeIDs(ctx context.Context, ids challengeIDs) context.Context {
+	return context.WithValue(ctx, challengeIDsKey{}, ids)
+}
+
+// challengeIDsFrom returns the IDs ctx carries, zero outside of challenges.
+func challengeIDsFrom(ctx context.Context) challengeIDs {
+	ids, _ := ctx.Value(challengeIDsKey{}).(challengeIDs)
+	return ids
+}
+
+// requestIDTransport sends the request ID of the challenge a request is made
+// for in the X-Request-ID header and its UID in the User-Agent.
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ids := challengeIDsFrom(req.Context())
+	req = req.Clone(req.Context())
+	if ids.requestID != "" {
+		req.Header.Set(requestIDHeader, ids.requestID)
+	}
+	if ids.uid != "" {
+		req.Header.Set("User-Agent", userAgent+" challenge/"+ids.uid)
+	} else {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	return t.base.RoundTrip(req)
+}
+
+func newRequestID() string {
+	return uuid.NewString()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDHeaders(t *testing.T) {
+	var mu sync.Mutex
+	var requests []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Header.Clone())
+		mu.Unlock()
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error":"forbidden"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	solver := &gcoreDNSProviderSolver{failures: newFailureLog(1)}
+	ch := challenge("_acme-challenge.example.com.", "key",
+		`{"apiToken":"t","apiUrl":"`+server.URL+`","zoneDiscovery":"probe"}`)
+	ch.UID = "4b1c0c41-5d0e-4f53-9f4e-2c1d0a6f2d11"
+	require.Error(t, solver.Present(ch))
+	require.Error(t, solver.Present(ch))
+
+	failures := solver.failures.List()
+	require.Len(t, failures, 1)
+	requestID := failures[0].RequestID
+	require.NotEmpty(t, requestID)
+	require.NotEmpty(t, requests)
+	// The second call got a request ID of its own, sent with all its requests.
+	last := requests[len(requests)-1]
+	assert.Equal(t, requestID, last.Get(requestIDHeader))
+	assert.NotEqual(t, requestID, requests[0].Get(requestIDHeader))
+	for _, header := range requests {
+		assert.Equal(t, "cert-manager-webhook-gcore challenge/4b1c0c41-5d0e-4f53-9f4e-2c1d0a6f2d11",
+			header.Get("User-Agent"))
+		assert.Len(t, header.Get(requestIDHeader), 36)
+	}
+}
+
+func TestRequestIDTransportOutsideChallenges(t *testing.T) {
+	t.Parallel()
+
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: &requestIDTransport{base: http.DefaultTransport}}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, userAgent, header.Get("User-Agent"))
+	assert.Empty(t, header.Get(requestIDHeader))
+}
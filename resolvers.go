@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+	"github.com/miekg/dns"
+)
+
+// dnsResolversFlag overrides the recursive nameservers of the DNS lookups the
+// webhook makes itself, in clusters with split-horizon DNS or IPv6-only
+// networking where the default resolvers give wrong answers.
+const dnsResolversFlag = "--dns-resolvers"
+
+// dnsResolvers returns the nameservers of the --dns-resolvers flags in args,
+// each a comma separated list of IP or IP:port, as host:port with port 53 by
+// default, and args without the --dns-resolvers flags.
+func dnsResolvers(args []string) ([]string, []string, error) {
+	values, rest, err := cutFlag(args, dnsResolversFlag)
+	if err != nil {
+		return nil, nil, err
+	}
+	var resolvers []string
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			resolver, err := parseResolver(strings.TrimSpace(entry))
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", dnsResolversFlag, err)
+			}
+			resolvers = append(resolvers, resolver)
+		}
+	}
+	return resolvers, rest, nil
+}
+
+// parseResolver returns the IP or IP:port resolver as host:port.
+func parseResolver(resolver string) (string, error) {
+	host, port := resolver, "53"
+	if h, p, err := net.SplitHostPort(resolver); err == nil {
+		host, port = h, p
+	}
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("%q is not an IP address or IP:port", resolver)
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", fmt.Errorf("%q has an invalid port", resolver)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// recursiveNameservers returns the nameservers recursive lookups are sent to,
+// those of --dns-resolvers or else cert-manager's.
+func (c *gcoreDNSProviderSolver) recursiveNameservers() []string {
+	if len(c.dnsResolvers) > 0 {
+		return c.dnsResolvers
+	}
+	return util.RecursiveNameservers
+}
+
+// lookupNSVia resolves the nameservers delegated for zone by asking the
+// recursive nameservers.
+func lookupNSVia(nameservers []string) func(ctx context.Context, zone string) ([]*net.NS, error) {
+	return func(ctx context.Context, zone string) ([]*net.NS, error) {
+		msg, err := util.DNSQuery(ctx, dns.Fqdn(zone), dns.TypeNS, nameservers, true)
+		if err != nil {
+			return nil, err
+		}
+		if msg.Rcode != dns.RcodeSuccess {
+			return nil, fmt.Errorf("%s", dns.RcodeToString[msg.Rcode])
+		}
+		var records []*net.NS
+		for _, rr := range msg.Answer {
+			if ns, ok := rr.(*dns.NS); ok {
+				records = append(records, &net.NS{Host: ns.Ns})
+			}
+		}
+		return records, nil
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSResolvers(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		args      []string
+		resolvers []string
+		rest      []string
+		errMsg    string
+	}{
+		{desc: "none", args: []string{"--v=2"}, rest: []string{"--v=2"}},
+		{desc: "default port", args: []string{"--dns-resolvers=1.1.1.1,2606:4700:4700::1111"},
+			resolvers: []string{"1.1.1.1:53", "[2606:4700:4700::1111]:53"}},
+		{desc: "explicit port", args: []string{"--dns-resolvers", "10.0.0.10:5353, [fd00::10]:53", "--v=2"},
+			resolvers: []string{"10.0.0.10:5353", "[fd00::10]:53"}, rest: []string{"--v=2"}},
+		{desc: "repeated", args: []string{"--dns-resolvers=1.1.1.1", "--dns-resolvers=8.8.8.8"},
+			resolvers: []string{"1.1.1.1:53", "8.8.8.8:53"}},
+		{desc: "host name", args: []string{"--dns-resolvers=dns.google"},
+			errMsg: `--dns-resolvers: "dns.google" is not an IP address or IP:port`},
+		{desc: "bad port", args: []string{"--dns-resolvers=1.1.1.1:dns"},
+			errMsg: `--dns-resolvers: "1.1.1.1:dns" has an invalid port`},
+		{desc: "empty entry", args: []string{"--dns-resolvers=1.1.1.1,"},
+			errMsg: `--dns-resolvers: "" is not an IP address or IP:port`},
+		{desc: "missing value", args: []string{"--dns-resolvers"}, errMsg: "--dns-resolvers needs a value"},
+	}
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			resolvers, rest, err := dnsResolvers(test.args)
+			if test.errMsg != "" {
+				assert.EqualError(t, err, test.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.resolvers, resolvers)
+			assert.Equal(t, test.rest, rest)
+		})
+	}
+}
+
+func TestRecursiveNameservers(t *testing.T) {
+	assert.NotEmpty(t, (&gcoreDNSProviderSolver{}).recursiveNameservers())
+	solver := &gcoreDNSProviderSolver{dnsResolvers: []string{"[fd00::10]:53"}}
+	assert.Equal(t, []string{"[fd00::10]:53"}, solver.recursiveNameservers())
+}
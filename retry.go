@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/semaphore"
+)
+
+// retryAttempts and retryBackoff bound how API calls failing with a
+// retryable error are repeated, unless the config sets a retry policy.
+var (
+	retryAttempts = 3
+	retryBackoff  = 500 * time.Millisecond
+)
+
+// defaultMaxBackoff caps the wait between retries.
+const defaultMaxBackoff = 30 * time.Second
+
+// retryPolicy controls how often and how far apart failing calls are repeated.
+type retryPolicy struct {
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	// jitter waits a random time between half and all of the backoff.
+	jitter bool
+}
+
+// defaultRetryPolicy returns the policy used when the config sets none.
+func defaultRetryPolicy() retryPolicy {
+	return retryPolicy{maxRetries: retryAttempts - 1, initialBackoff: retryBackoff, maxBackoff: defaultMaxBackoff}
+}
+
+// backoff returns the wait before the given retry, starting at 1. It
+// doubles with every retry up to maxBackoff.
+func (p retryPolicy) backoff(retry int) time.Duration {
+	d := p.initialBackoff
+	for i := 1; i < retry && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, p.maxBackoff)
+	if p.jitter {
+		d = d/2 + jitter(d-d/2)
+	}
+	return d
+}
+
+// idleConnCloser is implemented by clients that can drop their pooled
+// connections, so that a retry does not reuse a broken connection.
+type idleConnCloser interface {
+	CloseIdleConnections()
+}
+
+// errAPIUnreachable marks errors of calls that could not reach the API at all.
+var errAPIUnreachable = errors.New("G-Core API is unreachable")
+
+// retryingAPI wraps a dnsAPI and repeats calls failing with a retryable error
+// according to policy, nil meaning defaultRetryPolicy.
+// Calls that can't reach apiURL fail with errAPIUnreachable, and while
+// breaker, if any, is open calls fail with errProviderUnavailable. Each
+// attempt holds a slot of limiter, if any, while it runs.
+type retryingAPI struct {
+	api     dnsAPI
+	apiURL  string
+	policy  *retryPolicy
+	breaker *circuitBreaker
+	limiter *semaphore.Weighted
+}
+
+// retryCall runs call until it succeeds, fails with an error that is not
+// retryable, runs out of attempts or ctx is done. The attempts are traced
+// in a span named after the API operation op.
+func retryCall[T any](ctx context.Context, r *retryingAPI, op string,
+	call func(context.Context) (T, error)) (res T, err error) {
+	ctx, span := startSpan(ctx, "gcore.api."+op)
+	retries := 0
+	defer func() {
+		span.SetAttributes(attribute.Int("gcore.api.retries", retries))
+		endSpan(span, err)
+	}()
+	policy := defaultRetryPolicy()
+	if r.policy != nil {
+		policy = *r.policy
+	}
+	ctx, hint := withRetryAfterHint(ctx)
+	for retry := 0; ; retry++ {
+		retries = retry
+		if r.breaker != nil {
+			if ok, open := r.breaker.allow(time.Now()); !ok {
+				var zero T
+				return zero, fmt.Errorf("%w at %s: circuit breaker open for %s after repeated failures",
+					errProviderUnavailable, r.apiURL, open.Round(time.Second))
+			}
+		}
+		if r.limiter != nil {
+			if err := r.limiter.Acquire(ctx, 1); err != nil {
+				if r.breaker != nil {
+					r.breaker.abort()
+				}
+				var zero T
+				return zero, fmt.Errorf("wait for an API call slot: %w", err)
+			}
+		}
+		res, err := call(ctx)
+		if r.limiter != nil {
+			r.limiter.Release(1)
+		}
+		switch {
+		case r.breaker == nil:
+		case ctx.Err() != nil:
+			// A call cut short by ctx tells nothing about the API.
+			r.breaker.abort()
+		default:
+			r.breaker.record(err != nil && isProviderFailure(err), time.Now())
+		}
+		if err != nil && isUnreachable(err) {
+			return res, fmt.Errorf("%w at %s: %w", errAPIUnreachable, r.apiURL, err)
+		}
+		// A call timing out because ctx is done is not repeated.
+		if err == nil || retry >= policy.maxRetries || ctx.Err() != nil || !isRetryable(err) {
+			return res, err
+		}
+		if closer, ok := r.api.(idleConnCloser); ok {
+			closer.CloseIdleConnections()
+		}
+		wait := policy.backoff(retry + 1)
+		if retryAfter, ok := hint.take(); ok && isRateLimited(err) {
+			wait = retryAfter
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+				return res, fmt.Errorf("%w (retry after %s exceeds the deadline)", err, wait)
+			}
+		}
+		if errSleep := sleepContext(ctx, wait); errSleep != nil {
+			return res, fmt.Errorf("%w (retry aborted: %v)", err, errSleep)
+		}
+	}
+}
+
+// isRetryable reports whether err is a transient failure, such as an HTTP/2
+// GOAWAY or a connection reset by the API's load balancer, a request timeout,
+// a rate limit or a 5xx answer of the API. Creating an RRSet whose 5xx answer hid that it
+// was created is safe to repeat, the conflict is resolved like any other.
+func isRetryable(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	var apiErr dnssdk.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests ||
+			apiErr.StatusCode >= http.StatusInternalServerError && apiErr.StatusCode != http.StatusNotImplemented
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	msg := err.Error()
+	for _, transient := range []string{"GOAWAY", "connection reset", "broken pipe", "http2: client connection lost"} {
+		if strings.Contains(msg, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnreachable reports whether err means no connection to the API could be
+// made, e.g. because its host name does not resolve or the port is closed.
+func isUnreachable(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EHOSTUNREACH) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// do adapts calls returning only an error to retryCall.
+func (r *retryingAPI) do(ctx context.Context, op string, call func(context.Context) error) error {
+	_, err := retryCall(ctx, r, op, func(ctx context.Context) (struct{}, error) { return struct{}{}, call(ctx) })
+	return err
+}
+
+func (r *retryingAPI) Zone(ctx context.Context, name string) (dnssdk.Zone, error) {
+	return retryCall(ctx, r, "Zone", func(ctx context.Context) (dnssdk.Zone, error) { return r.api.Zone(ctx, name) })
+}
+
+func (r *retryingAPI) ZoneDetails(ctx context.Context, name string) (zoneDetails, error) {
+	return retryCall(ctx, r, "ZoneDetails", func(ctx context.Context) (zoneDetails, error) { return r.api.ZoneDetails(ctx, name) })
+}
+
+func (r *retryingAPI) ZonesWithParam(ctx context.Context, param dnssdk.ZonesParam) (dnssdk.ListZones, error) {
+	return retryCall(ctx, r, "ZonesWithParam", func(ctx context.Context) (dnssdk.ListZones, error) { return r.api.ZonesWithParam(ctx, param) })
+}
+
+func (r *retryingAPI) ZoneNameservers(ctx context.Context, name string) ([]string, error) {
+	return retryCall(ctx, r, "ZoneNameservers", func(ctx context.Context) ([]string, error) { return r.api.ZoneNameservers(ctx, name) })
+}
+
+func (r *retryingAPI) CreateZone(ctx context.Context, name string) (uint64, error) {
+	return retryCall(ctx, r, "CreateZone", func(ctx context.Context) (uint64, error) { return r.api.CreateZone(ctx, name) })
+}
+
+func (r *retryingAPI) EnableZone(ctx context.Context, name string) error {
+	return r.do(ctx, "EnableZone", func(ctx context.Context) error { return r.api.EnableZone(ctx, name) })
+}
+
+func (r *retryingAPI) RRSet(ctx context.Context, zone, name, recordType string) (dnssdk.RRSet, error) {
+	return retryCall(ctx, r, "RRSet", func(ctx context.Context) (dnssdk.RRSet, error) { return r.api.RRSet(ctx, zone, name, recordType) })
+}
+
+func (r *retryingAPI) RRSetWithVersion(ctx context.Context, zone, name, recordType string) (dnssdk.RRSet, rrsetVersion, error) {
+	type versioned struct {
+		rrset   dnssdk.RRSet
+		version rrsetVersion
+	}
+	res, err := retryCall(ctx, r, "RRSetWithVersion", func(ctx context.Context) (versioned, error) {
+		rrset, version, err := r.api.RRSetWithVersion(ctx, zone, name, recordType)
+		return versioned{rrset, version}, err
+	})
+	return res.rrset, res.version, err
+}
+
+func (r *retryingAPI) CreateRRSet(ctx context.Context, zone, name, recordType string, record dnssdk.RRSet) error {
+	return r.do(ctx, "CreateRRSet", func(ctx context.Context) error { return r.api.CreateRRSet(ctx, zone, name, recordType, record) })
+}
+
+func (r *retryingAPI) UpdateRRSet(ctx context.Context, zone, name, recordType string, record dnssdk.RRSet) error {
+	return r.do(ctx, "UpdateRRSet", func(ctx context.Context) error { return r.api.UpdateRRSet(ctx, zone, name, recordType, record) })
+}
+
+func (r *retryingAPI) UpdateRRSetIfMatch(ctx context.Context, zone, name, recordType string,
+	record dnssdk.RRSet, version rrsetVersion) error {
+	return r.do(ctx, "UpdateRRSetIfMatch", func(ctx context.Context) error {
+		return r.api.UpdateRRSetIfMatch(ctx, zone, name, recordType, record, version)
+	})
+}
+
+func (r *retryingAPI) DeleteRRSetIfMatch(ctx context.Context, zone, name, recordType string, version rrsetVersion) error {
+	return r.do(ctx, "DeleteRRSetIfMatch", func(ctx context.Context) error { return r.api.DeleteRRSetIfMatch(ctx, zone, name, recordType, version) })
+}
+
+func (r *retryingAPI) DeleteRRSet(ctx context.Context, zone, name, recordType string) error {
+	return r.do(ctx, "DeleteRRSet", func(ctx context.Context) error { return r.api.DeleteRRSet(ctx, zone, name, recordType) })
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyAPI fails the first zone lookups with err.
+type flakyAPI struct {
+	*mockSDK
+	failures int
+	err      error
+	closed   int
+}
+
+func (f *flakyAPI) Zone(ctx context.Context, name string) (dnssdk.Zone, error) {
+	if f.failures > 0 {
+		f.failures--
+		return dnssdk.Zone{}, f.err
+	}
+	return f.mockSDK.Zone(ctx, name)
+}
+
+func (f *flakyAPI) CloseIdleConnections() {
+	f.closed++
+}
+
+func TestRetryTransportErrors(t *testing.T) {
+	setForTest(t, &retryBackoff, time.Millisecond)
+	testCases := []struct {
+		desc    string
+		err     error
+		retried bool
+	}{
+		{
+			desc:    "connection reset",
+			err:     &url.Error{Op: "Get", URL: "https://api.gcore.com", Err: syscall.ECONNRESET},
+			retried: true,
+		},
+		{
+			desc:    "http2 goaway",
+			err:     fmt.Errorf("send request: %w", errors.New("http2: server sent GOAWAY and closed the connection")),
+			retried: true,
+		},
+		{
+			desc: "api error",
+			err:  dnssdk.APIError{StatusCode: http.StatusForbidden, Message: "forbidden"},
+		},
+		{
+			desc:    "server error",
+			err:     fmt.Errorf("get zone: %w", dnssdk.APIError{StatusCode: http.StatusServiceUnavailable}),
+			retried: true,
+		},
+		{
+			desc:    "gateway timeout",
+			err:     dnssdk.APIError{StatusCode: http.StatusGatewayTimeout, Message: "upstream timed out"},
+			retried: true,
+		},
+		{
+			desc: "not implemented",
+			err:  dnssdk.APIError{StatusCode: http.StatusNotImplemented},
+		},
+		{
+			desc:    "request timeout",
+			err:     &url.Error{Op: "Get", URL: "https://api.gcore.com", Err: context.DeadlineExceeded},
+			retried: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			flaky := &flakyAPI{mockSDK: newMockSDK("example.com"), failures: 1, err: test.err}
+			solver := &gcoreDNSProviderSolver{
+				newSDK: func(gcoreDNSProviderConfig, string) (dnsAPI, error) { return flaky, nil },
+			}
+
+			err := solver.Present(challenge("_acme-challenge.example.com.", "token-A",
+				`{"apiToken":"t","zoneDiscovery":"probe"}`))
+			if !test.retried {
+				assert.Error(t, err)
+				assert.Zero(t, flaky.closed)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 1, flaky.closed, "connections should be dropped before retrying")
+			assert.Equal(t, []string{"token-A"}, flaky.contents("example.com", "_acme-challenge.example.com"))
+		})
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	setForTest(t, &retryBackoff, time.Millisecond)
+	flaky := &flakyAPI{mockSDK: newMockSDK("example.com"), failures: retryAttempts, err: syscall.ECONNRESET}
+	api := &retryingAPI{api: flaky}
+
+	_, err := api.Zone(context.Background(), "example.com")
+	assert.ErrorIs(t, err, syscall.ECONNRESET)
+	assert.Equal(t, retryAttempts-1, flaky.closed)
+}
+
+func TestRetryStopsWhenContextDone(t *testing.T) {
+	setForTest(t, &retryBackoff, time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	flaky := &flakyAPI{mockSDK: newMockSDK("example.com"), failures: retryAttempts,
+		err: &url.Error{Op: "Get", URL: "https://api.gcore.com", Err: context.Canceled}}
+	api := &retryingAPI{api: flaky}
+
+	_, err := api.Zone(ctx, "example.com")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, flaky.closed, "a call failing because ctx is done is not repeated")
+}
+
+func TestRetryServerErrors(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`{"error":"bad gateway"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"example.com"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	sdk, err := newSDKClient(gcoreDNSProviderConfig{ApiUrl: server.URL}, "t")
+	require.NoError(t, err)
+	api := &retryingAPI{api: sdk, apiURL: server.URL,
+		policy: &retryPolicy{maxRetries: 2, initialBackoff: time.Millisecond, maxBackoff: time.Millisecond}}
+	details, err := api.ZoneDetails(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", details.Name)
+	assert.Equal(t, 3, calls)
+}
+
+func TestForceHTTP1(t *testing.T) {
+	sdk, err := newSDKClient(gcoreDNSProviderConfig{ForceHTTP1: true}, "t")
+	require.NoError(t, err)
+	transport := sdk.(*gcoreClient).HTTPClient.Transport.(*requestIDTransport).base.(*rateLimitTransport).base.(*http.Transport)
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSNextProto)
+	assert.Empty(t, transport.TLSNextProto)
+}
+
+func TestPresentUnreachableAPI(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	apiURL := server.URL
+	server.Close()
+
+	solver := &gcoreDNSProviderSolver{}
+	err := solver.Present(challenge("_acme-challenge.www.example.com.", "key",
+		fmt.Sprintf(`{"apiToken":"t","apiUrl":%q}`, apiURL)))
+	require.ErrorIs(t, err, errAPIUnreachable)
+	assert.ErrorContains(t, err, "G-Core API is unreachable at "+apiURL)
+	assert.NotContains(t, err.Error(), "not found")
+
+	assert.True(t, isUnreachable(&net.DNSError{Err: "no such host", Name: "api.invalid"}))
+	assert.True(t, isUnreachable(syscall.ECONNREFUSED))
+	assert.False(t, isUnreachable(dnssdk.APIError{StatusCode: http.StatusBadGateway}))
+	assert.False(t, isUnreachable(syscall.ECONNRESET))
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := retryPolicy{maxRetries: 5, initialBackoff: time.Second, maxBackoff: 5 * time.Second}
+	var got []time.Duration
+	for retry := 1; retry <= 5; retry++ {
+		got = append(got, policy.backoff(retry))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, got)
+
+	policy.jitter = true
+	for i := 0; i < 20; i++ {
+		d := policy.backoff(2)
+		assert.GreaterOrEqual(t, d, time.Second)
+		assert.LessOrEqual(t, d, 2*time.Second)
+	}
+
+	zero := 0
+	assert.Equal(t, retryPolicy{initialBackoff: 200 * time.Millisecond, maxBackoff: 2 * time.Second, jitter: true},
+		gcoreDNSProviderConfig{MaxRetries: &zero, InitialBackoff: 200, MaxBackoff: 2000, RetryJitter: true}.retryPolicy())
+	assert.Equal(t, defaultRetryPolicy(), gcoreDNSProviderConfig{}.retryPolicy())
+
+	tooMany := 11
+	assert.ErrorContains(t, gcoreDNSProviderConfig{MaxRetries: &tooMany}.Validate(), "maxRetries must be between 0 and 10")
+	assert.ErrorContains(t, gcoreDNSProviderConfig{InitialBackoff: -1}.Validate(), "must not be negative")
+	assert.ErrorContains(t, gcoreDNSProviderConfig{InitialBackoff: 2000, MaxBackoff: 1000}.Validate(),
+		"initialBackoff 2000 must not exceed maxBackoff 1000")
+}
+
+func TestPresentMaxRetries(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		cfg      string
+		failures int
+		wantErr  bool
+	}{
+		{desc: "retries disabled", cfg: `{"apiToken":"t","zoneDiscovery":"probe","maxRetries":0}`,
+			failures: 1, wantErr: true},
+		{desc: "within retries", cfg: `{"apiToken":"t","zoneDiscovery":"probe","maxRetries":4,"initialBackoff":1}`,
+			failures: 4},
+		{desc: "beyond retries", cfg: `{"apiToken":"t","zoneDiscovery":"probe","maxRetries":4,"initialBackoff":1}`,
+			failures: 5, wantErr: true},
+	}
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			flaky := &flakyAPI{mockSDK: newMockSDK("example.com"), failures: test.failures, err: syscall.ECONNRESET}
+			solver := &gcoreDNSProviderSolver{
+				newSDK: func(gcoreDNSProviderConfig, string) (dnsAPI, error) { return flaky, nil },
+			}
+			err := solver.Present(challenge("_acme-challenge.example.com.", "key", test.cfg))
+			if test.wantErr {
+				assert.ErrorIs(t, err, syscall.ECONNRESET)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+)
+
+// apiSchema converts challenge values to and from the RRSet record payload of
+// the G-Core API.
+type apiSchema struct {
+	// encode builds the record carrying a TXT value.
+	encode func(value string) dnssdk.ResourceRecord
+	// decode extracts the TXT value of a record, ok is false for records
+	// whose payload does not hold a plain TXT value.
+	decode func(record dnssdk.ResourceRecord) (value string, ok bool)
+}
+
+// recordSchema is the payload of G-Core API v2 records, which carry the TXT
+// value as the single element of content. Records go through it only, so
+// another payload shape is a change of this variable.
+var recordSchema = apiSchema{
+	encode: func(value string) dnssdk.ResourceRecord {
+		return dnssdk.ResourceRecord{Content: []any{value}, Enabled: true}
+	},
+	decode: func(record dnssdk.ResourceRecord) (string, bool) {
+		if len(record.Content) == 0 {
+			return "", false
+		}
+		value, ok := record.Content[0].(string)
+		return value, ok
+	},
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordSchema(t *testing.T) {
+	// a schema storing TXT values quoted in a list of character strings,
+	// to make sure the solver only goes through the adapter
+	quoted := apiSchema{
+		encode: func(value string) dnssdk.ResourceRecord {
+			return dnssdk.ResourceRecord{Content: []any{`"` + value + `"`}, Enabled: true}
+		},
+		decode: func(record dnssdk.ResourceRecord) (string, bool) {
+			if len(record.Content) == 0 {
+				return "", false
+			}
+			value, ok := record.Content[0].(string)
+			return strings.Trim(value, `"`), ok
+		},
+	}
+
+	const fqdn = "_acme-challenge.example.com."
+	testCases := []struct {
+		desc   string
+		schema apiSchema
+		stored string
+	}{
+		{desc: "v2", schema: recordSchema, stored: "token-A"},
+		{desc: "quoted", schema: quoted, stored: `"token-A"`},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			setForTest(t, &recordSchema, test.schema)
+			mock := newMockSDK("example.com")
+			solver := solverWithMock(mock)
+			cfg := `{"apiToken":"t","strictCleanup":true}`
+
+			require.NoError(t, solver.Present(challenge(fqdn, "token-A", cfg)))
+			assert.Equal(t, []string{test.stored}, mock.contents("example.com", "_acme-challenge.example.com"))
+
+			require.NoError(t, solver.CleanUp(challenge(fqdn, "token-A", cfg)))
+			assert.Empty(t, mock.contents("example.com", "_acme-challenge.example.com"))
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+)
+
+// writeProbeLabel names the temporary TXT record created by the write scope probe.
+const writeProbeLabel = "_cert-manager-write-probe"
+
+// writeProbeAttempts bounds the probes run again after a replica probing the
+// same zone at once deleted the shared probe record first.
+const writeProbeAttempts = 3
+
+// errNoWriteScope is returned when the credential may read but not change a zone.
+var errNoWriteScope = errors.New("credential has no write access")
+
+// scopeCacheKey identifies a zone that one G-Core account proved to be able to write to.
+type scopeCacheKey struct {
+	account string
+	zone    string
+}
+
+// verifyWriteScope makes sure the credential may change records of zone, or
+// of the configured scratch zone, before a challenge is written to it. The
+// probe creates and deletes a temporary TXT record; success is remembered
+// per account and zone so the probe runs only once per cache period.
+func (c *gcoreDNSProviderSolver) verifyWriteScope(ctx context.Context, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig, zone string) error {
+	if cfg.ScratchZone != "" {
+		zone = asciiDomain(cfg.ScratchZone)
+	}
+	key := scopeCacheKey{account: cfg.account, zone: zone}
+	if _, ok := c.writeScope.Get(key); ok {
+		return nil
+	}
+
+	name := writeProbeLabel + "." + zone
+	probe := dnssdk.RRSet{TTL: cfg.ttlForZone(zone), Records: []dnssdk.ResourceRecord{recordSchema.encode("probe")}}
+	for attempt := 1; ; attempt++ {
+		err := sdk.CreateRRSet(ctx, zone, name, txtType, probe)
+		if err != nil && !isRRSetExists(err) {
+			return writeScopeError(zone, "create", err)
+		}
+		// A probe left behind by an interrupted run is removed all the same,
+		// which proves write access as well. One that is gone was removed by
+		// another replica probing at the same time, which proves nothing.
+		err = sdk.DeleteRRSet(ctx, zone, name, txtType)
+		if isNotFound(err) && attempt < writeProbeAttempts {
+			continue
+		}
+		if err != nil {
+			return writeScopeError(zone, "delete", err)
+		}
+		// A dry run proves nothing, nothing was written.
+		if !cfg.DryRun {
+			c.writeScope.Set(key, struct{}{})
+		}
+		return nil
+	}
+}
+
+func writeScopeError(zone, op string, err error) error {
+	var apiErr dnssdk.APIError
+	if errors.As(err, &apiErr) &&
+		(apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+		return fmt.Errorf("zone %s: %w: %s probe record denied: %s", zone, errNoWriteScope, op, apiErr.Message)
+	}
+	return fmt.Errorf("zone %s: %s probe record: %w", zone, op, err)
+}
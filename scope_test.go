@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readOnlyAPI denies every write like a token without write scope.
+type readOnlyAPI struct {
+	*mockSDK
+}
+
+func (r readOnlyAPI) CreateRRSet(context.Context, string, string, string, dnssdk.RRSet) error {
+	return dnssdk.APIError{StatusCode: http.StatusForbidden, Message: "permission denied"}
+}
+
+func (r readOnlyAPI) DeleteRRSet(context.Context, string, string, string) error {
+	return dnssdk.APIError{StatusCode: http.StatusForbidden, Message: "permission denied"}
+}
+
+func TestVerifyWriteScope(t *testing.T) {
+	t.Parallel()
+
+	t.Run("denied", func(t *testing.T) {
+		t.Parallel()
+		m := newMockSDK("example.com")
+		solver := &gcoreDNSProviderSolver{
+			newSDK: func(gcoreDNSProviderConfig, string) (dnsAPI, error) { return readOnlyAPI{m}, nil },
+		}
+		err := solver.Present(challenge("_acme-challenge.example.com.", "key",
+			`{"apiToken":"token","verifyWriteScope":true}`))
+		require.ErrorIs(t, err, errNoWriteScope)
+		assert.ErrorContains(t, err, "zone example.com")
+	})
+
+	t.Run("granted once per zone", func(t *testing.T) {
+		t.Parallel()
+		m := newMockSDK("example.com")
+		solver := solverWithMock(m)
+		solver.writeScope = newCache[scopeCacheKey, struct{}](time.Minute, 10)
+		cfg := `{"apiToken":"token","verifyWriteScope":true}`
+		require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "a", cfg)))
+		require.NoError(t, solver.Present(challenge("_acme-challenge.www.example.com.", "b", cfg)))
+
+		assert.Nil(t, m.contents("example.com", writeProbeLabel+".example.com"))
+		assert.Equal(t, []string{"a"}, m.contents("example.com", "_acme-challenge.example.com"))
+		// one probe plus the two challenge records
+		assert.Equal(t, 3, m.creates)
+	})
+
+	t.Run("scratch zone", func(t *testing.T) {
+		t.Parallel()
+		m := newMockSDK("example.com", "scratch.example.net")
+		solver := solverWithMock(m)
+		sdk, cfg, err := solver.initSDK(t.Context(), challenge("_acme-challenge.example.com.", "a",
+			`{"apiToken":"token","verifyWriteScope":true,"scratchZone":"scratch.example.net."}`))
+		require.NoError(t, err)
+		require.NoError(t, solver.verifyWriteScope(context.Background(), sdk, cfg, "example.com"))
+		assert.Equal(t, 1, m.creates)
+		assert.Nil(t, m.contents("scratch.example.net", writeProbeLabel+".scratch.example.net"))
+	})
+
+	t.Run("leftover probe", func(t *testing.T) {
+		t.Parallel()
+		m := newMockSDK("example.com")
+		solver := solverWithMock(m)
+		sdk, cfg, err := solver.initSDK(t.Context(), challenge("_acme-challenge.example.com.", "a",
+			`{"apiToken":"token","verifyWriteScope":true}`))
+		require.NoError(t, err)
+		require.NoError(t, sdk.CreateRRSet(context.Background(), "example.com", writeProbeLabel+".example.com",
+			txtType, dnssdk.RRSet{Records: []dnssdk.ResourceRecord{{Content: []any{"probe"}}}}))
+		require.NoError(t, solver.verifyWriteScope(context.Background(), sdk, cfg, "example.com"))
+		assert.Nil(t, m.contents("example.com", writeProbeLabel+".example.com"))
+	})
+}
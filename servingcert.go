@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// tlsCertDirFlag names the directory holding the serving certificate as
+	// tls.crt and tls.key, the layout of a mounted kubernetes.io/tls Secret.
+	// It is a shorthand of the --tls-cert-file and --tls-private-key-file
+	// flags of the webhook library, which reloads the files when they change,
+	// so a certificate renewed by cert-manager is served without a restart.
+	tlsCertDirFlag = "--tls-cert-dir"
+
+	tlsCertFileFlag       = "--tls-cert-file"
+	tlsPrivateKeyFileFlag = "--tls-private-key-file"
+
+	tlsCertFileName = "tls.crt"
+	tlsKeyFileName  = "tls.key"
+)
+
+// tlsCertDir replaces the --tls-cert-dir flags in args with the
+// --tls-cert-file and --tls-private-key-file flags the webhook library
+// understands. The last one wins.
+func tlsCertDir(args []string) ([]string, error) {
+	values, rest, err := cutFlag(args, tlsCertDirFlag)
+	if err != nil || len(values) == 0 {
+		return rest, err
+	}
+	if slices.ContainsFunc(rest, func(arg string) bool {
+		return isFlag(arg, tlsCertFileFlag) || isFlag(arg, tlsPrivateKeyFileFlag)
+	}) {
+		return nil, fmt.Errorf("%s can't be combined with %s or %s", tlsCertDirFlag, tlsCertFileFlag,
+			tlsPrivateKeyFileFlag)
+	}
+	dir := values[len(values)-1]
+	return append(rest,
+		tlsCertFileFlag+"="+filepath.Join(dir, tlsCertFileName),
+		tlsPrivateKeyFileFlag+"="+filepath.Join(dir, tlsKeyFileName),
+	), nil
+}
+
+// isFlag reports whether arg sets flag, as "flag" or "flag=value".
+func isFlag(arg, flag string) bool {
+	return arg == flag || strings.HasPrefix(arg, flag+"=")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+func TestTLSCertDir(t *testing.T) {
+	t.Parallel()
+
+	args, err := tlsCertDir([]string{"--secure-port=443", "--tls-cert-dir", "/old", "--tls-cert-dir=/tls"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--secure-port=443", "--tls-cert-file=/tls/tls.crt",
+		"--tls-private-key-file=/tls/tls.key"}, args)
+
+	args, err = tlsCertDir([]string{"--tls-cert-file=/tls/tls.crt", "--tls-private-key-file=/tls/tls.key"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--tls-cert-file=/tls/tls.crt", "--tls-private-key-file=/tls/tls.key"}, args)
+
+	_, err = tlsCertDir([]string{"--tls-cert-dir=/tls", "--tls-private-key-file", "/tls/tls.key"})
+	assert.ErrorContains(t, err, "--tls-cert-dir can't be combined with --tls-cert-file or --tls-private-key-file")
+	_, err = tlsCertDir([]string{"--tls-cert-dir"})
+	assert.ErrorContains(t, err, "needs a value")
+}
+
+// TestTLSCertDirReload checks that the webhook library picks up a renewed
+// certificate written to the files --tls-cert-dir points it to.
+func TestTLSCertDirReload(t *testing.T) {
+	dir := t.TempDir()
+	writePair := func(host string) []byte {
+		crt, key, err := certutil.GenerateSelfSignedCertKey(host, nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, tlsKeyFileName), key, 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, tlsCertFileName), crt, 0o600))
+		return crt
+	}
+	first := writePair("first.example.com")
+
+	args, err := tlsCertDir([]string{"--tls-cert-dir=" + dir})
+	require.NoError(t, err)
+	var crtFile, keyFile string
+	for _, arg := range args {
+		if value, ok := strings.CutPrefix(arg, tlsCertFileFlag+"="); ok {
+			crtFile = value
+		}
+		if value, ok := strings.CutPrefix(arg, tlsPrivateKeyFileFlag+"="); ok {
+			keyFile = value
+		}
+	}
+	content, err := dynamiccertificates.NewDynamicServingContentFromFiles("serving-cert", crtFile, keyFile)
+	require.NoError(t, err)
+	crt, _ := content.CurrentCertKeyContent()
+	assert.Equal(t, first, crt)
+
+	go content.Run(t.Context(), 1)
+	second := writePair("second.example.com")
+	assert.Eventually(t, func() bool {
+		crt, _ := content.CurrentCertKeyContent()
+		return bytes.Equal(crt, second)
+	}, 10*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+)
+
+// startupCheckTimeout bounds the API calls of the startup check.
+const startupCheckTimeout = 30 * time.Second
+
+// runStartupCheck checks in the background whether the ambient
+// GCORE_API_TOKEN is accepted and may list zones, and logs the outcome, so
+// that an expired token or one without access to DNS shows at startup rather
+// than in the first failed challenge. The check only reads: write access is
+// probed by the challenges of issuers setting verifyWriteScope. It doesn't
+// hold up the webhook, whose readiness GCORE_READINESS_CHECK_INTERVAL ties to
+// the token.
+func (c *gcoreDNSProviderSolver) runStartupCheck(stopCh <-chan struct{}) error {
+	sdk, _, err := c.ambientAPI(startupCheckEnvVar)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), startupCheckTimeout)
+	go func() {
+		defer cancel()
+		if err := c.checkToken(ctx, sdk); err != nil {
+			c.log.Error(redactError(classifyError(err)), "startup token check failed")
+			return
+		}
+		c.log.Info("startup token check passed")
+	}()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return nil
+}
+
+// ambientAPI returns an API client authenticated with GCORE_API_TOKEN, for
+// the webhook's own work outside of challenges, which setting enabled.
+func (c *gcoreDNSProviderSolver) ambientAPI(setting string) (dnsAPI, gcoreDNSProviderConfig, error) {
+	token := os.Getenv(apiTokenEnvVar)
+	if token == "" {
+		return nil, gcoreDNSProviderConfig{}, fmt.Errorf("%s is set but %s is not", setting, apiTokenEnvVar)
+	}
+	cfg := gcoreDNSProviderConfig{ApiToken: token}
+	cfg.setDefaults()
+	cfg.account = accountKey(cfg.ApiUrl, token)
+	api, err := c.sdkClient(cfg, token)
+	if err != nil {
+		return nil, cfg, err
+	}
+	retrying := &retryingAPI{api: api, apiURL: cfg.ApiUrl, breaker: c.breakers.get(cfg.ApiUrl)}
+	wrapped := c.dryRunWrap(context.Background(), c.audit.wrap(retrying, auditActor{Account: cfg.account}), &cfg)
+	return wrapped, cfg, nil
+}
+
+// checkToken lists a zone of the token's account, and looks up
+// GCORE_STARTUP_CHECK_ZONE if it is set.
+func (c *gcoreDNSProviderSolver) checkToken(ctx context.Context, sdk dnsAPI) error {
+	_, err := sdk.ZonesWithParam(ctx, dnssdk.ZonesParam{Limit: 1})
+	var apiErr dnssdk.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized:
+			return fmt.Errorf("%s is invalid or expired: %w", apiTokenEnvVar, err)
+		case http.StatusForbidden:
+			return fmt.Errorf("%s may not list zones: %w", apiTokenEnvVar, err)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("list zones: %w", err)
+	}
+	zone := strings.Trim(c.startupCheckZone, ".")
+	if zone == "" {
+		return nil
+	}
+	if _, err := sdk.Zone(ctx, zone); isNotFound(err) {
+		return fmt.Errorf("zone %s is not in the account of %s", zone, apiTokenEnvVar)
+	} else if err != nil {
+		return fmt.Errorf("get zone %s: %w", zone, err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyListAPI fails zone listings with err until it was called failures times.
+type flakyListAPI struct {
+	*mockSDK
+	err      error
+	failures int
+	calls    int
+}
+
+func (f *flakyListAPI) ZonesWithParam(ctx context.Context, param dnssdk.ZonesParam) (dnssdk.ListZones, error) {
+	f.calls++
+	if f.failures < 0 || f.calls <= f.failures {
+		return dnssdk.ListZones{}, f.err
+	}
+	return f.mockSDK.ZonesWithParam(ctx, param)
+}
+
+func TestCheckStartupToken(t *testing.T) {
+	t.Parallel()
+	solverFor := func(api dnsAPI) *gcoreDNSProviderSolver {
+		return &gcoreDNSProviderSolver{newSDK: func(gcoreDNSProviderConfig, string) (dnsAPI, error) { return api, nil }}
+	}
+
+	t.Run("read-only token", func(t *testing.T) {
+		t.Parallel()
+		m := newMockSDK("example.com", "example.net")
+		require.NoError(t, solverFor(m).checkToken(t.Context(), readOnlyAPI{m}))
+		assert.Zero(t, m.creates, "the check writes nothing")
+		assert.Equal(t, 1, m.listCalls)
+	})
+
+	t.Run("configured zone", func(t *testing.T) {
+		t.Parallel()
+		m := newMockSDK("example.com")
+		solver := solverFor(m)
+		solver.startupCheckZone = "example.com."
+		require.NoError(t, solver.checkToken(t.Context(), m))
+		solver.startupCheckZone = "example.org"
+		assert.EqualError(t, solver.checkToken(t.Context(), m), "zone example.org is not in the account of GCORE_API_TOKEN")
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		t.Parallel()
+		api := &flakyListAPI{mockSDK: newMockSDK("example.net"), failures: -1,
+			err: dnssdk.APIError{StatusCode: http.StatusUnauthorized, Message: "token expired"}}
+		err := solverFor(api).checkToken(t.Context(), api)
+		assert.ErrorContains(t, err, "GCORE_API_TOKEN is invalid or expired")
+		assert.Equal(t, 1, api.calls)
+	})
+}
+
+func TestRunStartupCheck(t *testing.T) {
+	t.Setenv(apiTokenEnvVar, "token")
+	logged := make(chan string, 1)
+	api := &flakyListAPI{mockSDK: newMockSDK("example.net"), failures: -1, err: errors.New("no route to host")}
+	solver := &gcoreDNSProviderSolver{
+		newSDK: func(gcoreDNSProviderConfig, string) (dnsAPI, error) { return api, nil },
+		log:    funcr.New(func(_, args string) { logged <- args }, funcr.Options{}),
+	}
+
+	// An unreachable API doesn't hold up the webhook, the failure is logged.
+	require.NoError(t, solver.runStartupCheck(nil))
+	select {
+	case line := <-logged:
+		assert.Contains(t, line, `"msg"="startup token check failed"`)
+		assert.Contains(t, line, "no route to host")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the startup check logged nothing")
+	}
+
+	t.Setenv(apiTokenEnvVar, "")
+	assert.EqualError(t, solver.runStartupCheck(nil), "GCORE_STARTUP_CHECK is set but GCORE_API_TOKEN is not")
+}
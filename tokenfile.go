@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenFile reads an API token from a mounted file, such as a projected
+// secret volume, and reads it again whenever the file changes so a rotated
+// token is picked up without restarting the webhook.
+type tokenFile struct {
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+// Token returns the token, re-reading the file if it changed since the last read.
+func (f *tokenFile) Token(context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", fmt.Errorf("read token file: %w", err)
+	}
+	if f.token != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.token, nil
+	}
+	return f.read(info)
+}
+
+// Reload re-reads the file regardless of whether it looks changed and
+// returns the token it holds now.
+func (f *tokenFile) Reload() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", fmt.Errorf("read token file: %w", err)
+	}
+	return f.read(info)
+}
+
+func (f *tokenFile) read(info os.FileInfo) (string, error) {
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", fmt.Errorf("read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", f.path)
+	}
+	f.token, f.modTime, f.size = token, info.ModTime(), info.Size()
+	return token, nil
+}
+
+// tokenFileTransport authenticates every request with the current token of
+// a token file. A request rejected with 401 is repeated once after forcing
+// a reload, in case the token was rotated since the file was last read.
+type tokenFileTransport struct {
+	base http.RoundTripper
+	file *tokenFile
+}
+
+func (t *tokenFileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.file.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(withAPIKey(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	reloaded, err := t.file.Reload()
+	if err != nil || reloaded == token || (req.Body != nil && req.GetBody == nil) {
+		return resp, nil
+	}
+	retry := withAPIKey(req, reloaded)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return t.base.RoundTrip(retry)
+}
+
+func withAPIKey(req *http.Request, token string) *http.Request {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "APIKey "+token)
+	return req
+}
+
+// tokenFileFor returns the shared reader of an apiTokenFile. Only files below
+// the directory named by GCORE_API_TOKEN_DIR may be used, otherwise an issuer
+// could have any file of the webhook pod sent to its endpoint.
+func (c *gcoreDNSProviderSolver) tokenFileFor(path string) (*tokenFile, error) {
+	if c.tokenFileDir == "" {
+		return nil, fmt.Errorf("apiTokenFile is disabled, set %s on the webhook to the directory of token files",
+			tokenDirEnvVar)
+	}
+	path = filepath.Clean(path)
+	rel, err := filepath.Rel(c.tokenFileDir, path)
+	if err != nil || !filepath.IsAbs(path) || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("apiTokenFile %s is not below %s", path, c.tokenFileDir)
+	}
+	c.tokenFilesMu.Lock()
+	defer c.tokenFilesMu.Unlock()
+	if c.tokenFiles == nil {
+		c.tokenFiles = map[string]*tokenFile{}
+	}
+	if f, ok := c.tokenFiles[path]; ok {
+		return f, nil
+	}
+	f := &tokenFile{path: path}
+	c.tokenFiles[path] = f
+	return f, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("token-1\n"), 0o600))
+	f := &tokenFile{path: path}
+
+	token, err := f.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	require.NoError(t, os.WriteFile(path, []byte("token-22\n"), 0o600))
+	token, err = f.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-22", token)
+
+	require.NoError(t, os.WriteFile(path, []byte("\n"), 0o600))
+	_, err = f.Token(context.Background())
+	assert.ErrorContains(t, err, "is empty")
+
+	require.NoError(t, os.Remove(path))
+	_, err = f.Token(context.Background())
+	assert.ErrorContains(t, err, "read token file")
+}
+
+func TestTokenFileFor(t *testing.T) {
+	dir := t.TempDir()
+	solver := &gcoreDNSProviderSolver{}
+	_, err := solver.tokenFileFor(filepath.Join(dir, "token"))
+	assert.ErrorContains(t, err, "GCORE_API_TOKEN_DIR")
+
+	solver.tokenFileDir = dir
+	f, err := solver.tokenFileFor(filepath.Join(dir, "gcore", "token"))
+	require.NoError(t, err)
+	again, err := solver.tokenFileFor(filepath.Join(dir, "gcore", ".", "token"))
+	require.NoError(t, err)
+	assert.Same(t, f, again)
+
+	for _, path := range []string{
+		"/var/run/secrets/kubernetes.io/serviceaccount/token",
+		filepath.Join(dir, "..", "token"),
+		dir,
+		"token",
+	} {
+		_, err := solver.tokenFileFor(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestTokenFileTransportReloadsOn401(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, `{"ttl":60}`, string(body))
+		if r.Header.Get("Authorization") != "APIKey token-B" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("token-A"), 0o600))
+	f := &tokenFile{path: path}
+	_, err := f.Token(context.Background())
+	require.NoError(t, err)
+	// Rotate the token without the change being visible in the file's
+	// size or modification time, so only the 401 triggers the reload.
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte("token-B"), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now(), info.ModTime()))
+
+	client := &http.Client{Transport: &tokenFileTransport{base: http.DefaultTransport, file: f}}
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"ttl":60}`))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, 2, requests)
+
+	// A token the API keeps rejecting is not retried forever.
+	require.NoError(t, os.WriteFile(path, []byte("token-C"), 0o600))
+	resp, err = client.Post(server.URL, "application/json", strings.NewReader(`{"ttl":60}`))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, 3, requests)
+}
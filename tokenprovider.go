@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+// tokenProvider supplies the API token of one credential source. Providers
+// that cache the token, such as token files and Vault, are shared between
+// challenges, so Token must be safe for concurrent use.
+type tokenProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// staticToken is a token given verbatim, in the config or the environment.
+type staticToken string
+
+func (t staticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// secretToken reads the token from a key of a Kubernetes secret.
+type secretToken struct {
+	solver    *gcoreDNSProviderSolver
+	namespace string
+	name      string
+	key       string
+}
+
+func (s secretToken) Token(ctx context.Context) (string, error) {
+	return s.solver.readSecret(ctx, s.namespace, s.name, s.key)
+}
+
+// tokenProviderSource is a credential source. provider returns nil if the
+// config does not use the source.
+type tokenProviderSource struct {
+	name     string
+	provider func(c *gcoreDNSProviderSolver, cfg gcoreDNSProviderConfig,
+		ch *v1alpha1.ChallengeRequest) (tokenProvider, error)
+}
+
+// tokenProviderSources lists the credential sources in order of precedence.
+// A new source only needs an entry here and a validate case in the config.
+var tokenProviderSources = []tokenProviderSource{
+	{name: "apiToken", provider: func(_ *gcoreDNSProviderSolver, cfg gcoreDNSProviderConfig,
+		_ *v1alpha1.ChallengeRequest) (tokenProvider, error) {
+		if cfg.ApiToken == "" {
+			return nil, nil
+		}
+		return staticToken(cfg.ApiToken), nil
+	}},
+	{name: "apiTokenFile", provider: func(c *gcoreDNSProviderSolver, cfg gcoreDNSProviderConfig,
+		_ *v1alpha1.ChallengeRequest) (tokenProvider, error) {
+		if cfg.APITokenFile == "" {
+			return nil, nil
+		}
+		return c.tokenFileFor(cfg.APITokenFile)
+	}},
+	{name: "apiTokenSecretRef", provider: func(c *gcoreDNSProviderSolver, cfg gcoreDNSProviderConfig,
+		ch *v1alpha1.ChallengeRequest) (tokenProvider, error) {
+		if cfg.APITokenSecretRef.Name == "" {
+			return nil, nil
+		}
+		return c.secretRefToken("apiTokenSecretRef", cfg.APITokenSecretRef, ch)
+	}},
+	{name: "apiKeySecretRef", provider: func(c *gcoreDNSProviderSolver, cfg gcoreDNSProviderConfig,
+		ch *v1alpha1.ChallengeRequest) (tokenProvider, error) {
+		if cfg.APIKeySecretRef.Name == "" {
+			return nil, nil
+		}
+		return secretToken{solver: c, namespace: ch.ResourceNamespace,
+			name: cfg.APIKeySecretRef.Name, key: cfg.APIKeySecretRef.Key}, nil
+	}},
+	{name: "tokenSource", provider: func(c *gcoreDNSProviderSolver, cfg gcoreDNSProviderConfig,
+		_ *v1alpha1.ChallengeRequest) (tokenProvider, error) {
+		if cfg.TokenSource == nil {
+			return nil, nil
+		}
+		return c.vaultSourceFor(cfg)
+	}},
+	// With login, the password stands in for the token.
+	{name: "login", provider: func(c *gcoreDNSProviderSolver, cfg gcoreDNSProviderConfig,
+		ch *v1alpha1.ChallengeRequest) (tokenProvider, error) {
+		if cfg.Login.Username == "" {
+			return nil, nil
+		}
+		return c.secretRefToken("login.passwordSecretRef", cfg.Login.PasswordSecretRef, ch)
+	}},
+	{name: "ambient", provider: func(c *gcoreDNSProviderSolver, _ gcoreDNSProviderConfig,
+		ch *v1alpha1.ChallengeRequest) (tokenProvider, error) {
+		if token := c.ambientToken(ch); token != "" {
+			return staticToken(token), nil
+		}
+		return nil, nil
+	}},
+}
+
+// tokenProviderFor returns the provider of the first credential source the
+// config uses.
+func (c *gcoreDNSProviderSolver) tokenProviderFor(cfg gcoreDNSProviderConfig,
+	ch *v1alpha1.ChallengeRequest) (tokenProvider, error) {
+	for _, source := range tokenProviderSources {
+		provider, err := source.provider(c, cfg, ch)
+		if err != nil {
+			return nil, err
+		}
+		if provider != nil {
+			return provider, nil
+		}
+	}
+	if ch.AllowAmbientCredentials && !c.ambientNamespaceAllowed(ch.ResourceNamespace) {
+		return nil, fmt.Errorf("no api token configured and ambient credentials are not allowed for issuers "+
+			"in namespace %q, see %s", ch.ResourceNamespace, ambientNSEnvVar)
+	}
+	return nil, errors.New("no api token configured and ambient credentials are not available")
+}
+
+// secretRefToken returns the provider of a secret reference of the config
+// named field. Its namespace defaults to the issuer's.
+func (c *gcoreDNSProviderSolver) secretRefToken(field string, ref secretRef,
+	ch *v1alpha1.ChallengeRequest) (tokenProvider, error) {
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = ch.ResourceNamespace
+	}
+	// The webhook may read secrets in every namespace, so an Issuer must
+	// not be able to point it at another namespace's secrets by default.
+	if namespace != ch.ResourceNamespace && !c.allowCrossNamespaceSecrets {
+		return nil, fmt.Errorf("%s namespace %q differs from the issuer's namespace %q; "+
+			"set %s=true on the webhook to allow it", field, namespace, ch.ResourceNamespace, crossNamespaceEnvVar)
+	}
+	return secretToken{solver: c, namespace: namespace, name: ref.Name, key: ref.Key}, nil
+}
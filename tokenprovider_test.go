@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	certmgrv1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestTokenProviderFor(t *testing.T) {
+	dir := t.TempDir()
+	tokenPath := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("file-token\n"), 0o600))
+	t.Setenv(apiTokenEnvVar, "env-token")
+	t.Setenv(vaultTokenEnvVar, "root")
+
+	solver := &gcoreDNSProviderSolver{
+		client: fake.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{Name: "gcore", Namespace: "default"},
+			Data:       map[string][]byte{"token": []byte("secret-token"), "password": []byte("secret-password")},
+		}),
+		tokenFileDir: dir,
+		vaultAddrs:   []string{"https://vault:8200"},
+	}
+	ref := secretRef{Name: "gcore", Key: "token"}
+	testCases := []struct {
+		desc     string
+		cfg      gcoreDNSProviderConfig
+		ambient  bool
+		provider tokenProvider
+		expected string
+	}{
+		{desc: "apiToken", cfg: gcoreDNSProviderConfig{ApiToken: "config-token", APITokenFile: tokenPath},
+			provider: staticToken("config-token"), expected: "config-token"},
+		{desc: "apiTokenFile", cfg: gcoreDNSProviderConfig{APITokenFile: tokenPath, APITokenSecretRef: ref},
+			provider: &tokenFile{}, expected: "file-token"},
+		{desc: "apiTokenSecretRef", cfg: gcoreDNSProviderConfig{APITokenSecretRef: ref},
+			provider: secretToken{}, expected: "secret-token"},
+		{desc: "apiKeySecretRef", cfg: gcoreDNSProviderConfig{APIKeySecretRef: certmgrv1.SecretKeySelector{
+			LocalObjectReference: certmgrv1.LocalObjectReference{Name: "gcore"}, Key: "token"}},
+			provider: secretToken{}, expected: "secret-token"},
+		{desc: "tokenSource", cfg: gcoreDNSProviderConfig{TokenSource: &tokenSource{Type: tokenSourceVault,
+			Path: "secret/data/gcore", AuthMethod: vaultAuthToken}}, provider: &vaultSource{}},
+		{desc: "login", cfg: gcoreDNSProviderConfig{Login: login{Username: "user",
+			PasswordSecretRef: secretRef{Name: "gcore", Key: "password"}}},
+			provider: secretToken{}, expected: "secret-password"},
+		{desc: "ambient", ambient: true, provider: staticToken(""), expected: "env-token"},
+	}
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			ch := &v1alpha1.ChallengeRequest{ResourceNamespace: "default", AllowAmbientCredentials: test.ambient}
+			provider, err := solver.tokenProviderFor(test.cfg, ch)
+			require.NoError(t, err)
+			assert.IsType(t, test.provider, provider)
+			if test.expected == "" {
+				return
+			}
+			token, err := provider.Token(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, token)
+		})
+	}
+
+	_, err := solver.tokenProviderFor(gcoreDNSProviderConfig{}, &v1alpha1.ChallengeRequest{})
+	assert.EqualError(t, err, "no api token configured and ambient credentials are not available")
+
+	// Errors of the selected source are returned rather than falling back
+	// to a later one.
+	_, err = solver.tokenProviderFor(gcoreDNSProviderConfig{APITokenFile: "/etc/passwd"},
+		&v1alpha1.ChallengeRequest{AllowAmbientCredentials: true})
+	assert.ErrorContains(t, err, "apiTokenFile /etc/passwd is not below")
+}
+
+func TestSecretRefToken(t *testing.T) {
+	ch := &v1alpha1.ChallengeRequest{ResourceNamespace: "default"}
+	ref := secretRef{Name: "gcore", Namespace: "dns", Key: "token"}
+
+	_, err := (&gcoreDNSProviderSolver{}).secretRefToken("apiTokenSecretRef", ref, ch)
+	assert.ErrorContains(t, err, `apiTokenSecretRef namespace "dns" differs from the issuer's namespace "default"`)
+
+	solver := &gcoreDNSProviderSolver{allowCrossNamespaceSecrets: true}
+	provider, err := solver.secretRefToken("apiTokenSecretRef", ref, ch)
+	require.NoError(t, err)
+	assert.Equal(t, secretToken{solver: solver, namespace: "dns", name: "gcore", key: "token"}, provider)
+
+	provider, err = solver.secretRefToken("apiTokenSecretRef", secretRef{Name: "gcore", Key: "token"}, ch)
+	require.NoError(t, err)
+	assert.Equal(t, "default", provider.(secretToken).namespace)
+}
+
+// blockingToken waits for its context to end.
+type blockingToken struct{}
+
+func (blockingToken) Token(ctx context.Context) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestResolveTokenContext(t *testing.T) {
+	setForTest(t, &tokenProviderSources, []tokenProviderSource{{name: "blocking",
+		provider: func(*gcoreDNSProviderSolver, gcoreDNSProviderConfig, *v1alpha1.ChallengeRequest) (tokenProvider,
+			error) {
+			return blockingToken{}, nil
+		}}})
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, err := (&gcoreDNSProviderSolver{}).resolveToken(ctx, gcoreDNSProviderConfig{}, &v1alpha1.ChallengeRequest{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
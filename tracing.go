@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Standard OpenTelemetry environment variables selecting the OTLP collector
+// spans are exported to. Without either, tracing is off.
+const (
+	otlpEndpointEnvVar       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	otlpTracesEndpointEnvVar = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	otelSDKDisabledEnvVar    = "OTEL_SDK_DISABLED"
+
+	tracingServiceName = "cert-manager-webhook-gcore"
+)
+
+// tracer starts the spans of the solver. It does nothing until setupTracing
+// installed an exporting tracer provider.
+var tracer = otel.Tracer("github.com/G-Core/cert-manager-webhook-gcore")
+
+// setupTracing installs a tracer provider exporting spans over OTLP/gRPC
+// when an OTLP endpoint is configured, returning nil otherwise. The exporter
+// reads the other OTEL_EXPORTER_OTLP_* variables, e.g. headers and TLS, and
+// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES describe the webhook.
+func setupTracing(ctx context.Context) (*sdktrace.TracerProvider, error) {
+	if os.Getenv(otlpEndpointEnvVar) == "" && os.Getenv(otlpTracesEndpointEnvVar) == "" ||
+		os.Getenv(otelSDKDisabledEnvVar) == "true" {
+		return nil, nil
+	}
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(
+		resource.NewSchemaless(attribute.String("service.name", tracingServiceName)),
+		resource.Environment(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider, nil
+}
+
+// startSpan starts a span of the solver as a child of the span in ctx.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends span, marking it failed with err if err is not nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// challengeAttributes identify the challenge of a span, to find it next to
+// the traces of cert-manager.
+func challengeAttributes(ch *v1alpha1.ChallengeRequest) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("acme.challenge.uid", string(ch.UID)),
+		attribute.String("acme.challenge.fqdn", ch.ResolvedFQDN),
+		attribute.String("acme.challenge.zone", ch.ResolvedZone),
+		attribute.String("k8s.namespace.name", ch.ResourceNamespace),
+	}
+}
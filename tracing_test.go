@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// challengeSpans returns the spans recorded in the traces of the challenge
+// with the given UID.
+func challengeSpans(t *testing.T, recorder *tracetest.SpanRecorder, uid string) []sdktrace.ReadOnlySpan {
+	t.Helper()
+	traces := map[trace.TraceID]bool{}
+	for _, span := range recorder.Ended() {
+		if slices.Contains(span.Attributes(), attribute.String("acme.challenge.uid", uid)) {
+			traces[span.SpanContext().TraceID()] = true
+		}
+	}
+	require.NotEmpty(t, traces, "no span of challenge %s", uid)
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if traces[span.SpanContext().TraceID()] {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	return names
+}
+
+// spanRecorder installs a tracer provider recording the spans of all tests,
+// as the tracer delegates to the first global provider set. The spans of a
+// test are told apart by their trace.
+var spanRecorder = sync.OnceValue(func() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return recorder
+})
+
+func TestTracing(t *testing.T) {
+	recorder := spanRecorder()
+
+	mock := newMockSDK("example.com")
+	mock.zones["example.com"].nameservers = []string{"ns1.gcorelabs.net"}
+	solver := solverWithMock(mock)
+	solver.lookupTXT = func(context.Context, string, string) ([]string, error) { return []string{"key"}, nil }
+
+	ch := challenge("_acme-challenge.example.com.", "key", `{"apiToken":"t","verifyPropagation":true}`)
+	ch.UID = "present-uid"
+	require.NoError(t, solver.Present(ch))
+	names := spanNames(challengeSpans(t, recorder, "present-uid"))
+	assert.Contains(t, names, "gcore.Present")
+	assert.Contains(t, names, "gcore.ZoneLookup")
+	assert.Contains(t, names, "gcore.api.RRSetWithVersion")
+	assert.Contains(t, names, "gcore.api.CreateRRSet")
+	assert.Contains(t, names, "gcore.PropagationWait")
+
+	ch = challenge("_acme-challenge.example.org.", "key", `{"apiToken":"t"}`)
+	ch.UID = "failed-uid"
+	require.Error(t, solver.Present(ch))
+	require.NoError(t, solver.CleanUp(ch))
+	spans := challengeSpans(t, recorder, "failed-uid")
+	assert.Contains(t, spanNames(spans), "gcore.Present")
+	for _, span := range spans {
+		if span.Name() == "gcore.Present" || span.Name() == "gcore.ZoneLookup" {
+			assert.Equal(t, codes.Error, span.Status().Code, span.Name())
+		}
+	}
+}
+
+func TestTracingAPIRequests(t *testing.T) {
+	recorder := spanRecorder()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.Header.Get("Traceparent"), "the trace context is passed on to the API")
+		_, _ = w.Write([]byte(`{"name":"example.com"}`))
+	}))
+	defer server.Close()
+	sdk, err := newSDKClient(gcoreDNSProviderConfig{ApiUrl: server.URL, traceRequests: true}, "t")
+	require.NoError(t, err)
+	api := &retryingAPI{api: sdk, apiURL: server.URL}
+	ctx, root := startSpan(context.Background(), "test")
+	_, err = api.Zone(ctx, "example.com")
+	root.End()
+	require.NoError(t, err)
+
+	var call sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "gcore.api.Zone" && span.Parent().SpanID() == root.SpanContext().SpanID() {
+			call = span
+		}
+	}
+	require.NotNil(t, call)
+	var requests int
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == call.SpanContext().SpanID() {
+			requests++
+		}
+	}
+	assert.Equal(t, 1, requests, "the HTTP request is a child of the API call")
+}
+
+func TestSetupTracingDisabled(t *testing.T) {
+	t.Setenv(otlpEndpointEnvVar, "")
+	t.Setenv(otlpTracesEndpointEnvVar, "")
+	provider, err := setupTracing(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, provider)
+
+	t.Setenv(otlpEndpointEnvVar, "http://collector:4317")
+	t.Setenv(otelSDKDisabledEnvVar, "true")
+	provider, err = setupTracing(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, provider)
+}
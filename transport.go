@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+const (
+	tlsVersion12 = "1.2"
+	tlsVersion13 = "1.3"
+)
+
+// tlsVersions maps the minTLSVersion values to the crypto/tls constants.
+var tlsVersions = map[string]uint16{
+	tlsVersion12: tls.VersionTLS12,
+	tlsVersion13: tls.VersionTLS13,
+}
+
+// apiTransport returns the HTTP transport for calls to the G-Core DNS and
+// auth APIs, set up with the proxy, TLS and HTTP/1.1 options of the config.
+// Without proxyUrl, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+// variables of the webhook apply.
+func (cfg gcoreDNSProviderConfig) apiTransport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// The environment is read for every client rather than once per process
+	// like http.ProxyFromEnvironment does.
+	envProxy := httpproxy.FromEnvironment().ProxyFunc()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) { return envProxy(req.URL) }
+	if cfg.ProxyURL != "" {
+		proxyURL, err := parseProxyURL(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("proxyUrl: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if cfg.ForceHTTP1 {
+		// A non-nil empty TLSNextProto map keeps the transport from
+		// negotiating HTTP/2.
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// clientIDHeader selects the client account a reseller request acts on.
+const clientIDHeader = "Client-Id"
+
+// clientTransport makes every request act on behalf of a reseller's client
+// account.
+type clientTransport struct {
+	base     http.RoundTripper
+	clientID uint64
+}
+
+func (t *clientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(clientIDHeader, strconv.FormatUint(t.clientID, 10))
+	return t.base.RoundTrip(req)
+}
+
+// parseProxyURL parses an http, https or socks5 proxy URL.
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("%q is not an http, https or socks5 URL", raw)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%q has no host", raw)
+	}
+	return u, nil
+}
+
+// tlsConfig returns the client TLS config for the API, nil when the config
+// sets no TLS options.
+func (cfg gcoreDNSProviderConfig) tlsConfig() (*tls.Config, error) {
+	if cfg.CABundle == "" && !cfg.InsecureSkipVerify && cfg.MinTLSVersion == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		// Only set when explicitly asked for, e.g. to debug a TLS
+		// inspecting proxy, and documented as such.
+		InsecureSkipVerify: cfg.InsecureSkipVerify, //nolint:gosec
+	}
+	if cfg.MinTLSVersion != "" {
+		version, ok := tlsVersions[cfg.MinTLSVersion]
+		if !ok {
+			return nil, fmt.Errorf("minTLSVersion must be %q or %q, got %q", tlsVersion12, tlsVersion13, cfg.MinTLSVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+	if cfg.CABundle != "" {
+		// The bundle is added to the system roots, so a proxy CA does not
+		// stop the webhook from reaching the API directly.
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(cfg.CABundle)) {
+			return nil, errors.New("caBundle holds no PEM encoded certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITransportTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	caBundle := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	get := func(cfg gcoreDNSProviderConfig) error {
+		transport, err := cfg.apiTransport()
+		require.NoError(t, err)
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		return err
+	}
+	assert.ErrorContains(t, get(gcoreDNSProviderConfig{}), "certificate")
+	assert.NoError(t, get(gcoreDNSProviderConfig{CABundle: caBundle}))
+	assert.NoError(t, get(gcoreDNSProviderConfig{InsecureSkipVerify: true}))
+
+	transport, err := gcoreDNSProviderConfig{}.apiTransport()
+	require.NoError(t, err)
+	assert.Nil(t, transport.TLSClientConfig)
+
+	transport, err = gcoreDNSProviderConfig{MinTLSVersion: tlsVersion13}.apiTransport()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), transport.TLSClientConfig.MinVersion)
+
+	assert.ErrorContains(t, gcoreDNSProviderConfig{MinTLSVersion: "1.0"}.Validate(), "minTLSVersion")
+	assert.ErrorContains(t, gcoreDNSProviderConfig{CABundle: "not a certificate"}.Validate(),
+		"caBundle holds no PEM encoded certificate")
+}
+
+func TestAPITransportProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error":"proxied"}`))
+	}))
+	t.Cleanup(proxy.Close)
+
+	solver := &gcoreDNSProviderSolver{}
+	err := solver.Present(challenge("_acme-challenge.example.com.", "key",
+		fmt.Sprintf(`{"apiToken":"t","apiUrl":"http://api.gcore.invalid/dns","proxyUrl":%q}`, proxy.URL)))
+	assert.ErrorContains(t, err, "proxied")
+	require.NotEmpty(t, proxied)
+	assert.Contains(t, proxied, "http://api.gcore.invalid/dns/v2/zones/example.com")
+
+	t.Setenv("HTTP_PROXY", proxy.URL)
+	transport, err := gcoreDNSProviderConfig{}.apiTransport()
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, "http://api.gcore.invalid/dns", nil)
+	require.NoError(t, err)
+	proxyURL, err := transport.Proxy(req)
+	require.NoError(t, err)
+	assert.Equal(t, proxy.URL, proxyURL.String())
+
+	assert.ErrorContains(t, gcoreDNSProviderConfig{ProxyURL: "ftp://proxy"}.Validate(),
+		`proxyUrl: "ftp://proxy" is not an http, https or socks5 URL`)
+	assert.ErrorContains(t, gcoreDNSProviderConfig{ProxyURL: "http://"}.Validate(), "has no host")
+}
+
+func TestPresentClientID(t *testing.T) {
+	var clientIDs []string
+	server := httptest.NewServer(rejectingAPI(clientIDHeader, &clientIDs))
+	t.Cleanup(server.Close)
+
+	solver := &gcoreDNSProviderSolver{}
+	for _, cfg := range []string{`{"apiToken":"t","apiUrl":%q,"clientId":1234}`, `{"apiToken":"t","apiUrl":%q}`} {
+		err := solver.Present(challenge("_acme-challenge.example.com.", "key", fmt.Sprintf(cfg, server.URL)))
+		assert.ErrorContains(t, err, "stop here")
+	}
+	// The filtered zone query fails and the zone is probed, both on behalf
+	// of the client.
+	assert.Equal(t, []string{"1234", "1234", "", ""}, clientIDs)
+
+	accounts := map[string]bool{}
+	for _, cfg := range []string{`{"apiToken":"t"}`, `{"apiToken":"t","clientId":1}`, `{"apiToken":"t","clientId":2}`} {
+		_, resolved, err := solver.initSDK(t.Context(), challenge("_acme-challenge.example.com.", "key", cfg))
+		require.NoError(t, err)
+		accounts[resolved.account] = true
+	}
+	assert.Len(t, accounts, 3, "every client account is cached apart")
+}
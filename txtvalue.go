@@ -0,0 +1,40 @@
+package main
+
+import "strings"
+
+// The G-Core API stores TXT values verbatim, but DNS libraries and zone files
+// hold them in presentation format: quotes and backslashes are escaped with a
+// backslash and other bytes outside printable ASCII written as \DDD. Values
+// read from DNS answers are decoded before they are compared to a challenge
+// key, so keys with such characters are still found. Values read back from
+// the API are compared as they are: it returns what was stored, so decoding
+// them would turn a key such as \065 into another one.
+
+// unescapeTXT returns the TXT value of a character string in presentation
+// format: \DDD stands for the byte of decimal value DDD and \X for X.
+func unescapeTXT(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+3 < len(s) && isDigit(s[i+1]) && isDigit(s[i+2]) && isDigit(s[i+3]) {
+			if n := int(s[i+1]-'0')*100 + int(s[i+2]-'0')*10 + int(s[i+3]-'0'); n <= 255 {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		i++
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
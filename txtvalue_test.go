@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// specialKeys are challenge values with characters that need escaping or
+// quoting somewhere on their way.
+var specialKeys = []string{
+	`say "hi"`,
+	`back\slash`,
+	`trailing\`,
+	`semi;colon (and) spaces`,
+	"tab\tand\nnewline",
+	"ключ",
+	`\"already\" escaped\065`,
+}
+
+// escapeTXT returns the presentation format of a TXT value, as DNS
+// libraries and zone files write it.
+func escapeTXT(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func TestEscapeTXT(t *testing.T) {
+	assert.Equal(t, `say \"hi\"`, escapeTXT(`say "hi"`))
+	assert.Equal(t, `back\\slash`, escapeTXT(`back\slash`))
+	assert.Equal(t, `tab\009`, escapeTXT("tab\t"))
+	assert.Equal(t, "LHDhK3oGRvkiefQnx7OOczTY5Tic_xZ6HcMOc_gmtoM", escapeTXT("LHDhK3oGRvkiefQnx7OOczTY5Tic_xZ6HcMOc_gmtoM"))
+
+	assert.Equal(t, "A", unescapeTXT(`\065`))
+	assert.Equal(t, `\999`[1:], unescapeTXT(`\999`), "out of range escapes are taken literally")
+	assert.Equal(t, `a\`, unescapeTXT(`a\`))
+
+	for _, key := range specialKeys {
+		assert.Equal(t, key, unescapeTXT(escapeTXT(key)), key)
+	}
+}
+
+// TestUnescapeTXTWire checks the decoding of TXT records as the DNS library
+// unpacks them from an answer, split into character strings of at most 255
+// bytes.
+func TestUnescapeTXTWire(t *testing.T) {
+	for _, key := range append(specialKeys, strings.Repeat(`"\`, 200)) {
+		var chunks []string
+		for rest := key; rest != ""; {
+			n := min(len(rest), 255)
+			chunks = append(chunks, escapeTXT(rest[:n]))
+			rest = rest[n:]
+		}
+		msg := new(dns.Msg)
+		msg.SetQuestion("_acme-challenge.example.com.", dns.TypeTXT)
+		msg.Answer = []dns.RR{&dns.TXT{
+			Hdr: dns.RR_Header{Name: "_acme-challenge.example.com.", Rrtype: dns.TypeTXT, Class: dns.ClassINET},
+			Txt: chunks,
+		}}
+		wire, err := msg.Pack()
+		require.NoError(t, err)
+		var answer dns.Msg
+		require.NoError(t, answer.Unpack(wire))
+
+		var value strings.Builder
+		for _, s := range answer.Answer[0].(*dns.TXT).Txt {
+			value.WriteString(unescapeTXT(s))
+		}
+		assert.Equal(t, key, value.String())
+	}
+}
+
+// TestSpecialKeysRoundTrip presents and cleans up challenge values with
+// special characters through the API client, against a server storing the
+// RRSets as they were sent.
+func TestSpecialKeysRoundTrip(t *testing.T) {
+	var mu sync.Mutex
+	rrsets := map[string][]byte{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/zones/example.com", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"example.com"}`))
+	})
+	mux.HandleFunc("/v2/zones/example.com/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, ok := rrsets[r.URL.Path]
+		switch {
+		case r.Method == http.MethodPost && ok:
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error":"rrset already exists"}`))
+		case r.Method == http.MethodPost || r.Method == http.MethodPut:
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.True(t, json.Valid(body))
+			rrsets[r.URL.Path] = body
+		case r.Method == http.MethodDelete:
+			delete(rrsets, r.URL.Path)
+		case !ok:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"not found"}`))
+		default:
+			_, _ = w.Write(body)
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	const path = "/v2/zones/example.com/_acme-challenge.example.com/TXT"
+	cfg := `{"apiToken":"secret","apiUrl":"` + server.URL + `","zoneDiscovery":"probe","strictCleanup":true}`
+	solver := &gcoreDNSProviderSolver{}
+	for _, key := range specialKeys {
+		require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", key, cfg)), key)
+		// Presenting again finds the record instead of adding it twice.
+		require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", key, cfg)), key)
+	}
+	var stored struct {
+		Records []struct {
+			Content []string `json:"content"`
+		} `json:"resource_records"`
+	}
+	require.NoError(t, json.Unmarshal(rrsets[path], &stored))
+	var values []string
+	for _, record := range stored.Records {
+		values = append(values, record.Content...)
+	}
+	assert.ElementsMatch(t, specialKeys, values)
+
+	for _, key := range specialKeys {
+		require.NoError(t, solver.CleanUp(challenge("_acme-challenge.example.com.", key, cfg)), key)
+	}
+	assert.NotContains(t, rrsets, path)
+}
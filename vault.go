@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+)
+
+const (
+	tokenSourceVault = "vault"
+
+	vaultAuthKubernetes = "kubernetes"
+	vaultAuthToken      = "token"
+
+	// vaultSecretTTL is how long a token read from a secret without lease
+	// is used before it is read again, to pick up rotations.
+	vaultSecretTTL = 5 * time.Minute
+	// vaultRenewBefore is how long before its lease ends the Vault client
+	// token is replaced by logging in again.
+	vaultRenewBefore = 30 * time.Second
+	// vaultTokenEnvVar holds the Vault token of the token auth method.
+	vaultTokenEnvVar = "VAULT_TOKEN"
+)
+
+// serviceAccountTokenPath is the token the kubernetes auth method logs in with.
+var serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// tokenSource names an external system the API token is read from.
+type tokenSource struct {
+	// Type of the source, only "vault" is supported.
+	Type string `json:"type"`
+	// Address of the Vault server, one of GCORE_VAULT_ADDRS. Defaults to
+	// the first of them.
+	Address string `json:"address"`
+	// Path of the secret, e.g. "secret/data/gcore" for a KV version 2
+	// secret engine mounted at secret/.
+	Path string `json:"path"`
+	// Key of the secret holding the token, defaults to "token".
+	Key string `json:"key"`
+	// AuthMethod is "kubernetes" (default), logging in with the webhook's
+	// service account, or "token", using VAULT_TOKEN of the webhook.
+	AuthMethod string `json:"authMethod"`
+	// AuthMount is the mount path of the kubernetes auth method, defaults
+	// to "kubernetes".
+	AuthMount string `json:"authMount"`
+	// Role the kubernetes auth method logs in as.
+	Role string `json:"role"`
+}
+
+// validate checks the token source fields.
+func (s tokenSource) validate() error {
+	var problems configErrors
+	if s.Type != tokenSourceVault {
+		problems.add(fmt.Errorf("tokenSource.type must be %q, got %q", tokenSourceVault, s.Type))
+	}
+	if strings.Trim(s.Path, "/") == "" {
+		problems.add(errors.New("tokenSource.path is not set"))
+	}
+	if s.Address != "" {
+		if err := validateURL(s.Address); err != nil {
+			problems.add(fmt.Errorf("tokenSource.address: %w", err))
+		}
+	}
+	switch s.AuthMethod {
+	case "", vaultAuthKubernetes:
+		if s.Role == "" {
+			problems.add(errors.New("tokenSource.role is required by the kubernetes auth method"))
+		}
+	case vaultAuthToken:
+	default:
+		problems.add(fmt.Errorf("tokenSource.authMethod must be %q or %q, got %q",
+			vaultAuthKubernetes, vaultAuthToken, s.AuthMethod))
+	}
+	return problems.err()
+}
+
+// vaultSource reads the API token from a Vault secret. It logs in again
+// before its Vault token expires, and reads the secret again when its lease
+// ends so rotated tokens are picked up.
+type vaultSource struct {
+	source tokenSource
+	client *http.Client
+	now    func() time.Time
+
+	mu          sync.Mutex
+	vaultToken  string
+	vaultExpiry time.Time
+	token       string
+	tokenExpiry time.Time
+}
+
+// Token returns the API token, reading the secret when its lease ended.
+func (v *vaultSource) Token(ctx context.Context) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.token != "" && v.now().Before(v.tokenExpiry) {
+		return v.token, nil
+	}
+	if err := v.login(ctx, false); err != nil {
+		return "", err
+	}
+	err := v.read(ctx)
+	if isAuthRejected(err) && v.source.AuthMethod != vaultAuthToken {
+		// The Vault token may have been revoked before its lease ended.
+		if err = v.login(ctx, true); err == nil {
+			err = v.read(ctx)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return v.token, nil
+}
+
+// login gets a Vault token, unless the current one is still valid and force
+// is false.
+// https://developer.hashicorp.com/vault/api-docs/auth/kubernetes#login
+func (v *vaultSource) login(ctx context.Context, force bool) error {
+	if v.source.AuthMethod == vaultAuthToken {
+		v.vaultToken = os.Getenv(vaultTokenEnvVar)
+		if v.vaultToken == "" {
+			return fmt.Errorf("vault: %s is not set on the webhook", vaultTokenEnvVar)
+		}
+		return nil
+	}
+	if !force && v.vaultToken != "" &&
+		(v.vaultExpiry.IsZero() || v.now().Add(vaultRenewBefore).Before(v.vaultExpiry)) {
+		return nil
+	}
+	jwt, err := os.ReadFile(serviceAccountTokenPath)
+	if err != nil {
+		return fmt.Errorf("vault login: read service account token: %w", err)
+	}
+	var resp struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	body := map[string]string{"role": v.source.Role, "jwt": strings.TrimSpace(string(jwt))}
+	if err := v.do(ctx, http.MethodPost, "auth/"+v.source.AuthMount+"/login", body, &resp); err != nil {
+		return fmt.Errorf("vault login: %w", err)
+	}
+	if resp.Auth.ClientToken == "" {
+		return errors.New("vault login: response holds no client token")
+	}
+	v.vaultToken, v.vaultExpiry = resp.Auth.ClientToken, time.Time{}
+	if resp.Auth.LeaseDuration > 0 {
+		v.vaultExpiry = v.now().Add(time.Duration(resp.Auth.LeaseDuration) * time.Second)
+	}
+	return nil
+}
+
+// read reads the API token from the secret. Both KV version 1 and version 2
+// responses are understood.
+func (v *vaultSource) read(ctx context.Context) error {
+	var resp struct {
+		LeaseDuration int            `json:"lease_duration"`
+		Data          map[string]any `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodGet, v.source.Path, nil, &resp); err != nil {
+		return fmt.Errorf("vault read %s: %w", v.source.Path, err)
+	}
+	data := resp.Data
+	if nested, ok := data["data"].(map[string]any); ok {
+		data = nested
+	}
+	token, ok := data[v.source.Key].(string)
+	if !ok || token == "" {
+		return fmt.Errorf("vault read %s: key %s not found", v.source.Path, v.source.Key)
+	}
+	ttl := vaultSecretTTL
+	if resp.LeaseDuration > 0 {
+		ttl = min(ttl, time.Duration(resp.LeaseDuration)*time.Second)
+	}
+	v.token, v.tokenExpiry = token, v.now().Add(ttl)
+	return nil
+}
+
+// do sends a request to the Vault API and decodes the response into dest.
+// Vault errors are returned as an APIError with the status code.
+func (v *vaultSource) do(ctx context.Context, method, path string, body, dest any) error {
+	var reqBody io.Reader
+	if body != nil {
+		bs, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(bs)
+	}
+	endpoint := v.source.Address + "/v1/" + strings.Trim(path, "/")
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return err
+	}
+	if v.vaultToken != "" {
+		req.Header.Set("X-Vault-Token", v.vaultToken)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response body: %w", err)
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return dnssdk.APIError{StatusCode: resp.StatusCode, Message: string(respBody)}
+	}
+	return json.Unmarshal(respBody, dest)
+}
+
+// vaultSourceFor returns the shared reader of a token source. Only the
+// Vault servers listed in GCORE_VAULT_ADDRS may be used, otherwise an issuer
+// could have the webhook's Vault credentials sent to any address.
+func (c *gcoreDNSProviderSolver) vaultSourceFor(cfg gcoreDNSProviderConfig) (*vaultSource, error) {
+	source := *cfg.TokenSource
+	if len(c.vaultAddrs) == 0 {
+		return nil, fmt.Errorf("tokenSource %q is disabled, set %s on the webhook to the allowed Vault addresses",
+			source.Type, vaultAddrsEnvVar)
+	}
+	if source.Address == "" {
+		source.Address = c.vaultAddrs[0]
+	}
+	source.Address = strings.TrimSuffix(source.Address, "/")
+	if !slices.Contains(c.vaultAddrs, source.Address) {
+		return nil, fmt.Errorf("tokenSource.address %s is not one of %s", source.Address, vaultAddrsEnvVar)
+	}
+	if source.Key == "" {
+		source.Key = "token"
+	}
+	if source.AuthMethod == "" {
+		source.AuthMethod = vaultAuthKubernetes
+	}
+	if source.AuthMount == "" {
+		source.AuthMount = vaultAuthKubernetes
+	}
+	c.vaultMu.Lock()
+	defer c.vaultMu.Unlock()
+	if c.vaultSources == nil {
+		c.vaultSources = map[tokenSource]*vaultSource{}
+	}
+	if v, ok := c.vaultSources[source]; ok {
+		return v, nil
+	}
+	v := &vaultSource{
+		source: source,
+		client: &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+		now:    time.Now,
+	}
+	c.vaultSources[source] = v
+	return v, nil
+}
+
+// vaultAddrsFromEnv reads the allowed Vault addresses from the comma
+// separated GCORE_VAULT_ADDRS variable.
+func vaultAddrsFromEnv() []string {
+	addrs := splitList(os.Getenv(vaultAddrsEnvVar))
+	for i, addr := range addrs {
+		addrs[i] = strings.TrimSuffix(addr, "/")
+	}
+	return addrs
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// vaultServer serves the kubernetes auth login and a KV version 2 secret at
+// secret/data/gcore. Every login hands out a new client token.
+type vaultServer struct {
+	mu     sync.Mutex
+	token  string
+	secret string
+	logins int
+	reads  int
+}
+
+func (v *vaultServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	switch r.URL.Path {
+	case "/v1/auth/kubernetes/login":
+		var body struct{ Role, JWT string }
+		if json.NewDecoder(r.Body).Decode(&body) != nil || body.Role != "cert-manager" || body.JWT != "sa-jwt" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		v.logins++
+		v.token = fmt.Sprintf("vault-token-%d", v.logins)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"auth": map[string]any{"client_token": v.token, "lease_duration": 3600},
+		})
+	case "/v1/secret/data/gcore":
+		if r.Header.Get("X-Vault-Token") != v.token {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		v.reads++
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{"data": map[string]any{"token": v.secret}},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestVaultSource(t *testing.T) {
+	vault := &vaultServer{secret: "gcore-1"}
+	server := httptest.NewServer(vault)
+	t.Cleanup(server.Close)
+	saToken := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(saToken, []byte("sa-jwt\n"), 0o600))
+	defaultPath := serviceAccountTokenPath
+	serviceAccountTokenPath = saToken
+	t.Cleanup(func() { serviceAccountTokenPath = defaultPath })
+
+	solver := &gcoreDNSProviderSolver{vaultAddrs: []string{server.URL}}
+	cfg := gcoreDNSProviderConfig{TokenSource: &tokenSource{Type: tokenSourceVault, Path: "secret/data/gcore",
+		Role: "cert-manager"}}
+	source, err := solver.vaultSourceFor(cfg)
+	require.NoError(t, err)
+	again, err := solver.vaultSourceFor(cfg)
+	require.NoError(t, err)
+	assert.Same(t, source, again)
+
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "gcore-1", token)
+	_, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, vault.logins)
+	assert.Equal(t, 1, vault.reads)
+
+	// A rotated token is read once the secret TTL passed.
+	vault.secret = "gcore-2"
+	source.now = func() time.Time { return time.Now().Add(vaultSecretTTL) }
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "gcore-2", token)
+	assert.Equal(t, 1, vault.logins)
+
+	// A revoked Vault token is replaced by logging in again.
+	vault.token = "revoked"
+	source.now = func() time.Time { return time.Now().Add(2 * vaultSecretTTL) }
+	_, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, vault.logins)
+
+	// The Vault token is renewed before its lease ends.
+	source.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, vault.logins)
+}
+
+func TestVaultSourceTokenAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/gcore" || r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"lease_duration":60,"data":{"api-token":"gcore-kv1"}}`))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv(vaultTokenEnvVar, "root")
+
+	solver := &gcoreDNSProviderSolver{
+		vaultAddrs: []string{server.URL},
+		newSDK: func(_ gcoreDNSProviderConfig, token string) (dnsAPI, error) {
+			assert.Equal(t, "gcore-kv1", token)
+			return newMockSDK("example.com"), nil
+		},
+	}
+	err := solver.Present(challenge("_acme-challenge.example.com.", "key", fmt.Sprintf(
+		`{"tokenSource":{"type":"vault","address":"%s/","path":"/kv/gcore","key":"api-token","authMethod":"token"}}`,
+		server.URL)))
+	require.NoError(t, err)
+}
+
+func TestVaultSourceFor(t *testing.T) {
+	cfg := gcoreDNSProviderConfig{TokenSource: &tokenSource{Type: tokenSourceVault, Path: "secret/data/gcore",
+		Role: "cert-manager", Address: "https://attacker.example"}}
+
+	_, err := (&gcoreDNSProviderSolver{}).vaultSourceFor(cfg)
+	assert.ErrorContains(t, err, `tokenSource "vault" is disabled, set GCORE_VAULT_ADDRS`)
+	_, err = (&gcoreDNSProviderSolver{vaultAddrs: []string{"https://vault:8200"}}).vaultSourceFor(cfg)
+	assert.ErrorContains(t, err, "tokenSource.address https://attacker.example is not one of GCORE_VAULT_ADDRS")
+
+	assert.ErrorContains(t, gcoreDNSProviderConfig{TokenSource: &tokenSource{Type: "aws"}}.Validate(),
+		`3 problems: tokenSource.type must be "vault", got "aws"; tokenSource.path is not set; `+
+			"tokenSource.role is required by the kubernetes auth method")
+
+	t.Setenv(vaultAddrsEnvVar, "https://vault:8200/, https://vault.backup:8200")
+	assert.Equal(t, []string{"https://vault:8200", "https://vault.backup:8200"}, vaultAddrsFromEnv())
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+)
+
+// verifyAttempts bounds how many times Present rewrites an RRSet that the API
+// only partially persisted.
+const verifyAttempts = 3
+
+// verifyRRSet reads the RRSet back after a write and makes sure every record
+// of the intended set was persisted. Depending on cfg.OnVerifyMismatch a
+// partially applied write is either rewritten in full or reported as an error.
+func verifyRRSet(ctx context.Context, sdk dnsAPI, cfg gcoreDNSProviderConfig,
+	zone, fqdn string, intended dnssdk.RRSet) error {
+	for attempt := 1; ; attempt++ {
+		actual, version, err := sdk.RRSetWithVersion(ctx, zone, fqdn, txtType)
+		if err != nil {
+			return fmt.Errorf("read back rrset: %w", err)
+		}
+		missing := missingRecords(intended.Records, actual.Records)
+		if missing == 0 {
+			return nil
+		}
+		if cfg.OnVerifyMismatch == verifyMismatchError || attempt == verifyAttempts {
+			return fmt.Errorf("rrset %s %s: %d of %d records missing after write",
+				fqdn, txtType, missing, len(intended.Records))
+		}
+		// Only add back what is missing, so records written concurrently by
+		// another replica survive the rewrite.
+		actual.Records = sortRecords(mergeRecords(actual.Records, intended.Records))
+		if actual.TTL == 0 {
+			actual.TTL = intended.TTL
+		}
+		err = sdk.UpdateRRSetIfMatch(ctx, zone, fqdn, txtType, actual, version)
+		if isPreconditionFailed(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("rewrite rrset: %w", err)
+		}
+	}
+}
+
+// missingRecords counts the intended records whose content is not present in
+// the actual records.
+func missingRecords(intended, actual []dnssdk.ResourceRecord) int {
+	present := make(map[string]struct{}, len(actual))
+	for _, record := range actual {
+		present[record.ContentToString()] = struct{}{}
+	}
+	missing := 0
+	for _, record := range intended {
+		if _, ok := present[record.ContentToString()]; !ok {
+			missing++
+		}
+	}
+	return missing
+}
+
+// mergeRecords appends the records of add whose content is not in records.
+func mergeRecords(records, add []dnssdk.ResourceRecord) []dnssdk.ResourceRecord {
+	present := make(map[string]struct{}, len(records))
+	for _, record := range records {
+		present[record.ContentToString()] = struct{}{}
+	}
+	merged := append([]dnssdk.ResourceRecord(nil), records...)
+	for _, record := range add {
+		if _, ok := present[record.ContentToString()]; !ok {
+			merged = append(merged, record)
+		}
+	}
+	return merged
+}
+
+// sortRecords orders records by content so the payloads written back are the
+// same whatever order the API or concurrent writers produced.
+func sortRecords(records []dnssdk.ResourceRecord) []dnssdk.ResourceRecord {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].ContentToString() < records[j].ContentToString()
+	})
+	return records
+}
+
+// hasKey reports whether records hold a TXT record whose content is exactly
+// key.
+func (cfg gcoreDNSProviderConfig) hasKey(records []dnssdk.ResourceRecord, key string) bool {
+	for _, record := range records {
+		if content, ok := recordSchema.decode(record); ok && content == key {
+			return true
+		}
+	}
+	return false
+}
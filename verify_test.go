@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresentVerifiesWrite(t *testing.T) {
+	const fqdn = "_acme-challenge.example.com."
+
+	t.Run("retry rewrites lost record", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		solver := solverWithMock(mock)
+		require.NoError(t, solver.Present(challenge(fqdn, "token-A", `{"apiToken":"t"}`)))
+
+		mock.lossyWrites = 1
+		require.NoError(t, solver.Present(challenge(fqdn, "token-B", `{"apiToken":"t"}`)))
+		assert.ElementsMatch(t, []string{"token-A", "token-B"}, mock.contents("example.com", "_acme-challenge.example.com"))
+	})
+
+	t.Run("error policy reports lost record", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		solver := solverWithMock(mock)
+		cfg := `{"apiToken":"t","onVerifyMismatch":"error"}`
+		require.NoError(t, solver.Present(challenge(fqdn, "token-A", cfg)))
+
+		mock.lossyWrites = 1
+		err := solver.Present(challenge(fqdn, "token-B", cfg))
+		assert.ErrorContains(t, err, "1 of 2 records missing after write")
+	})
+
+	t.Run("retry gives up eventually", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		mock.lossyWrites = verifyAttempts
+		err := solverWithMock(mock).Present(challenge(fqdn, "token-A", `{"apiToken":"t"}`))
+		assert.ErrorContains(t, err, "1 of 1 records missing after write")
+	})
+}
+
+func TestPresentWritesSortedRecords(t *testing.T) {
+	const fqdn = "_acme-challenge.example.com."
+	orders := [][]string{
+		{"token-C", "token-A", "token-B"},
+		{"token-B", "token-C", "token-A"},
+		{"token-A", "token-B", "token-C"},
+	}
+	for _, order := range orders {
+		mock := newMockSDK("example.com")
+		solver := solverWithMock(mock)
+		for _, key := range order {
+			require.NoError(t, solver.Present(challenge(fqdn, key, `{"apiToken":"t"}`)))
+		}
+		assert.Equal(t, []string{"token-A", "token-B", "token-C"}, mock.contents("example.com", "_acme-challenge.example.com"))
+
+		require.NoError(t, solver.CleanUp(challenge(fqdn, "token-B", `{"apiToken":"t"}`)))
+		assert.Equal(t, []string{"token-A", "token-C"}, mock.contents("example.com", "_acme-challenge.example.com"))
+	}
+}
+
+func TestHasKeyEmptyContent(t *testing.T) {
+	cfg := gcoreDNSProviderConfig{}
+	cfg.setDefaults()
+	records := []dnssdk.ResourceRecord{{Enabled: true}, {Content: []any{"token-A"}, Enabled: true}}
+	assert.True(t, cfg.hasKey(records, "token-A"))
+	assert.False(t, cfg.hasKey(records, ""))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"golang.org/x/net/publicsuffix"
+)
+
+// createZone creates the zone of fqdn in the account after none of its
+// candidate zones was found, for issuers that set allowZoneCreation. The
+// zone is zoneName if set, else the shallowest candidate within the
+// registered domain of fqdn, as told by the public suffix list, so that
+// e.g. a record under customer.co.uk creates customer.co.uk rather than
+// co.uk. Public suffixes are never created. Zone policies apply as for any
+// zone written to, and a zone created concurrently by another challenge is
+// used as is.
+func (c *gcoreDNSProviderSolver) createZone(ctx context.Context, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig, fqdn string) (string, error) {
+	zone := asciiDomain(cfg.ZoneName)
+	if zone == "" {
+		registered, err := publicsuffix.EffectiveTLDPlusOne(asciiDomain(fqdn))
+		if err != nil {
+			return "", fmt.Errorf("create zone: %s has no registered domain: %w", fqdn, err)
+		}
+		candidates := extractZones(fqdn, cfg.MinZoneLabels)
+		for i := len(candidates) - 1; i >= 0 && zone == ""; i-- {
+			if inZone(candidates[i], registered) {
+				zone = candidates[i]
+			}
+		}
+		if zone == "" {
+			return "", fmt.Errorf("create zone: %s has no candidate zone", fqdn)
+		}
+	}
+	if suffix, _ := publicsuffix.PublicSuffix(asciiDomain(zone)); suffix == asciiDomain(zone) {
+		return "", fmt.Errorf("create zone: %s is a public suffix", zone)
+	}
+	if err := c.checkZone(cfg, zone); err != nil {
+		return "", fmt.Errorf("create zone: %w", err)
+	}
+	if _, err := sdk.CreateZone(ctx, zone); err != nil && !isZoneExists(err) {
+		return "", fmt.Errorf("create zone %s: %w", zone, err)
+	}
+	// A dry run logged the zone it would create instead.
+	if !cfg.DryRun {
+		c.logger(ctx).Info("created zone for challenge record", "zone", zone, "recordName", fqdn)
+	}
+	return zone, nil
+}
+
+// isZoneExists reports whether creating a zone failed because it exists.
+func isZoneExists(err error) bool {
+	var apiErr dnssdk.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusConflict ||
+		apiErr.StatusCode == http.StatusBadRequest && strings.Contains(strings.ToLower(apiErr.Message), "exist")
+}
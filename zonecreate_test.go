@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresentAllowZoneCreation(t *testing.T) {
+	const fqdn = "_acme-challenge.www.customer.com."
+
+	t.Run("disabled", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		err := solverWithMock(mock).Present(challenge(fqdn, "key", `{"apiToken":"t"}`))
+		assert.ErrorIs(t, err, errZoneNotFound)
+		assert.NotContains(t, mock.zones, "customer.com")
+	})
+
+	t.Run("registered domain", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		require.NoError(t, solverWithMock(mock).Present(challenge(fqdn, "key", `{"apiToken":"t","allowZoneCreation":true}`)))
+		assert.Equal(t, []string{"key"}, mock.contents("customer.com", "_acme-challenge.www.customer.com"))
+		assert.NotContains(t, mock.zones, "www.customer.com")
+	})
+
+	t.Run("multi-label public suffix", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		require.NoError(t, solverWithMock(mock).Present(challenge("_acme-challenge.www.customer.co.uk.", "key",
+			`{"apiToken":"t","allowZoneCreation":true}`)))
+		assert.Equal(t, []string{"key"}, mock.contents("customer.co.uk", "_acme-challenge.www.customer.co.uk"))
+		assert.NotContains(t, mock.zones, "co.uk")
+	})
+
+	t.Run("public suffix zone name", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		cfg := `{"apiToken":"t","allowZoneCreation":true,"zoneName":"co.uk"}`
+		err := solverWithMock(mock).Present(challenge("_acme-challenge.customer.co.uk.", "key", cfg))
+		assert.ErrorContains(t, err, "create zone: co.uk is a public suffix")
+		assert.NotContains(t, mock.zones, "co.uk")
+	})
+
+	t.Run("zone name", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		cfg := `{"apiToken":"t","allowZoneCreation":true,"zoneName":"www.customer.com"}`
+		require.NoError(t, solverWithMock(mock).Present(challenge(fqdn, "key", cfg)))
+		assert.Equal(t, []string{"key"}, mock.contents("www.customer.com", "_acme-challenge.www.customer.com"))
+		assert.NotContains(t, mock.zones, "customer.com")
+	})
+
+	t.Run("denied zone", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		cfg := `{"apiToken":"t","allowZoneCreation":true,"allowedZones":["example.com"]}`
+		err := solverWithMock(mock).Present(challenge(fqdn, "key", cfg))
+		assert.ErrorIs(t, err, errZoneNotPermitted)
+		assert.NotContains(t, mock.zones, "customer.com")
+	})
+
+	t.Run("cleanup does not create zones", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		require.NoError(t, solverWithMock(mock).CleanUp(challenge(fqdn, "key", `{"apiToken":"t","allowZoneCreation":true}`)))
+		assert.NotContains(t, mock.zones, "customer.com")
+	})
+
+	assert.True(t, isZoneExists(dnssdk.APIError{StatusCode: http.StatusBadRequest, Message: "zone already exists"}))
+	assert.True(t, isZoneExists(dnssdk.APIError{StatusCode: http.StatusConflict}))
+	assert.False(t, isZoneExists(dnssdk.APIError{StatusCode: http.StatusBadRequest, Message: "invalid zone name"}))
+	assert.ErrorContains(t, gcoreDNSProviderConfig{AllowZoneCreation: true, ZoneTagFilter: map[string]string{"a": "b"}}.Validate(),
+		"allowZoneCreation can't be used with zoneTagFilter or zoneID")
+}
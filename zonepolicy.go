@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// errZoneNotPermitted is returned when the allowed or denied zones forbid
+// writing to a zone.
+var errZoneNotPermitted = errors.New("zone is not permitted")
+
+// zonePolicy restricts the zones challenge records may be written to. An
+// entry covers the zone of that name and the zones below it. Denied zones
+// win over allowed ones, and an empty allow list allows every zone.
+type zonePolicy struct {
+	allowed []string
+	denied  []string
+}
+
+// check returns errZoneNotPermitted if the policy forbids writing to zone.
+// allowedName and deniedName name the lists in the error.
+func (p zonePolicy) check(zone, allowedName, deniedName string) error {
+	for _, denied := range p.denied {
+		if inZone(zone, strings.Trim(denied, ".")) {
+			return fmt.Errorf("%w: %s is denied by %s entry %q", errZoneNotPermitted, zone, deniedName, denied)
+		}
+	}
+	if len(p.allowed) == 0 {
+		return nil
+	}
+	for _, allowed := range p.allowed {
+		if inZone(zone, strings.Trim(allowed, ".")) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not in %s %v", errZoneNotPermitted, zone, allowedName, p.allowed)
+}
+
+// checkZone makes sure both the webhook's and the issuer's zone lists allow
+// writing to zone.
+func (c *gcoreDNSProviderSolver) checkZone(cfg gcoreDNSProviderConfig, zone string) error {
+	if err := c.zonePolicy.check(zone, allowedZonesEnvVar, deniedZonesEnvVar); err != nil {
+		return err
+	}
+	return zonePolicy{allowed: cfg.AllowedZones, denied: cfg.DeniedZones}.check(zone, "allowedZones", "deniedZones")
+}
+
+// zonePolicyFromEnv reads the webhook-wide zone lists from the comma
+// separated GCORE_ALLOWED_ZONES and GCORE_DENIED_ZONES variables.
+func zonePolicyFromEnv() zonePolicy {
+	return zonePolicy{
+		allowed: splitList(os.Getenv(allowedZonesEnvVar)),
+		denied:  splitList(os.Getenv(deniedZonesEnvVar)),
+	}
+}
+
+// splitList splits a comma separated list, dropping empty entries.
+func splitList(list string) []string {
+	var res []string
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			res = append(res, entry)
+		}
+	}
+	return res
+}
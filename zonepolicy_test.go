@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZonePolicy(t *testing.T) {
+	policy := zonePolicy{allowed: []string{"example.com.", "example.net"}, denied: []string{"prod.example.com"}}
+	testCases := []struct {
+		zone   string
+		errMsg string
+	}{
+		{zone: "example.com"},
+		{zone: "dev.example.com"},
+		{zone: "EXAMPLE.NET"},
+		{zone: "prod.example.com", errMsg: `prod.example.com is denied by deniedZones entry "prod.example.com"`},
+		{zone: "eu.prod.example.com", errMsg: `is denied by deniedZones entry "prod.example.com"`},
+		{zone: "example.org", errMsg: "example.org is not in allowedZones [example.com. example.net]"},
+		{zone: "badexample.com", errMsg: "badexample.com is not in allowedZones"},
+	}
+	for _, test := range testCases {
+		test := test
+		t.Run(test.zone, func(t *testing.T) {
+			t.Parallel()
+			err := policy.check(test.zone, "allowedZones", "deniedZones")
+			if test.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, errZoneNotPermitted)
+			assert.ErrorContains(t, err, test.errMsg)
+		})
+	}
+	assert.NoError(t, zonePolicy{}.check("example.org", "allowedZones", "deniedZones"))
+}
+
+func TestPresentZonePolicy(t *testing.T) {
+	mock := newMockSDK("example.com", "example.net", "example.org")
+	solver := solverWithMock(mock)
+	solver.zonePolicy = zonePolicy{denied: []string{"example.org"}}
+	cfg := `{"apiToken":"t","allowedZones":["example.com","example.org"]}`
+
+	require.NoError(t, solver.Present(challenge("_acme-challenge.example.com.", "key", cfg)))
+
+	err := solver.Present(challenge("_acme-challenge.example.net.", "key", cfg))
+	assert.ErrorIs(t, err, errZoneNotPermitted)
+	assert.ErrorContains(t, err, "example.net is not in allowedZones")
+	assert.Nil(t, mock.contents("example.net", "_acme-challenge.example.net"))
+
+	err = solver.Present(challenge("_acme-challenge.example.org.", "key", cfg))
+	assert.ErrorContains(t, err, `example.org is denied by GCORE_DENIED_ZONES entry "example.org"`)
+
+	err = solver.CleanUp(challenge("_acme-challenge.example.net.", "key", cfg))
+	assert.ErrorIs(t, err, errZoneNotPermitted)
+
+	t.Setenv(allowedZonesEnvVar, " example.com, ,example.net ")
+	assert.Equal(t, zonePolicy{allowed: []string{"example.com", "example.net"}}, zonePolicyFromEnv())
+}
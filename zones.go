@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+)
+
+// zoneListPageSize is the number of zones requested per zone list page.
+const zoneListPageSize = 100
+
+// eachZone pages through the zones matching param, calling fn for each zone
+// until it returns false. Pages are requested with zoneListPageSize, but the
+// next offset follows the zones actually returned, so an API that caps the
+// page size below the request still has every page read.
+func eachZone(ctx context.Context, sdk dnsAPI, param dnssdk.ZonesParam, fn func(dnssdk.Zone) bool) error {
+	param.Limit = zoneListPageSize
+	for {
+		page, err := sdk.ZonesWithParam(ctx, param)
+		if err != nil {
+			return err
+		}
+		for _, zone := range page.Zones {
+			if !fn(zone) {
+				return nil
+			}
+		}
+		param.Offset += uint64(len(page.Zones))
+		// Without a total the last page is the first one that is not full.
+		if len(page.Zones) == 0 || page.TotalAmount > 0 && param.Offset >= uint64(page.TotalAmount) ||
+			page.TotalAmount == 0 && len(page.Zones) < zoneListPageSize {
+			return nil
+		}
+	}
+}
+
+// listZones pages through the account's zones looking for the candidate zone
+// names and returns the ones found in candidate order. The names are passed
+// as filter so the API only returns candidates, but pages are also scanned
+// correctly if the filter is ignored. Only one page is held at a time, and
+// with stopAtFirst the search ends as soon as the first candidate is seen.
+func listZones(ctx context.Context, sdk dnsAPI, candidates []string, stopAtFirst bool) ([]string, error) {
+	rank := make(map[string]int, len(candidates))
+	for i, candidate := range candidates {
+		rank[strings.ToLower(candidate)] = i
+	}
+	found := make([]bool, len(candidates))
+	err := eachZone(ctx, sdk, dnssdk.ZonesParam{Name: candidates, ExactMatch: true}, func(zone dnssdk.Zone) bool {
+		i, ok := rank[strings.ToLower(strings.Trim(zone.Name, "."))]
+		if ok {
+			found[i] = true
+		}
+		return !ok || i != 0 || !stopAtFirst
+	})
+	if err != nil {
+		return nil, err
+	}
+	var matched []string
+	for i, ok := range found {
+		if ok {
+			matched = append(matched, candidates[i])
+		}
+	}
+	return matched, nil
+}
+
+// errZoneFilterIgnored reports a zone list answer listing zones that were
+// not asked for, from an API that doesn't support the zone name filter.
+var errZoneFilterIgnored = errors.New("zone name filter ignored")
+
+// filterZones asks for the candidate zone names in a single filtered zone
+// list query and returns the ones the account has, in candidate order. It
+// fails when the answer shows the API ignored the filter, so the caller can
+// fall back to probing.
+func filterZones(ctx context.Context, sdk dnsAPI, candidates []string) ([]string, error) {
+	page, err := sdk.ZonesWithParam(ctx, dnssdk.ZonesParam{
+		Limit:      uint64(len(candidates)),
+		Name:       candidates,
+		ExactMatch: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if page.TotalAmount > len(candidates) {
+		return nil, fmt.Errorf("%w, %d zones listed", errZoneFilterIgnored, page.TotalAmount)
+	}
+	found := make(map[string]bool, len(page.Zones))
+	for _, zone := range page.Zones {
+		name := strings.ToLower(strings.Trim(zone.Name, "."))
+		if !slices.ContainsFunc(candidates, func(c string) bool { return strings.EqualFold(c, name) }) {
+			return nil, fmt.Errorf("%w, %s listed", errZoneFilterIgnored, name)
+		}
+		found[name] = true
+	}
+	var matched []string
+	for _, candidate := range candidates {
+		if found[strings.ToLower(candidate)] {
+			matched = append(matched, candidate)
+		}
+	}
+	return matched, nil
+}
+
+// zoneNameByID returns the name of the zone with the given ID.
+func zoneNameByID(ctx context.Context, sdk dnsAPI, id uint64) (string, error) {
+	page, err := sdk.ZonesWithParam(ctx, dnssdk.ZonesParam{ID: []uint64{id}, Limit: 1})
+	if err != nil {
+		return "", err
+	}
+	if len(page.Zones) == 0 {
+		return "", fmt.Errorf("zone with id %d not found", id)
+	}
+	return strings.Trim(page.Zones[0].Name, "."), nil
+}
+
+// soaZone finds the zone of fqdn by resolving its authoritative zone with SOA
+// queries, like cert-manager does, and checks that the account has it. One
+// API call replaces probing every candidate, which matters for accounts with
+// many zones.
+func (c *gcoreDNSProviderSolver) soaZone(ctx context.Context, fqdn string, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig) (string, string, error) {
+	lookupSOA := c.lookupSOA
+	if lookupSOA == nil {
+		lookupSOA = func(ctx context.Context, fqdn string) (string, error) {
+			return util.FindZoneByFqdn(ctx, fqdn, c.recursiveNameservers())
+		}
+	}
+	apex, err := lookupSOA(ctx, strings.Trim(fqdn, ".")+".")
+	if err != nil {
+		return "", "", fmt.Errorf("soa lookup: %w", err)
+	}
+	zone := strings.ToLower(strings.Trim(apex, "."))
+	if strings.Count(zone, ".")+1 < max(cfg.MinZoneLabels, defaultMinZoneLabels) || !inZone(fqdn, zone) {
+		return "", "", fmt.Errorf("soa lookup: %s is not a candidate zone of %s", apex, fqdn)
+	}
+	details, err := c.lookupZone(ctx, sdk, cfg, zone)
+	if err != nil {
+		return "", "", err
+	}
+	if len(cfg.ZoneTagFilter) > 0 && !matchZoneTags(details.Meta, cfg.ZoneTagFilter) {
+		return "", "", fmt.Errorf("zone %s lacks the tags required by zoneTagFilter %v", zone, cfg.ZoneTagFilter)
+	}
+	return zone, details.Name, nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// largeZoneListing returns n filler zone names with the given zones mixed in.
+func largeZoneListing(n int, zones ...string) []string {
+	listing := make([]string, 0, n+len(zones))
+	for i := 0; i < n; i++ {
+		listing = append(listing, fmt.Sprintf("zone-%05d.example.net", i))
+		if i == n/2 {
+			listing = append(listing, zones...)
+		}
+	}
+	return listing
+}
+
+func TestListZones(t *testing.T) {
+	candidates := []string{"www.sub.example.com", "sub.example.com", "example.com"}
+
+	t.Run("filtered listing", func(t *testing.T) {
+		mock := newMockSDK("sub.example.com", "example.com", "example.org")
+		matched, err := listZones(context.Background(), mock, candidates, true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sub.example.com", "example.com"}, matched)
+		assert.Equal(t, 1, mock.listCalls)
+	})
+
+	t.Run("unfiltered large listing", func(t *testing.T) {
+		mock := newMockSDK()
+		mock.ignoreNameFilter = true
+		mock.listing = largeZoneListing(10000, "example.com", "sub.example.com")
+
+		matched, err := listZones(context.Background(), mock, candidates, true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sub.example.com", "example.com"}, matched)
+		assert.Equal(t, 10002/zoneListPageSize+1, mock.listCalls)
+	})
+
+	t.Run("stops at longest candidate", func(t *testing.T) {
+		mock := newMockSDK()
+		mock.ignoreNameFilter = true
+		mock.listing = largeZoneListing(10000, "www.sub.example.com")
+
+		matched, err := listZones(context.Background(), mock, candidates, true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"www.sub.example.com"}, matched)
+		assert.Equal(t, 5000/zoneListPageSize+1, mock.listCalls)
+	})
+
+	t.Run("capped page size", func(t *testing.T) {
+		mock := newMockSDK()
+		mock.ignoreNameFilter = true
+		mock.maxPageSize = 25
+		mock.listing = largeZoneListing(1000, "example.com")
+
+		matched, err := listZones(context.Background(), mock, candidates, true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"example.com"}, matched)
+		assert.Equal(t, 1001/25+1, mock.listCalls)
+	})
+
+	t.Run("page size multiple", func(t *testing.T) {
+		mock := newMockSDK()
+		mock.ignoreNameFilter = true
+		mock.listing = largeZoneListing(2*zoneListPageSize-1, "example.com")
+
+		_, err := listZones(context.Background(), mock, candidates, true)
+		require.NoError(t, err)
+		assert.Equal(t, 2, mock.listCalls, "the total should end the listing without an empty page")
+	})
+}
+
+func TestDetectZoneListDiscovery(t *testing.T) {
+	mock := newMockSDK("example.com", "sub.example.com")
+	solver := solverWithMock(mock)
+	cfg := gcoreDNSProviderConfig{ZoneDiscovery: zoneDiscoveryList, MinZoneLabels: 2}
+
+	zone, err := solver.detectZone(context.Background(), "_acme-challenge.www.sub.example.com", mock, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "sub.example.com", zone)
+	assert.Zero(t, mock.zoneLookups, "list discovery should not probe zones")
+
+	_, err = solver.detectZone(context.Background(), "_acme-challenge.example.org", mock, cfg)
+	assert.ErrorContains(t, err, "not found in zone list")
+}
+
+func TestDetectZoneFilterDiscovery(t *testing.T) {
+	cfg := gcoreDNSProviderConfig{ZoneDiscovery: zoneDiscoveryFilter, MinZoneLabels: 2}
+
+	t.Run("filtered query", func(t *testing.T) {
+		mock := newMockSDK("example.com", "sub.example.com", "example.org")
+		zone, err := solverWithMock(mock).detectZone(context.Background(),
+			"_acme-challenge.a.b.c.sub.example.com", mock, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "sub.example.com", zone)
+		assert.Equal(t, 1, mock.listCalls)
+		assert.Zero(t, mock.zoneLookups, "filter discovery should not probe zones")
+
+		_, err = solverWithMock(mock).detectZone(context.Background(), "_acme-challenge.example.net", mock, cfg)
+		assert.ErrorIs(t, err, errZoneNotFound)
+	})
+
+	t.Run("ignored filter", func(t *testing.T) {
+		mock := newMockSDK("example.com", "example.org")
+		mock.ignoreNameFilter = true
+		zone, err := solverWithMock(mock).detectZone(context.Background(), "_acme-challenge.www.example.org", mock, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "example.org", zone)
+		assert.Equal(t, 1, mock.listCalls)
+		assert.Equal(t, 2, mock.zoneLookups, "candidates should be probed when the filter is ignored")
+	})
+
+	t.Run("remembered ignored filter", func(t *testing.T) {
+		mock := newMockSDK("example.com", "example.org")
+		mock.ignoreNameFilter = true
+		solver := solverWithMock(mock)
+		solver.unfiltered = newCache[string, struct{}](time.Hour, 10)
+		for _, fqdn := range []string{"_acme-challenge.www.example.org", "_acme-challenge.www.example.com"} {
+			_, err := solver.detectZone(context.Background(), fqdn, mock, cfg)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 1, mock.listCalls, "an endpoint ignoring the filter should not be asked again")
+	})
+}
+
+func TestDetectZoneMatchPolicy(t *testing.T) {
+	testCases := []struct {
+		discovery string
+		match     string
+		want      string
+	}{
+		{discovery: zoneDiscoveryFilter, match: zoneMatchDeepest, want: "sub.example.com"},
+		{discovery: zoneDiscoveryFilter, match: zoneMatchShallowest, want: "example.com"},
+		{discovery: zoneDiscoveryProbe, match: zoneMatchDeepest, want: "sub.example.com"},
+		{discovery: zoneDiscoveryProbe, match: zoneMatchShallowest, want: "example.com"},
+		{discovery: zoneDiscoveryList, match: zoneMatchDeepest, want: "sub.example.com"},
+		{discovery: zoneDiscoveryList, match: zoneMatchShallowest, want: "example.com"},
+	}
+	for _, test := range testCases {
+		test := test
+		t.Run(test.discovery+" "+test.match, func(t *testing.T) {
+			t.Parallel()
+			mock := newMockSDK("example.com", "sub.example.com")
+			cfg := gcoreDNSProviderConfig{ZoneDiscovery: test.discovery, ZoneMatch: test.match, MinZoneLabels: 2}
+			zone, err := solverWithMock(mock).detectZone(context.Background(), "_acme-challenge.www.sub.example.com", mock, cfg)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, zone)
+		})
+	}
+
+	t.Run("default", func(t *testing.T) {
+		mock := newMockSDK("example.com", "sub.example.com")
+		require.NoError(t, solverWithMock(mock).Present(challenge("_acme-challenge.sub.example.com.", "key", `{"apiToken":"t"}`)))
+		assert.Equal(t, []string{"key"}, mock.contents("sub.example.com", "_acme-challenge.sub.example.com"))
+		assert.Empty(t, mock.contents("example.com", "_acme-challenge.sub.example.com"))
+	})
+
+	t.Run("cached parent zone", func(t *testing.T) {
+		mock := newMockSDK("example.com", "sub.example.com")
+		solver := solverWithMock(mock)
+		solver.zones = newCache[zoneCacheKey, zoneDetails](time.Hour, 10)
+		cfg := gcoreDNSProviderConfig{ZoneDiscovery: zoneDiscoveryFilter, ZoneMatch: zoneMatchDeepest, MinZoneLabels: 2}
+		zone, err := solver.detectZone(context.Background(), "_acme-challenge.example.com", mock, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "example.com", zone)
+		zone, err = solver.detectZone(context.Background(), "_acme-challenge.www.sub.example.com", mock, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "sub.example.com", zone, "a cached parent zone must not hide the child zone")
+
+		listCalls := mock.listCalls
+		_, err = solver.detectZone(context.Background(), "_acme-challenge.www.sub.example.com", mock, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, listCalls, mock.listCalls, "the zone of a record name is cached")
+	})
+
+	assert.ErrorContains(t, gcoreDNSProviderConfig{ZoneMatch: "closest"}.Validate(), `zoneMatch must be "deepest" or "shallowest"`)
+	assert.ErrorContains(t, gcoreDNSProviderConfig{ZoneMatch: zoneMatchShallowest, ZoneDiscovery: zoneDiscoverySOA}.Validate(),
+		"can't be used with zoneDiscovery")
+}
+
+func TestDetectZoneSOADiscovery(t *testing.T) {
+	mock := newMockSDK("example.com", "sub.example.com")
+	solver := solverWithMock(mock)
+	apexes := map[string]string{
+		"_acme-challenge.www.sub.example.com.": "sub.example.com.",
+		"_acme-challenge.example.com.":         "example.com.",
+		"_acme-challenge.other.example.com.":   "other.example.com.",
+	}
+	solver.lookupSOA = func(_ context.Context, fqdn string) (string, error) {
+		if apex, ok := apexes[fqdn]; ok {
+			return apex, nil
+		}
+		return "", fmt.Errorf("no SOA for %s", fqdn)
+	}
+	cfg := gcoreDNSProviderConfig{ZoneDiscovery: zoneDiscoverySOA, MinZoneLabels: 2}
+
+	zone, err := solver.detectZone(context.Background(), "_acme-challenge.www.sub.example.com", mock, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "sub.example.com", zone)
+	assert.Equal(t, 1, mock.zoneLookups, "soa discovery should check a single zone")
+
+	// A zone DNS knows but the account lacks, and a failed SOA lookup, fall
+	// back to probing the candidates.
+	for _, fqdn := range []string{"_acme-challenge.other.example.com", "_acme-challenge.www.example.com"} {
+		zone, err = solver.detectZone(context.Background(), fqdn, mock, cfg)
+		require.NoError(t, err, fqdn)
+		assert.Equal(t, "example.com", zone, fqdn)
+	}
+}
+
+func BenchmarkListZones(b *testing.B) {
+	mock := newMockSDK()
+	mock.ignoreNameFilter = true
+	mock.listing = largeZoneListing(100000, "example.com")
+	candidates := []string{"www.example.com", "example.com"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		matched, err := listZones(context.Background(), mock, candidates, true)
+		if err != nil || len(matched) != 1 {
+			b.Fatalf("unexpected result %v: %v", matched, err)
+		}
+	}
+}
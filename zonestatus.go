@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+)
+
+// errZoneDisabled is returned when a write failed because G-Core has the
+// zone disabled or suspended, which the API answers like a permission
+// problem.
+var errZoneDisabled = errors.New("disabled in G-Core")
+
+// disabled reports whether the zone is disabled or suspended. Zones whose
+// answer has neither field are taken as enabled.
+func (d zoneDetails) disabled() bool {
+	switch strings.ToLower(d.Status) {
+	case "disabled", "suspended", "blocked":
+		return true
+	}
+	return d.Enabled != nil && !*d.Enabled
+}
+
+// checkDisabledZone looks at the status of zone after a write to it was
+// refused with writeErr. For a disabled zone it either enables the zone,
+// when cfg.EnableDisabledZones is set, and reports the write should be
+// retried, or returns an errZoneDisabled error. Any other failure is
+// returned as is.
+func (c *gcoreDNSProviderSolver) checkDisabledZone(ctx context.Context, sdk dnsAPI,
+	cfg gcoreDNSProviderConfig, zone string, writeErr error) (bool, error) {
+	var apiErr dnssdk.APIError
+	if !errors.As(writeErr, &apiErr) || apiErr.StatusCode < 400 || apiErr.StatusCode >= 500 ||
+		apiErr.StatusCode == http.StatusTooManyRequests {
+		return false, writeErr
+	}
+	details, err := sdk.ZoneDetails(ctx, zone)
+	if err != nil || !details.disabled() {
+		return false, writeErr
+	}
+	if !cfg.EnableDisabledZones {
+		return false, fmt.Errorf("zone %s is %w, enable it or set enableDisabledZones: %w", zone, errZoneDisabled, writeErr)
+	}
+	if err := sdk.EnableZone(ctx, zone); err != nil {
+		return false, fmt.Errorf("zone %s is %w: %w", zone, errZoneDisabled, err)
+	}
+	c.logger(ctx).Info("enabled disabled zone for challenge record", "zone", zone)
+	return true, nil
+}
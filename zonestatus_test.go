@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	dnssdk "github.com/G-Core/gcore-dns-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZoneDetailsDisabled(t *testing.T) {
+	enabled, disabled := true, false
+	testCases := []struct {
+		details  zoneDetails
+		expected bool
+	}{
+		{details: zoneDetails{}},
+		{details: zoneDetails{Enabled: &enabled}},
+		{details: zoneDetails{Enabled: &disabled}, expected: true},
+		{details: zoneDetails{Status: "Suspended"}, expected: true},
+		{details: zoneDetails{Enabled: &enabled, Status: "disabled"}, expected: true},
+		{details: zoneDetails{Status: "active"}},
+	}
+	for _, test := range testCases {
+		assert.Equal(t, test.expected, test.details.disabled(), "%+v", test.details)
+	}
+}
+
+func TestPresentDisabledZone(t *testing.T) {
+	const fqdn = "_acme-challenge.example.com."
+
+	t.Run("fails with a clear error", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		mock.zones["example.com"].disabled = true
+		solver := solverWithMock(mock)
+		err := solver.Present(challenge(fqdn, "token", `{"apiToken":"t"}`))
+		require.ErrorIs(t, err, errZoneDisabled)
+		assert.NotErrorIs(t, err, errAuth)
+		assert.True(t, strings.HasPrefix(err.Error(), "G-Core zone is disabled"), err.Error())
+		assert.ErrorContains(t, err, "zone example.com is disabled in G-Core")
+		assert.True(t, mock.zones["example.com"].disabled)
+	})
+
+	t.Run("enables the zone", func(t *testing.T) {
+		mock := newMockSDK("example.com")
+		mock.zones["example.com"].disabled = true
+		solver := solverWithMock(mock)
+		require.NoError(t, solver.Present(challenge(fqdn, "token", `{"apiToken":"t","enableDisabledZones":true}`)))
+		assert.False(t, mock.zones["example.com"].disabled)
+		assert.Equal(t, []string{"token"}, mock.contents("example.com", "_acme-challenge.example.com"))
+	})
+
+	t.Run("enabled zone keeps the api error", func(t *testing.T) {
+		solver := solverWithMock(newMockSDK("example.com"))
+		forbidden := dnssdk.APIError{StatusCode: http.StatusForbidden, Message: "forbidden"}
+		retry, err := solver.checkDisabledZone(context.Background(), newMockSDK("example.com"),
+			gcoreDNSProviderConfig{EnableDisabledZones: true}, "example.com", forbidden)
+		assert.False(t, retry)
+		assert.Equal(t, forbidden, err)
+	})
+}